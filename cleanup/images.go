@@ -0,0 +1,129 @@
+// Package cleanup removes data nothing refers to any more.
+package cleanup
+
+import (
+	"context"
+	"errors"
+	"goozinshe/imaging"
+	"goozinshe/logger"
+	"goozinshe/models"
+	"goozinshe/repositories"
+	"goozinshe/storage"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// ImageSweeper deletes posters no movie has referenced for longer than the
+// grace period, together with their variants. The grace period keeps posters
+// that are being uploaded right now and leaves time to undo a change.
+type ImageSweeper struct {
+	imagesRepo *repositories.ImagesRepository
+	storage    storage.Storage
+	grace      time.Duration
+}
+
+type SweepResult struct {
+	// Expired are the images that are (or, in a dry run, would be) deleted.
+	Expired []models.Image
+	Deleted int
+}
+
+func NewImageSweeper(imagesRepo *repositories.ImagesRepository, storage storage.Storage, grace time.Duration) *ImageSweeper {
+	return &ImageSweeper{imagesRepo: imagesRepo, storage: storage, grace: grace}
+}
+
+// Sweep deletes expired images. Files in the storage that aren't tracked yet,
+// e.g. posters uploaded before images were tracked, are tracked from now on
+// and expire like any other image. A dry run only reports what would be
+// deleted and changes nothing.
+func (s *ImageSweeper) Sweep(c context.Context, dryRun bool) (SweepResult, error) {
+	logger := logger.GetLogger()
+
+	stored, err := s.storedOriginals(c)
+	if err != nil {
+		logger.Error("Could not list stored images", zap.Error(err))
+		return SweepResult{}, err
+	}
+
+	expired, err := s.imagesRepo.FindExpired(c, stored, s.grace, dryRun)
+	if err != nil {
+		return SweepResult{}, err
+	}
+
+	result := SweepResult{Expired: expired}
+	if dryRun || len(expired) == 0 {
+		return result, nil
+	}
+
+	ids := make([]string, 0, len(expired))
+	for _, image := range expired {
+		ids = append(ids, image.Id)
+	}
+
+	// Files are deleted while the rows are locked. A file that couldn't be
+	// deleted loses its row anyway and is tracked again by the next sweep.
+	var errs []error
+	deleted, err := s.imagesRepo.DeleteOrphaned(c, ids, func(id string) {
+		for _, key := range append([]string{id}, imaging.VariantKeys(id)...) {
+			err := s.storage.Delete(c, key)
+			if err != nil && !errors.Is(err, storage.ErrInvalidKey) {
+				logger.Error("Could not delete image file", zap.String("key", key), zap.Error(err))
+				errs = append(errs, err)
+			}
+		}
+	})
+	if err != nil {
+		return result, errors.Join(append(errs, err)...)
+	}
+
+	result.Deleted = len(deleted)
+	logger.Info("Swept orphaned images", zap.Int("deleted", result.Deleted))
+	return result, errors.Join(errs...)
+}
+
+// Run sweeps every interval until c is cancelled.
+func (s *ImageSweeper) Run(c context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.Sweep(c, false); err != nil {
+				logger.GetLogger().Error("Image sweep failed", zap.Error(err))
+			}
+		}
+	}
+}
+
+// storedOriginals lists the stored files except for variants of other stored
+// files, which are deleted together with their original.
+func (s *ImageSweeper) storedOriginals(c context.Context) ([]models.Image, error) {
+	files := make([]storage.ObjectInfo, 0)
+	err := s.storage.List(c, func(info storage.ObjectInfo) error {
+		files = append(files, info)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	bases := make(map[string]bool, len(files))
+	for _, file := range files {
+		bases[strings.TrimSuffix(file.Key, filepath.Ext(file.Key))] = true
+	}
+
+	originals := make([]models.Image, 0, len(files))
+	for _, file := range files {
+		if base, ok := imaging.ParseVariantKey(file.Key); ok && bases[base] {
+			continue
+		}
+		originals = append(originals, models.Image{Id: file.Key, Size: file.Size, CreatedAt: file.ModTime})
+	}
+	return originals, nil
+}
@@ -0,0 +1,42 @@
+package cleanup
+
+import (
+	"context"
+	"goozinshe/logger"
+	"goozinshe/repositories"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// TrashPurger permanently deletes movies, genres and users that have been in
+// the trash for longer than the retention period.
+type TrashPurger struct {
+	trashRepo *repositories.TrashRepository
+	retention time.Duration
+}
+
+func NewTrashPurger(trashRepo *repositories.TrashRepository, retention time.Duration) *TrashPurger {
+	return &TrashPurger{trashRepo: trashRepo, retention: retention}
+}
+
+func (p *TrashPurger) Purge(c context.Context) (repositories.PurgeResult, error) {
+	return p.trashRepo.Purge(c, p.retention)
+}
+
+// Run purges every interval until c is cancelled.
+func (p *TrashPurger) Run(c context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.Done():
+			return
+		case <-ticker.C:
+			if _, err := p.Purge(c); err != nil {
+				logger.GetLogger().Error("Trash purge failed", zap.Error(err))
+			}
+		}
+	}
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"goozinshe/cleanup"
+	"goozinshe/config"
+	"goozinshe/migrations"
+	"goozinshe/models"
+	"goozinshe/repositories"
+	"goozinshe/storage"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const commandsUsage = `usage:
+  ozinshe-go [-migrate]          start the server, optionally applying pending migrations first
+  ozinshe-go migrate up          apply all pending migrations
+  ozinshe-go migrate down [n]    roll back the last n migrations (default 1)
+  ozinshe-go migrate status      list migrations and whether they are applied
+  ozinshe-go storage import dir  copy the files of a local directory into the configured storage
+  ozinshe-go images gc [-dry-run] [-grace d]
+                                 delete posters no movie has referenced for longer than the grace period
+  ozinshe-go trash purge [-retention-days n]
+                                 delete movies, genres and users that have been in the trash for n days`
+
+// runCommand executes the command line subcommand given in args.
+func runCommand(conn *pgxpool.Pool, args []string) error {
+	switch args[0] {
+	case "migrate":
+		return runMigrateCommand(conn, args[1:])
+	case "storage":
+		return runStorageCommand(args[1:])
+	case "images":
+		return runImagesCommand(conn, args[1:])
+	case "trash":
+		return runTrashCommand(conn, args[1:])
+	default:
+		return fmt.Errorf("unknown command %q\n%s", args[0], commandsUsage)
+	}
+}
+
+func runMigrateCommand(conn *pgxpool.Pool, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("missing migrate subcommand\n%s", commandsUsage)
+	}
+
+	migrator, err := migrations.NewMigrator(conn)
+	if err != nil {
+		return err
+	}
+
+	c := context.Background()
+	switch args[0] {
+	case "up":
+		count, err := migrator.Up(c)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("applied %d migration(s)\n", count)
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				return fmt.Errorf("invalid number of migrations %q", args[1])
+			}
+		}
+		count, err := migrator.Down(c, steps)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("rolled back %d migration(s)\n", count)
+	case "status":
+		statuses, err := migrator.Status(c)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+		for _, status := range statuses {
+			appliedAt := "pending"
+			if status.AppliedAt != nil {
+				appliedAt = status.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(w, "%04d\t%s\t%s\n", status.Version, status.Name, appliedAt)
+		}
+		return w.Flush()
+	default:
+		return fmt.Errorf("unknown migrate subcommand %q\n%s", args[0], commandsUsage)
+	}
+
+	return nil
+}
+
+func runStorageCommand(args []string) error {
+	if len(args) != 2 || args[0] != "import" {
+		return fmt.Errorf("usage: ozinshe-go storage import dir\n%s", commandsUsage)
+	}
+
+	target, err := storage.New(config.Config)
+	if err != nil {
+		return err
+	}
+	source, err := storage.NewLocalStorage(args[1])
+	if err != nil {
+		return err
+	}
+
+	c := context.Background()
+	count := 0
+	err = source.List(c, func(info storage.ObjectInfo) error {
+		key := info.Key
+		object, err := source.Get(c, key)
+		if err != nil {
+			return err
+		}
+		defer object.Body.Close()
+
+		if err := target.Put(c, key, object.Body, object.Size, object.ContentType); err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+		count++
+		return nil
+	})
+	fmt.Printf("imported %d file(s)\n", count)
+	return err
+}
+
+func runImagesCommand(conn *pgxpool.Pool, args []string) error {
+	if len(args) == 0 || args[0] != "gc" {
+		return fmt.Errorf("usage: ozinshe-go images gc [-dry-run] [-grace d]\n%s", commandsUsage)
+	}
+
+	flags := flag.NewFlagSet("images gc", flag.ContinueOnError)
+	dryRun := flags.Bool("dry-run", false, "only report the images that would be deleted")
+	grace := flags.Duration("grace", config.Config.ImageGcGracePeriod, "how long an image must have been unreferenced")
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	posterStorage, err := storage.New(config.Config)
+	if err != nil {
+		return err
+	}
+
+	sweeper := cleanup.NewImageSweeper(repositories.NewImagesRepository(conn), posterStorage, *grace)
+	result, err := sweeper.Sweep(context.Background(), *dryRun)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "IMAGE\tSIZE\tORPHANED AT")
+	for _, image := range result.Expired {
+		fmt.Fprintf(w, "%s\t%d\t%s\n", image.Id, image.Size, image.OrphanedAt.Format(time.RFC3339))
+	}
+	w.Flush()
+
+	if *dryRun {
+		fmt.Printf("would delete %d image(s)\n", len(result.Expired))
+	} else {
+		fmt.Printf("deleted %d image(s)\n", result.Deleted)
+	}
+	return err
+}
+
+func runTrashCommand(conn *pgxpool.Pool, args []string) error {
+	if len(args) == 0 || args[0] != "purge" {
+		return fmt.Errorf("usage: ozinshe-go trash purge [-retention-days n]\n%s", commandsUsage)
+	}
+
+	flags := flag.NewFlagSet("trash purge", flag.ContinueOnError)
+	days := flags.Int("retention-days", config.Config.TrashRetentionDays, "how many days items stay in the trash")
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	purger := cleanup.NewTrashPurger(repositories.NewTrashRepository(conn), time.Duration(*days)*24*time.Hour)
+	result, err := purger.Purge(context.Background())
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("purged %d movie(s), %d genre(s) and %d user(s)\n",
+		result[models.EntityMovie], result[models.EntityGenre], result[models.EntityUser])
+	return nil
+}
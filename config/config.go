@@ -1,6 +1,9 @@
 package config
 
-import "time"
+import (
+	"strings"
+	"time"
+)
 
 var Config *MapConfig
 
@@ -9,4 +12,36 @@ type MapConfig struct {
 	DbConnectionString string  		 `mapstructure:"DB_CONNECTION_STRING"`
 	JwtSecretKey       string  		 `mapstructure:"JWT_SECRET_KEY"`
 	JwtExpiresIn       time.Duration `mapstructure:"JWT_EXPIRE_DURATION"`
+	RefreshExpiresIn   time.Duration `mapstructure:"REFRESH_TOKEN_EXPIRE_DURATION"`
+	StorageDriver      string        `mapstructure:"STORAGE_DRIVER"`
+	StorageLocalDir    string        `mapstructure:"STORAGE_LOCAL_DIR"`
+	S3Endpoint         string        `mapstructure:"S3_ENDPOINT"`
+	S3Region           string        `mapstructure:"S3_REGION"`
+	S3Bucket           string        `mapstructure:"S3_BUCKET"`
+	S3AccessKey        string        `mapstructure:"S3_ACCESS_KEY"`
+	S3SecretKey        string        `mapstructure:"S3_SECRET_KEY"`
+	S3UseSSL           bool          `mapstructure:"S3_USE_SSL"`
+	ImageGcInterval    time.Duration `mapstructure:"IMAGE_GC_INTERVAL"`
+	ImageGcGracePeriod time.Duration `mapstructure:"IMAGE_GC_GRACE_PERIOD"`
+	TrashRetentionDays int           `mapstructure:"TRASH_RETENTION_DAYS"`
+	TrashPurgeInterval time.Duration `mapstructure:"TRASH_PURGE_INTERVAL"`
+	LocaleFallback     string        `mapstructure:"LOCALE_FALLBACK"`
+}
+
+// TrashRetention is how long deleted items stay in the trash.
+func (c *MapConfig) TrashRetention() time.Duration {
+	return time.Duration(c.TrashRetentionDays) * 24 * time.Hour
+}
+
+// LocaleFallbacks lists the locales content is shown in, in order, when it
+// isn't translated to the requested one. Without fallbacks the original text
+// is shown instead.
+func (c *MapConfig) LocaleFallbacks() []string {
+	locales := make([]string, 0)
+	for _, locale := range strings.Split(c.LocaleFallback, ",") {
+		if locale = strings.ToLower(strings.TrimSpace(locale)); locale != "" {
+			locales = append(locales, locale)
+		}
+	}
+	return locales
 }
@@ -1,6 +1,11 @@
 package handlers
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
 	"goozinshe/config"
 	"goozinshe/models"
 	"goozinshe/repositories"
@@ -10,20 +15,39 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
 )
 
 type AuthHandlers struct {
-	usersRepo *repositories.UsersRepository
+	usersRepo    *repositories.UsersRepository
+	sessionsRepo *repositories.SessionsRepository
 }
 
-func NewAuthHandlers(usersRepo *repositories.UsersRepository) *AuthHandlers {
-	return &AuthHandlers{usersRepo: usersRepo}
+func NewAuthHandlers(
+	usersRepo *repositories.UsersRepository,
+	sessionsRepo *repositories.SessionsRepository) *AuthHandlers {
+	return &AuthHandlers{
+		usersRepo:    usersRepo,
+		sessionsRepo: sessionsRepo,
+	}
 }
 
+var errInvalidCredentials = models.NewUnauthorizedError("invalid_credentials", "Invalid credentials")
+
 type SignInRequest struct {
-	Email 		string
-	Password 	string
+	Email 		string	`json:"email" binding:"required,email"`
+	Password 	string	`json:"password" binding:"required"`
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refreshToken" binding:"required"`
+}
+
+type tokensResponse struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refreshToken"`
+	ExpiresIn    int    `json:"expiresIn"`
 }
 
 // SignIn godoc
@@ -32,69 +56,169 @@ type SignInRequest struct {
 // @Accept       json
 // @Produce      json
 // @Param request body handlers.SignInRequest true "Request body"
-// @Success      200  {object} object{token=string} "OK"
-// @Failure   	 401  {object} models.ApiError "authorization header required"
-// @Failure   	 500  {object} models.ApiError
+// @Success      200  {object} handlers.tokensResponse "OK"
+// @Failure   	 400  {object} models.Problem "Invalid payload"
+// @Failure   	 401  {object} models.Problem "Invalid credentials"
+// @Failure   	 500  {object} models.Problem
 // @Router       /auth/signIn [post]
 func (h *AuthHandlers) SignIn(c *gin.Context) {
 	var request SignInRequest
-	if err := c.BindJSON(&request); err != nil {
-		c.JSON(http.StatusBadRequest, models.NewApiError("Invalid payload"))
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.Error(bindingError(err))
 		return
 	}
 
 	user, err := h.usersRepo.FindByEmail(c, request.Email)
+	if errors.Is(err, repositories.ErrUserNotFound) {
+		c.Error(errInvalidCredentials)
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusBadRequest, models.NewApiError(err.Error()))
+		c.Error(err)
+		return
 	}
-	
+
 	err = bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(request.Password))
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, models.NewApiError("Invalid credentials"))
+		c.Error(errInvalidCredentials)
 		return
 	}
 
-	claims := jwt.RegisteredClaims {
-		Subject: strconv.Itoa(user.Id),
-		ExpiresAt: jwt.NewNumericDate(time.Now().Add(config.Config.JwtExpiresIn)),
+	refreshToken, refreshTokenHash, err := generateRefreshToken()
+	if err != nil {
+		c.Error(err)
+		return
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString([]byte(config.Config.JwtSecretKey))
+	sessionId, err := h.sessionsRepo.Create(c, user.Id, refreshTokenHash, time.Now().Add(config.Config.RefreshExpiresIn))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	h.respondWithTokens(c, user, sessionId, refreshToken)
+}
+
+// Refresh godoc
+// @Tags auth
+// @Summary      Refresh tokens
+// @Description  Exchanges a refresh token for a new access token and a new refresh token. Every refresh token can be used only once; reusing one signs out the whole session.
+// @Accept       json
+// @Produce      json
+// @Param request body handlers.RefreshRequest true "Request body"
+// @Success      200  {object} handlers.tokensResponse "OK"
+// @Failure   	 400  {object} models.Problem "Invalid payload"
+// @Failure   	 401  {object} models.Problem "Invalid refresh token"
+// @Failure   	 500  {object} models.Problem
+// @Router       /auth/refresh [post]
+func (h *AuthHandlers) Refresh(c *gin.Context) {
+	var request RefreshRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.Error(bindingError(err))
+		return
+	}
+
+	refreshToken, refreshTokenHash, err := generateRefreshToken()
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	sessionId, userId, err := h.sessionsRepo.Rotate(c, hashRefreshToken(request.RefreshToken), refreshTokenHash, time.Now().Add(config.Config.RefreshExpiresIn))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	user, err := h.usersRepo.FindById(c, userId)
+	if errors.Is(err, repositories.ErrUserNotFound) {
+		c.Error(repositories.ErrRefreshTokenInvalid)
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.NewApiError("Couldn't generate JWT token"))
+		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"token": tokenString})
+	h.respondWithTokens(c, user, sessionId, refreshToken)
 }
 
 // SignOut godoc
 // @Summary      Sign Out
+// @Description  Revokes the current session: its access token and refresh tokens stop working immediately.
 // @Tags auth
 // @Accept       json
 // @Produce      json
 // @Success      200   "OK"
+// @Failure   	 500  {object} models.Problem
 // @Router       /auth/signOut [post]
 // @Security Bearer
 func (h *AuthHandlers) SignOut(c *gin.Context) {
+	err := h.sessionsRepo.Revoke(c, c.GetString("sessionId"), c.GetString("tokenId"), c.GetTime("tokenExpiresAt"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
 	c.Status(http.StatusOK)
 }
 
+func (h *AuthHandlers) respondWithTokens(c *gin.Context, user models.User, sessionId string, refreshToken string) {
+	claims := models.AuthClaims {
+		Role: user.Role,
+		SessionId: sessionId,
+		RegisteredClaims: jwt.RegisteredClaims {
+			ID: uuid.NewString(),
+			Subject: strconv.Itoa(user.Id),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(config.Config.JwtExpiresIn)),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	tokenString, err := token.SignedString([]byte(config.Config.JwtSecretKey))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, tokensResponse{
+		Token:        tokenString,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int(config.Config.JwtExpiresIn.Seconds()),
+	})
+}
+
+// generateRefreshToken returns a random refresh token and the hash that is
+// stored in the database in its place.
+func generateRefreshToken() (string, string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", err
+	}
+
+	token := base64.RawURLEncoding.EncodeToString(buf)
+	return token, hashRefreshToken(token), nil
+}
+
+func hashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
 // GetUserInfo godoc
 // @Summary      Get user info
 // @Tags auth
 // @Accept       json
 // @Produce      json
 // @Success      200  {object} handlers.userResponse "OK"
-// @Failure   	 500  {object} models.ApiError
+// @Failure   	 500  {object} models.Problem
 // @Router       /auth/userInfo [get]
 // @Security Bearer
 func (h *AuthHandlers) GetUserInfo(c *gin.Context) {
 	userId := c.GetInt("userId")
 	user, err := h.usersRepo.FindById(c, userId)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		c.Error(err)
 		return
 	}
 
@@ -102,5 +226,6 @@ func (h *AuthHandlers) GetUserInfo(c *gin.Context) {
 		Id:		user.Id,
 		Email: 	user.Email,
 		Name: 	user.Name,
+		Role: 	user.Role,
 	})
 }
\ No newline at end of file
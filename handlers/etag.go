@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"goozinshe/repositories"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// entityTag is the ETag of a response body showing an entity at a version.
+// If-Match only compares the version, so changes of other users' ratings don't
+// fail an edit, while If-None-Match compares the hash of the body as well.
+func entityTag(version int, body []byte) string {
+	sum := sha256.Sum256(body)
+	return fmt.Sprintf(`"%d-%x"`, version, sum[:8])
+}
+
+// writeEntity responds with the body and its ETag, or with 304 Not Modified
+// to a GET whose If-None-Match lists the ETag.
+func writeEntity(c *gin.Context, version int, body any) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	tag := entityTag(version, data)
+	c.Header("ETag", tag)
+
+	if c.Request.Method == http.MethodGet && etagListed(c.GetHeader("If-None-Match"), tag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	c.Data(http.StatusOK, "application/json; charset=utf-8", data)
+}
+
+func etagListed(header string, tag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// ifMatchVersion checks the If-Match header of a change against the current
+// version of the entity. It returns the version the change must be applied
+// to, 0 when the request has no If-Match header or matches any version. It
+// records repositories.ErrVersionMismatch and returns false when no listed
+// ETag has the current version.
+func ifMatchVersion(c *gin.Context, current int) (int, bool) {
+	header := c.GetHeader("If-Match")
+	if header == "" || strings.TrimSpace(header) == "*" {
+		return 0, true
+	}
+
+	for _, candidate := range strings.Split(header, ",") {
+		// If-Match uses the strong comparison, weak tags never match.
+		candidate = strings.TrimSpace(candidate)
+		if strings.HasPrefix(candidate, "W/") {
+			continue
+		}
+		value, _, _ := strings.Cut(strings.Trim(candidate, `"`), "-")
+		if version, err := strconv.Atoi(value); err == nil && version == current {
+			return current, true
+		}
+	}
+
+	c.Error(repositories.ErrVersionMismatch)
+	return 0, false
+}
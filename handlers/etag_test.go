@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"errors"
+	"goozinshe/repositories"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestEntityTag(t *testing.T) {
+	tag := entityTag(3, []byte(`{"title":"Inception"}`))
+
+	if !regexp.MustCompile(`^"3-[0-9a-f]{16}"$`).MatchString(tag) {
+		t.Errorf("entityTag(3, ...) = %s, want a quoted version and hash", tag)
+	}
+	if again := entityTag(3, []byte(`{"title":"Inception"}`)); again != tag {
+		t.Errorf("entityTag is not stable: %s and %s", tag, again)
+	}
+	if other := entityTag(3, []byte(`{"title":"Начало"}`)); other == tag {
+		t.Errorf("entityTag of different bodies are both %s", tag)
+	}
+	if other := entityTag(4, []byte(`{"title":"Inception"}`)); other == tag {
+		t.Errorf("entityTag of different versions are both %s", tag)
+	}
+}
+
+func TestEtagListed(t *testing.T) {
+	tag := entityTag(3, []byte("{}"))
+
+	tests := []struct {
+		name   string
+		header string
+		want   bool
+	}{
+		{"no header", "", false},
+		{"same tag", tag, true},
+		{"any tag", "*", true},
+		{"weak tag", "W/" + tag, true},
+		{"in a list", `"1-0000000000000000", ` + tag, true},
+		{"other body", `"3-0000000000000000"`, false},
+		{"unquoted", tag[1 : len(tag)-1], false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := etagListed(tt.header, tag); got != tt.want {
+				t.Errorf("etagListed(%q) = %v, want %v", tt.header, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIfMatchVersion(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name        string
+		header      string
+		wantVersion int
+		wantOk      bool
+	}{
+		{"no header", "", 0, true},
+		{"any version", "*", 0, true},
+		{"current version", `"5-0123456789abcdef"`, 5, true},
+		{"only the version is compared", `"5-ffffffffffffffff"`, 5, true},
+		{"version without hash", `"5"`, 5, true},
+		{"in a list", `"4-0123456789abcdef", "5-0123456789abcdef"`, 5, true},
+		{"older version", `"4-0123456789abcdef"`, 0, false},
+		{"weak tag never matches", `W/"5-0123456789abcdef"`, 0, false},
+		{"not a version", `"abc"`, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest(http.MethodPut, "/movies/1", nil)
+			if tt.header != "" {
+				c.Request.Header.Set("If-Match", tt.header)
+			}
+
+			version, ok := ifMatchVersion(c, 5)
+			if version != tt.wantVersion || ok != tt.wantOk {
+				t.Errorf("ifMatchVersion(%q) = %d, %v, want %d, %v", tt.header, version, ok, tt.wantVersion, tt.wantOk)
+			}
+
+			mismatch := len(c.Errors) == 1 && errors.Is(c.Errors.Last().Err, repositories.ErrVersionMismatch)
+			if mismatch == tt.wantOk {
+				t.Errorf("ifMatchVersion(%q) recorded errors %v", tt.header, c.Errors)
+			}
+		})
+	}
+}
+
+func TestWriteEntity(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	body := map[string]string{"title": "Inception"}
+
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Request = httptest.NewRequest(http.MethodGet, "/movies/1", nil)
+	writeEntity(c, 2, body)
+
+	tag := recorder.Header().Get("ETag")
+	if recorder.Code != http.StatusOK || tag == "" {
+		t.Fatalf("writeEntity responded %d with ETag %q", recorder.Code, tag)
+	}
+
+	recorder = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(recorder)
+	c.Request = httptest.NewRequest(http.MethodGet, "/movies/1", nil)
+	c.Request.Header.Set("If-None-Match", tag)
+	writeEntity(c, 2, body)
+	c.Writer.WriteHeaderNow()
+
+	if recorder.Code != http.StatusNotModified {
+		t.Errorf("writeEntity with a matching If-None-Match responded %d, want %d", recorder.Code, http.StatusNotModified)
+	}
+	if recorder.Body.Len() != 0 {
+		t.Errorf("writeEntity with a matching If-None-Match sent a body %q", recorder.Body.String())
+	}
+}
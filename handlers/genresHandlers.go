@@ -12,6 +12,10 @@ type GenresHandler struct {
 	genresRepo *repositories.GenresRepository
 }
 
+type genreRequest struct {
+	Title string `json:"title" binding:"required,notblank,max=100"`
+}
+
 
 func NewGenresHandler(
 	genresRepo *repositories.GenresRepository,) *GenresHandler{
@@ -26,17 +30,26 @@ func NewGenresHandler(
 // @Accept       json
 // @Produce      json
 // @Param id path int true "Genre ID"
+// @Param If-None-Match header string false "ETag of a cached copy"
 // @Success      200  {object} models.Genre "OK"
-// @Failure   	 400  {object} models.ApiError "Validation error"
-// @Failure   	 500  {object} models.ApiError
+// @Header       200  {string} ETag "Version of the genre, for If-Match and If-None-Match"
+// @Success      304  "Not modified"
+// @Failure   	 400  {object} models.Problem "Validation error"
+// @Failure   	 500  {object} models.Problem
 // @Router       /genres/{id} [get]
 // @Security Bearer
 func (h *GenresHandler) FindAll(c *gin.Context) {
-	genres, err := h.genresRepo.FindAll(c)
+	page, ok := bindPageRequest(c)
+	if !ok {
+		return
+	}
+
+	genres, info, err := h.genresRepo.FindAll(c, c.GetStringSlice("locales"), page)
 	if err != nil {
-		c.Status(http.StatusInternalServerError)
+		c.Error(err)
 		return
 	}
+	writePageHeaders(c, page, info)
 	c.JSON(http.StatusOK, genres)
 }
 
@@ -45,9 +58,14 @@ func (h *GenresHandler) FindAll(c *gin.Context) {
 // @Summary      Get genres list
 // @Accept       json
 // @Produce      json
+// @Param page query int false "Page number, starting from 1"
+// @Param limit query int false "Page size (max 100)"
+// @Param cursor query string false "Opaque cursor from a Link header, takes precedence over page"
 // @Success      200  {array} models.Genre "OK"
-// @Failure   	 400  {object} models.ApiError "Validation error"
-// @Failure   	 500  {object} models.ApiError
+// @Header       200  {int} X-Total-Count "Total number of items"
+// @Header       200  {string} Link "Next and previous pages"
+// @Failure   	 400  {object} models.Problem "Invalid paging parameters"
+// @Failure   	 500  {object} models.Problem
 // @Router       /genres [get]
 // @Security Bearer
 func (h *GenresHandler) FindById(c *gin.Context) {
@@ -55,17 +73,17 @@ func (h *GenresHandler) FindById(c *gin.Context) {
 	id, err := strconv.Atoi(idStr)
 
 	if err != nil {
-		c.JSON(http.StatusBadRequest, models.NewApiError("Invalid genre id"))
+		c.Error(models.NewInvalidFieldError("id", "Invalid genre id"))
 		return
 	}
 
-	genre, err := h.genresRepo.FindById(c, id)
-    if err != nil {
-        c.JSON(http.StatusNotFound, models.NewApiError(err.Error()))
-        return
-    }
+	genre, err := h.genresRepo.FindById(c, id, c.GetStringSlice("locales"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
 
-	c.JSON(http.StatusOK, genre)
+	writeEntity(c, genre.Version, genre)
 }
 
 // Create godoc
@@ -73,24 +91,25 @@ func (h *GenresHandler) FindById(c *gin.Context) {
 // @Tags genres
 // @Accept       json
 // @Produce      json
-// @Param request body models.Genre true "Genre model"
+// @Param request body handlers.genreRequest true "Genre data"
 // @Success      200  {object} object{id=int}  "OK"
-// @Failure   	 400  {object} models.ApiError "Validation error"
-// @Failure   	 500  {object} models.ApiError
+// @Failure   	 400  {object} models.Problem "Validation error"
+// @Failure   	 403  {object} models.Problem "Insufficient permissions"
+// @Failure   	 500  {object} models.Problem
 // @Router       /genres [post]
 // @Security Bearer
 func (h *GenresHandler) Create(c *gin.Context) {
-	var createGenre models.Genre
+	var request genreRequest
 
-	err := c.BindJSON(&createGenre)
+	err := c.ShouldBindJSON(&request)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, models.NewApiError("Couldn't bind json"))
+		c.Error(bindingError(err))
 		return
 	}
 
-	id, err := h.genresRepo.Create(c, createGenre)
+	id, err := h.genresRepo.Create(c, models.Genre{Title: request.Title}, c.GetInt("userId"))
 	if err != nil {
-        c.JSON(http.StatusNotFound, models.NewApiError(err.Error()))
+        c.Error(err)
         return
     }
 
@@ -105,10 +124,13 @@ func (h *GenresHandler) Create(c *gin.Context) {
 // @Accept       json
 // @Produce      json
 // @Param id path int true "Genre id"
-// @Param request body models.Genre true "Genre model"
+// @Param request body handlers.genreRequest true "Genre data"
+// @Param If-Match header string false "ETag of the genre, the change fails with 412 when the genre was changed since"
 // @Success      200
-// @Failure   	 400  {object} models.ApiError "Validation error"
-// @Failure   	 500  {object} models.ApiError
+// @Failure   	 400  {object} models.Problem "Validation error"
+// @Failure   	 403  {object} models.Problem "Insufficient permissions"
+// @Failure   	 412  {object} models.Problem "Genre was changed by someone else"
+// @Failure   	 500  {object} models.Problem
 // @Router       /genres/{id} [put]
 // @Security Bearer
 func (h *GenresHandler) Update(c *gin.Context) {
@@ -116,42 +138,51 @@ func (h *GenresHandler) Update(c *gin.Context) {
 	id, err := strconv.Atoi(idStr)
 
 	if err != nil {
-		c.JSON(http.StatusBadRequest, models.NewApiError("Invalid genre id"))
+		c.Error(models.NewInvalidFieldError("id", "Invalid genre id"))
 		return
 	}
 
-	_, err = h.genresRepo.FindById(c, id)
+	existing, err := h.genresRepo.FindById(c, id, c.GetStringSlice("locales"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, models.NewApiError(err.Error()))
+		c.Error(err)
 		return
 	}
 
-	var updateGenre models.Genre
-	err = c.BindJSON(&updateGenre)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, models.NewApiError("Couldn't bind json"))
+	version, ok := ifMatchVersion(c, existing.Version)
+	if !ok {
 		return
 	}
 
+	var request genreRequest
+	err = c.ShouldBindJSON(&request)
+	if err != nil {
+		c.Error(bindingError(err))
+		return
+	}
 
-	err = h.genresRepo.Update(c, id, updateGenre)
+	err = h.genresRepo.Update(c, id, models.Genre{Title: request.Title, Version: version}, c.GetInt("userId"))
 	if err != nil {
-        c.JSON(http.StatusNotFound, models.NewApiError(err.Error()))
-        return
-    }
+		c.Error(err)
+		return
+	}
 
 	c.Status(http.StatusOK)
 }
 
 // Delete godoc
 // @Summary      Delete genre
+// @Description  Moves the genre to the trash. Movies keep it and get it back when it is restored. The only genre of a movie can't be deleted.
 // @Tags genres
 // @Accept       json
 // @Produce      json
 // @Param id path int true "Genre id"
+// @Param If-Match header string false "ETag of the genre, the change fails with 412 when the genre was changed since"
 // @Success      200
-// @Failure   	 400  {object} models.ApiError "Validation error"
-// @Failure   	 500  {object} models.ApiError
+// @Failure   	 400  {object} models.Problem "Validation error"
+// @Failure   	 403  {object} models.Problem "Insufficient permissions"
+// @Failure   	 409  {object} models.Problem "Genre is the only genre of a movie"
+// @Failure   	 412  {object} models.Problem "Genre was changed by someone else"
+// @Failure   	 500  {object} models.Problem
 // @Router       /genres/{id} [delete]
 // @Security Bearer
 func (h *GenresHandler) Delete(c *gin.Context) {
@@ -159,21 +190,26 @@ func (h *GenresHandler) Delete(c *gin.Context) {
 	id, err := strconv.Atoi(idStr)
 
 	if err != nil {
-		c.JSON(http.StatusBadRequest, models.NewApiError("Invalid genre id"))
+		c.Error(models.NewInvalidFieldError("id", "Invalid genre id"))
 		return
 	}
 
-	_, err = h.genresRepo.FindById(c, id)
+	existing, err := h.genresRepo.FindById(c, id, c.GetStringSlice("locales"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, models.NewApiError(err.Error()))
+		c.Error(err)
 		return
 	}
-	
-	err = h.genresRepo.Delete(c, id)
+
+	version, ok := ifMatchVersion(c, existing.Version)
+	if !ok {
+		return
+	}
+
+	err = h.genresRepo.Delete(c, id, version, c.GetInt("userId"))
 	if err != nil {
-        c.JSON(http.StatusNotFound, models.NewApiError(err.Error()))
-        return
-    }
+		c.Error(err)
+		return
+	}
 	c.Status(http.StatusOK)
 }
 
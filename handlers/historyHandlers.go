@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"goozinshe/models"
+	"goozinshe/repositories"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultHistoryLimit = 20
+	maxHistoryLimit     = 100
+)
+
+type HistoryHandler struct {
+	historyRepo *repositories.HistoryRepository
+}
+
+func NewHistoryHandler(historyRepo *repositories.HistoryRepository) *HistoryHandler {
+	return &HistoryHandler{historyRepo: historyRepo}
+}
+
+// FindAll godoc
+// @Summary      Get watch history
+// @Description  Returns the current user's watch events, newest first. Rewatches are listed as separate events.
+// @Tags history
+// @Accept       json
+// @Produce      json
+// @Param page query int false "Page number, starting from 1"
+// @Param limit query int false "Page size (max 100)"
+// @Success      200  {array} models.WatchEvent "OK"
+// @Failure   	 400  {object} models.Problem "Invalid paging parameters"
+// @Failure   	 500  {object} models.Problem
+// @Router       /me/history [get]
+// @Security Bearer
+func (h *HistoryHandler) FindAll(c *gin.Context) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		c.Error(models.NewInvalidFieldError("page", "Invalid page"))
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultHistoryLimit)))
+	if err != nil || limit < 1 || limit > maxHistoryLimit {
+		c.Error(models.NewInvalidFieldError("limit", "Invalid limit"))
+		return
+	}
+
+	events, err := h.historyRepo.FindAll(c, c.GetInt("userId"), c.GetStringSlice("locales"), page, limit)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, events)
+}
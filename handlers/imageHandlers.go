@@ -1,44 +1,207 @@
 package handlers
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"goozinshe/imaging"
+	"goozinshe/models"
+	"goozinshe/storage"
+	"io"
 	"net/http"
-	"os"
-	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"golang.org/x/sync/singleflight"
 )
 
-type imageHandlers struct{}
+// Images never change once uploaded, a new poster gets a new id.
+const imageCacheControl = "public, max-age=31536000, immutable"
 
-func NewImageHandlers() *imageHandlers {
-	return &imageHandlers{}
+var errInvalidImageId = models.NewInvalidFieldError("imageId", "Invalid image id")
+
+type imageHandlers struct {
+	storage    storage.Storage
+	generating singleflight.Group
+}
+
+func NewImageHandlers(storage storage.Storage) *imageHandlers {
+	return &imageHandlers{storage: storage}
 }
 
 // HandleGetImageById godoc
 // @Summary      Download image
+// @Description  Returns the image as uploaded, or a resized variant when size is set. Variants are WebP when the client accepts it, JPEG otherwise, unless format is set. Supports conditional and range requests.
 // @Tags images
 // @Accept       json
-// @Produce      application/octet-stream
-// @Param imageId path int true "image id"
-// @Success      200  {string} string "Image to download"
-// @Failure 400 {object} models.ApiError "Invalid image id"
-// @Failure   	 500  {object} models.ApiError
-// @Router       /images/:imageId [get]
+// @Produce      image/jpeg,image/webp,image/png
+// @Param imageId path string true "image id"
+// @Param size query string false "Variant: thumb (160px wide), card (400px) or full (1200px)"
+// @Param format query string false "Variant format: webp or jpeg"
+// @Success      200  {string} string "Image"
+// @Success      206  {string} string "Part of the image"
+// @Success      304 "Not modified"
+// @Failure 400 {object} models.Problem "Invalid image id, size or format"
+// @Failure 404 {object} models.Problem "Image not found"
+// @Failure   	 500  {object} models.Problem
+// @Router       /images/{imageId} [get]
 func (h *imageHandlers) HandleGetImageById(c *gin.Context) {
 	imageId := c.Param("imageId")
 	if imageId == "" {
-		c.JSON(http.StatusBadRequest, "Invalid image id")
+		c.Error(errInvalidImageId)
+		return
+	}
+
+	size := c.Query("size")
+	if size == "" {
+		object, err := h.storage.Get(c, imageId)
+		if err != nil {
+			c.Error(storageError(err))
+			return
+		}
+		serveImage(c, imageId, object)
+		return
+	}
+
+	if _, ok := imaging.FindVariant(size); !ok {
+		c.Error(models.NewInvalidFieldError("size", "Invalid image size"))
+		return
+	}
+
+	format := c.Query("format")
+	switch format {
+	case imaging.FormatWebP, imaging.FormatJPEG:
+	case "":
+		format = imaging.FormatJPEG
+		if strings.Contains(c.GetHeader("Accept"), "image/webp") {
+			format = imaging.FormatWebP
+		}
+		c.Header("Vary", "Accept")
+	default:
+		c.Error(models.NewInvalidFieldError("format", "Invalid image format"))
 		return
 	}
 
-	fileName := filepath.Base(imageId)
-	byteFile, err := os.ReadFile(fmt.Sprintf("images/%s", imageId))
+	key := imaging.VariantKey(imageId, size, format)
+	object, err := h.storage.Get(c, key)
+	if errors.Is(err, storage.ErrNotFound) {
+		object, err = h.generateVariants(c, imageId, key)
+	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, err.Error())
+		c.Error(storageError(err))
 		return
 	}
+	serveImage(c, key, object)
+}
+
+// generateVariants renders the variants of a poster uploaded before variants
+// were introduced and returns the one stored under key. Concurrent requests
+// for the same poster share one rendering.
+func (h *imageHandlers) generateVariants(c *gin.Context, imageId string, key string) (*storage.Object, error) {
+	// Variants have no variants of their own.
+	if _, ok := imaging.ParseVariantKey(imageId); ok {
+		return nil, storage.ErrInvalidKey
+	}
+
+	// The rendering is shared, so it must not be cancelled with the request
+	// that happened to start it.
+	ctx := context.WithoutCancel(c.Request.Context())
+	result, err, _ := h.generating.Do(imageId, func() (any, error) {
+		return h.storeVariants(ctx, imageId)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, rendition := range result.([]imaging.Rendition) {
+		if rendition.Key == key {
+			return &storage.Object{
+				Body:        io.NopCloser(bytes.NewReader(rendition.Data)),
+				Size:        int64(len(rendition.Data)),
+				ContentType: rendition.ContentType,
+				ModTime:     time.Now(),
+			}, nil
+		}
+	}
+	return nil, storage.ErrNotFound
+}
+
+// storeVariants renders every variant of the image stored under imageId and
+// stores them next to it.
+func (h *imageHandlers) storeVariants(ctx context.Context, imageId string) ([]imaging.Rendition, error) {
+	original, err := h.storage.Get(ctx, imageId)
+	if err != nil {
+		return nil, err
+	}
+	defer original.Body.Close()
+
+	data, err := io.ReadAll(original.Body)
+	if err != nil {
+		return nil, err
+	}
+	img, err := imaging.Decode(data)
+	if err != nil {
+		return nil, err
+	}
+
+	renditions, err := imaging.Generate(imageId, img)
+	if err != nil {
+		return nil, err
+	}
 
-	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", fileName))
-	c.Data(http.StatusOK, "application/octet-stream", byteFile)
-}
\ No newline at end of file
+	for _, rendition := range renditions {
+		err := h.storage.Put(ctx, rendition.Key, bytes.NewReader(rendition.Data), int64(len(rendition.Data)), rendition.ContentType)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return renditions, nil
+}
+
+// storageError tells clients which image couldn't be served. Other storage
+// errors are internal.
+func storageError(err error) error {
+	switch {
+	case errors.Is(err, storage.ErrInvalidKey):
+		return errInvalidImageId
+	case errors.Is(err, storage.ErrNotFound):
+		return models.NewNotFoundError("image_not_found", "Image not found")
+	case errors.Is(err, imaging.ErrUnsupportedImage), errors.Is(err, imaging.ErrImageTooLarge):
+		return models.NewNotFoundError("image_variant_not_found", "Image has no variants")
+	default:
+		return err
+	}
+}
+
+// serveImage sends the image inline with cache headers. http.ServeContent
+// answers conditional and range requests.
+func serveImage(c *gin.Context, name string, object *storage.Object) {
+	defer object.Body.Close()
+
+	content, ok := object.Body.(io.ReadSeeker)
+	if !ok {
+		data, err := io.ReadAll(object.Body)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+		content = bytes.NewReader(data)
+	}
+
+	if object.ContentType != "" {
+		c.Header("Content-Type", object.ContentType)
+	}
+	// Content addressed images are identified by the hash of their content,
+	// which makes it a strong ETag.
+	if hash, ok := imaging.ParseContentKey(name); ok {
+		c.Header("ETag", fmt.Sprintf(`"%s"`, hash))
+	} else if object.ETag != "" {
+		c.Header("ETag", object.ETag)
+	}
+	c.Header("Cache-Control", imageCacheControl)
+	c.Header("Content-Disposition", fmt.Sprintf("inline; filename=%q", name))
+
+	http.ServeContent(c.Writer, c.Request, name, object.ModTime, content)
+}
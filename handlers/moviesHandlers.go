@@ -1,59 +1,215 @@
 package handlers
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"goozinshe/imaging"
 	"goozinshe/models"
 	"goozinshe/repositories"
+	"goozinshe/storage"
+	"io"
+	"maps"
 	"mime/multipart"
 	"net/http"
-	"path/filepath"
+	"slices"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
-	"github.com/google/uuid"
+	"github.com/gin-gonic/gin/binding"
 )
 
 type MoviesHandler struct {
 	moviesRepo *repositories.MoviesRepository
 	genresRepo *repositories.GenresRepository
+	peopleRepo *repositories.PeopleRepository
+	imagesRepo *repositories.ImagesRepository
+	storage    storage.Storage
 }
 
 type createMovieRequest struct {
-	Title 		string					`form:"title"`
-	Description string					`form:"description"`
-	ReleaseYear int						`form:"releaseYear"`
-	Director 	string					`form:"director"`
-	TrailerUrl 	string					`form:"trailerUrl"`
-	GenreIds 	[]int					`form:"genreIds"`
+	Kind		string					`form:"kind" binding:"omitempty,oneof=movie series"`
+	Title 		string					`form:"title" binding:"required,notblank,max=200"`
+	Description string					`form:"description" binding:"required,notblank,max=5000"`
+	ReleaseYear int						`form:"releaseYear" binding:"required,release_year"`
+	Director 	string					`form:"director" binding:"required,notblank,max=200"`
+	TrailerUrl 	string					`form:"trailerUrl" binding:"omitempty,http_url"`
+	GenreIds 	[]int					`form:"genreIds" binding:"required,min=1,unique,dive,gt=0"`
 	Poster 		*multipart.FileHeader	`form:"poster"`
 }
 
 type updateMovieRequest struct {
-	Title       string                `form:"title"`
-	Description string                `form:"description"`
-	ReleaseYear int                   `form:"releaseYear"`
-	Director    string                `form:"director"`
-	TrailerUrl  string                `form:"trailerUrl"`
-	GenreIds    []int                 `form:"genreIds"`
+	Title       string                `form:"title" binding:"required,notblank,max=200"`
+	Description string                `form:"description" binding:"required,notblank,max=5000"`
+	ReleaseYear int                   `form:"releaseYear" binding:"required,release_year"`
+	Director    string                `form:"director" binding:"required,notblank,max=200"`
+	TrailerUrl  string                `form:"trailerUrl" binding:"omitempty,http_url"`
+	GenreIds    []int                 `form:"genreIds" binding:"required,min=1,unique,dive,gt=0"`
 	Poster      *multipart.FileHeader `form:"poster"`
 }
 
+// patchMovieRequest is a JSON Merge Patch of the movie metadata. Genres can
+// be replaced with genreIds or changed one by one with addGenreIds and
+// removeGenreIds.
+type patchMovieRequest struct {
+	Title          *string `json:"title" binding:"omitempty,notblank,max=200"`
+	Description    *string `json:"description" binding:"omitempty,notblank,max=5000"`
+	ReleaseYear    *int    `json:"releaseYear" binding:"omitempty,release_year"`
+	Director       *string `json:"director" binding:"omitempty,notblank,max=200"`
+	TrailerUrl     *string `json:"trailerUrl" binding:"omitempty,http_url"`
+	GenreIds       []int   `json:"genreIds" binding:"omitempty,unique,dive,gt=0"`
+	AddGenreIds    []int   `json:"addGenreIds" binding:"omitempty,unique,dive,gt=0"`
+	RemoveGenreIds []int   `json:"removeGenreIds" binding:"omitempty,unique,dive,gt=0"`
+}
+
+// setCreditsRequest lists all credits of a movie. Credits are billed in the
+// order they are listed within their role.
+type setCreditsRequest struct {
+	Credits []creditRequest `json:"credits" binding:"required,dive"`
+}
+
+type creditRequest struct {
+	PersonId  int    `json:"personId" binding:"required,gt=0"`
+	Role      string `json:"role" binding:"required,oneof=director actor writer composer"`
+	Character string `json:"character" binding:"max=200"`
+}
+
+// mergePatchContentType is the media type of JSON Merge Patch documents (RFC 7396).
+const mergePatchContentType = "application/merge-patch+json"
+
 func NewMoviesHandler(
 	genresRepo *repositories.GenresRepository,
-	moviesRepo *repositories.MoviesRepository) *MoviesHandler {
+	moviesRepo *repositories.MoviesRepository,
+	peopleRepo *repositories.PeopleRepository,
+	imagesRepo *repositories.ImagesRepository,
+	storage storage.Storage) *MoviesHandler {
 	return &MoviesHandler{
 		moviesRepo: moviesRepo,
 		genresRepo: genresRepo,
+		peopleRepo: peopleRepo,
+		imagesRepo: imagesRepo,
+		storage:    storage,
 	}
 }
 
 
-func (h *MoviesHandler) saveMoviePoster(c *gin.Context, poster *multipart.FileHeader) (string, error) {
-	filename := fmt.Sprintf("%s%s", uuid.NewString(), filepath.Ext(poster.Filename))
-	filepath := fmt.Sprintf("images/%s", filename)
-	err := c.SaveUploadedFile(poster, filepath)
+// readMoviePoster reads and validates the uploaded poster. A rejected poster
+// is returned as an *imaging.ValidationError.
+func readMoviePoster(poster *multipart.FileHeader) (*imaging.Poster, error) {
+	if poster == nil {
+		return nil, imaging.ErrPosterMissing
+	}
+
+	file, err := poster.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	// Read one byte more than allowed, so PreparePoster can tell the poster is too large.
+	data, err := io.ReadAll(io.LimitReader(file, imaging.MaxPosterBytes+1))
+	if err != nil {
+		return nil, err
+	}
 
-	return filename, err
+	return imaging.PreparePoster(data)
+}
+
+// saveMoviePoster stores a validated poster together with its resized
+// variants and returns the image id of the original. Image ids are content
+// hashes, so uploading the same poster again reuses the stored one.
+func (h *MoviesHandler) saveMoviePoster(c *gin.Context, prepared *imaging.Poster) (string, error) {
+	filename := imaging.ContentKey(prepared.Data, prepared.Extension)
+
+	// Track the image before storing it, so it is cleaned up if the movie is never saved.
+	created, err := h.imagesRepo.Register(c, models.Image{Id: filename, ContentType: prepared.ContentType, Size: int64(len(prepared.Data))})
+	if err != nil {
+		return "", err
+	}
+	if !created && h.isStored(c, filename) {
+		return filename, nil
+	}
+
+	renditions, err := imaging.Generate(filename, prepared.Image)
+	if err != nil {
+		return "", err
+	}
+
+	err = h.storage.Put(c, filename, bytes.NewReader(prepared.Data), int64(len(prepared.Data)), prepared.ContentType)
+	if err != nil {
+		return "", err
+	}
+
+	for _, rendition := range renditions {
+		err = h.storage.Put(c, rendition.Key, bytes.NewReader(rendition.Data), int64(len(rendition.Data)), rendition.ContentType)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return filename, nil
+}
+
+// isStored reports whether an identical poster was stored before. Variants
+// are stored after the original, so they exist too.
+func (h *MoviesHandler) isStored(c *gin.Context, key string) bool {
+	object, err := h.storage.Get(c, key)
+	if err != nil {
+		return false
+	}
+	object.Body.Close()
+	return true
+}
+
+// posterError turns a rejected upload into a validation error of the poster
+// field, which keeps the code of the poster check that failed.
+func posterError(err error) error {
+	var validationErr *imaging.ValidationError
+	if !errors.As(err, &validationErr) {
+		return err
+	}
+
+	return &models.ValidationError{
+		Code:    validationErr.Code,
+		Message: validationErr.Message,
+		Fields:  []models.FieldError{{Field: "poster", Code: validationErr.Code, Message: validationErr.Message}},
+	}
+}
+
+// addPosterViolation adds a rejected upload to the violations of a payload.
+// Other errors are returned.
+func addPosterViolation(fields *violations, err error) error {
+	var validationErr *imaging.ValidationError
+	if !errors.As(err, &validationErr) {
+		return err
+	}
+
+	fields.add("poster", validationErr.Code, validationErr.Message)
+	return nil
+}
+
+// findGenres returns the genres with the ids. Ids of genres that don't exist
+// are added to the violations as elements of the field, e.g. genreIds[2].
+func (h *MoviesHandler) findGenres(c *gin.Context, field string, ids []int, fields *violations) ([]models.Genre, error) {
+	if len(ids) == 0 {
+		return []models.Genre{}, nil
+	}
+
+	genres, err := h.genresRepo.FindAllByIds(c, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, id := range ids {
+		// Ids that aren't positive already broke a binding rule.
+		exists := slices.ContainsFunc(genres, func(genre models.Genre) bool { return genre.Id == id })
+		if id > 0 && !exists {
+			fields.add(fmt.Sprintf("%s[%d]", field, i), models.CodeNotFound, fmt.Sprintf("genre %d doesn't exist", id))
+		}
+	}
+	return genres, nil
 }
 
 // FindById godoc
@@ -62,24 +218,33 @@ func (h *MoviesHandler) saveMoviePoster(c *gin.Context, poster *multipart.FileHe
 // @Accept       json
 // @Produce      json
 // @Param id path int true "Movie id"
+// @Param If-None-Match header string false "ETag of a cached copy"
 // @Success      200  {object} models.Movie "OK"
-// @Failure   	 400  {object} models.ApiError "Invalid movie id"
-// @Failure   	 500  {object} models.ApiError
+// @Header       200  {string} ETag "Version of the movie, for If-Match and If-None-Match"
+// @Success      304  "Not modified"
+// @Failure   	 400  {object} models.Problem "Invalid movie id"
+// @Failure   	 404  {object} models.Problem "Movie not found"
+// @Failure   	 500  {object} models.Problem
 // @Router       /movies/{id} [get]
 // @Security Bearer
 func (h *MoviesHandler) FindAll(c *gin.Context) {
-	filters := models.MovieFilters {
-		SearchTerm: c.Query("search"),
-		IsWatched: 	c.Query("iswatched"),
-		GenreId: 	c.Query("genreids"),
-		Sort: 		c.Query("sort"),
+	filters, err := parseMovieFilters(c)
+	if err != nil {
+		c.Error(err)
+		return
 	}
 
-	movies, err := h.moviesRepo.FindAll(c, filters)
+	page, ok := bindPageRequest(c)
+	if !ok {
+		return
+	}
+
+	movies, info, err := h.moviesRepo.FindAll(c, c.GetInt("userId"), c.GetStringSlice("locales"), filters, page)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, err)
+		c.Error(err)
 		return
 	}
+	writePageHeaders(c, page, info)
 	c.JSON(http.StatusOK, movies)
 }
 
@@ -88,9 +253,16 @@ func (h *MoviesHandler) FindAll(c *gin.Context) {
 // @Tags movies
 // @Accept       json
 // @Produce      json
-// @Param filters query models.MovieFilters true "Movie filters"
-// @Success      200  {object} models.Movie "OK"
-// @Failure   	 500  {object} models.ApiError
+// @Param filters query models.MovieFilters false "Movie filters"
+// @Param sort query string false "Comma separated sort keys: title, releaseYear, rating, averageRating, createdAt, addedToWatchlistAt. Prefix a key with - to sort descending, e.g. -rating,title"
+// @Param page query int false "Page number, starting from 1"
+// @Param limit query int false "Page size (max 100)"
+// @Param cursor query string false "Opaque cursor from a Link header, takes precedence over page"
+// @Success      200  {array} models.Movie "OK"
+// @Header       200  {int} X-Total-Count "Total number of items"
+// @Header       200  {string} Link "Next and previous pages"
+// @Failure   	 400  {object} models.Problem "Invalid filter, paging or sort parameters"
+// @Failure   	 500  {object} models.Problem
 // @Router       /movies [get]
 // @Security Bearer
 func (h *MoviesHandler) FindById(c *gin.Context) {
@@ -98,17 +270,17 @@ func (h *MoviesHandler) FindById(c *gin.Context) {
 	id, err := strconv.Atoi(idStr)
 
 	if err != nil {
-		c.JSON(http.StatusBadRequest, models.NewApiError("Invalid movie id"))
+		c.Error(models.NewInvalidFieldError("id", "Invalid movie id"))
 		return
 	}
 
-	movie, err := h.moviesRepo.FindById(c, id)
-    if err != nil {
-        c.JSON(http.StatusNotFound, models.NewApiError(err.Error()))
-        return
-    }
+	movie, err := h.moviesRepo.FindById(c, id, c.GetInt("userId"), c.GetStringSlice("locales"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
 
-	c.JSON(http.StatusOK, movie)
+	writeEntity(c, movie.Version, movie)
 }
 
 // Create godoc
@@ -116,40 +288,59 @@ func (h *MoviesHandler) FindById(c *gin.Context) {
 // @Tags movies
 // @Accept       multipart/form-data
 // @Produce      json
+// @Param kind formData string false "movie (default) or series, can't be changed later"
 // @Param title formData string true "Title"
 // @Param description formData string true "Description"
 // @Param releaseYear formData int true "Year of release"
 // @Param director formData string true "Director"
-// @Param trailerUrl formData string true "Trailer URL"
+// @Param trailerUrl formData string false "Trailer URL, http or https"
 // @Param genreIds formData []int true "Genre ids"
-// @Param poster formData file true "Poster image"
+// @Param poster formData file true "Poster image: JPEG, PNG or WebP, at most 10 MB, 200x200 to 8000x8000 pixels"
 // @Success      200  {object} object{id=int} "OK"
-// @Failure   	 400  {object} models.ApiError "Invalid data"
-// @Failure   	 500  {object} models.ApiError
+// @Failure   	 400  {object} models.Problem "Invalid data or poster, see errors"
+// @Failure   	 403  {object} models.Problem "Insufficient permissions"
+// @Failure   	 500  {object} models.Problem
 // @Router       /movies [post]
 // @Security Bearer
 func (h *MoviesHandler) Create(c *gin.Context) {
 	var request createMovieRequest
 
-	err := c.Bind(&request)
+	fields, err := ruleViolations(c.ShouldBind(&request))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, models.NewApiError("Couldn't bind payload"))
+		c.Error(err)
 		return
 	}
 
-	genres, err := h.genresRepo.FindAllByIds(c, request.GenreIds)
+	genres, err := h.findGenres(c, "genreIds", request.GenreIds, &fields)
 	if err != nil {
-        c.JSON(http.StatusNotFound, models.NewApiError(err.Error()))
-        return
-    }
+		c.Error(err)
+		return
+	}
+
+	poster, err := readMoviePoster(request.Poster)
+	if err = addPosterViolation(&fields, err); err != nil {
+		c.Error(err)
+		return
+	}
+
+	if err := fields.err(); err != nil {
+		c.Error(err)
+		return
+	}
 
-	filename, err := h.saveMoviePoster(c, request.Poster)
+	filename, err := h.saveMoviePoster(c, poster)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		c.Error(err)
 		return
 	}
 
+	kind := request.Kind
+	if kind == "" {
+		kind = models.KindMovie
+	}
+
 	movie := models.Movie {
+		Kind:			kind,
 		Title: 			request.Title,
 		Description:	request.Description,
 		ReleaseYear:	request.ReleaseYear,
@@ -159,9 +350,9 @@ func (h *MoviesHandler) Create(c *gin.Context) {
 		Genres: 		genres,
 	}
 
-	id, err := h.moviesRepo.Create(c, movie)
+	id, err := h.moviesRepo.Create(c, movie, c.GetInt("userId"))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, err)
+		c.Error(err)
 		return
 	}
 
@@ -180,12 +371,16 @@ func (h *MoviesHandler) Create(c *gin.Context) {
 // @Param description formData string true "Description"
 // @Param releaseYear formData int true "Year of release"
 // @Param director formData string true "Director"
-// @Param trailerUrl formData string true "Trailer URL"
+// @Param trailerUrl formData string false "Trailer URL, http or https"
 // @Param genreIds formData []int true "Genre ids"
-// @Param poster formData file true "Poster image"
+// @Param poster formData file false "New poster image, the current one is kept when omitted"
+// @Param If-Match header string false "ETag of the movie, the change fails with 412 when the movie was changed since"
 // @Success      200  {object} object{id=int} "OK"
-// @Failure   	 400  {object} models.ApiError "Invalid data"
-// @Failure   	 500  {object} models.ApiError
+// @Failure   	 400  {object} models.Problem "Invalid data or poster, see errors"
+// @Failure   	 403  {object} models.Problem "Insufficient permissions"
+// @Failure   	 412  {object} models.Problem "Movie was changed by someone else"
+// @Failure   	 404  {object} models.Problem "Movie not found"
+// @Failure   	 500  {object} models.Problem
 // @Router       /movies/{id} [put]
 // @Security Bearer
 func (h *MoviesHandler) Update(c *gin.Context) {
@@ -193,35 +388,57 @@ func (h *MoviesHandler) Update(c *gin.Context) {
 	id, err := strconv.Atoi(idStr)
 
 	if err != nil {
-		c.JSON(http.StatusBadRequest, models.NewApiError("Invalid movie id"))
+		c.Error(models.NewInvalidFieldError("id", "Invalid movie id"))
 		return
 	}
 
-	_, err = h.moviesRepo.FindById(c, id)
+	existing, err := h.moviesRepo.FindById(c, id, c.GetInt("userId"), c.GetStringSlice("locales"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, models.NewApiError(err.Error()))
+		c.Error(err)
+		return
+	}
+
+	version, ok := ifMatchVersion(c, existing.Version)
+	if !ok {
 		return
 	}
 
 	var request updateMovieRequest
-	err = c.Bind(&request)
+	fields, err := ruleViolations(c.ShouldBind(&request))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, models.NewApiError("Couldn't bind json"))
+		c.Error(err)
 		return
 	}
 
-	genres, err := h.genresRepo.FindAllByIds(c, request.GenreIds)
+	genres, err := h.findGenres(c, "genreIds", request.GenreIds, &fields)
 	if err != nil {
-        c.JSON(http.StatusNotFound, models.NewApiError(err.Error()))
-        return
-    }
+		c.Error(err)
+		return
+	}
 
-	filename, err := h.saveMoviePoster(c, request.Poster)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+	var poster *imaging.Poster
+	if request.Poster != nil {
+		poster, err = readMoviePoster(request.Poster)
+		if err = addPosterViolation(&fields, err); err != nil {
+			c.Error(err)
+			return
+		}
+	}
+
+	if err := fields.err(); err != nil {
+		c.Error(err)
 		return
 	}
 
+	filename := existing.PosterUrl
+	if poster != nil {
+		filename, err = h.saveMoviePoster(c, poster)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+	}
+
 	movie := models.Movie {
 		Title:       request.Title,
 		Description: request.Description,
@@ -229,23 +446,361 @@ func (h *MoviesHandler) Update(c *gin.Context) {
 		Director:    request.Director,
 		TrailerUrl:  request.TrailerUrl,
 		PosterUrl:   filename,
+		Version:     version,
 		Genres:      genres,
 	}
 
-	h.moviesRepo.Update(c, id, movie)
+	err = h.moviesRepo.Update(c, id, movie, c.GetInt("userId"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
 
 	c.Status(http.StatusOK)
 }
 
+// Patch godoc
+// @Summary      Partially update movie
+// @Description  Applies a JSON Merge Patch to the movie metadata. Fields that are left out keep their value, a null trailerUrl removes the trailer. genreIds replaces all genres, addGenreIds and removeGenreIds add or remove single genres. The poster is changed with PUT /movies/{id}/poster.
+// @Tags movies
+// @Accept       application/merge-patch+json
+// @Produce      json
+// @Param id path int true "Movie id"
+// @Param request body handlers.patchMovieRequest true "Changes"
+// @Param If-Match header string false "ETag of the movie, the change fails with 412 when the movie was changed since"
+// @Success      200  {object} models.Movie "Updated movie"
+// @Header       200  {string} ETag "New version of the movie"
+// @Failure   	 400  {object} models.Problem "Invalid data"
+// @Failure   	 403  {object} models.Problem "Insufficient permissions"
+// @Failure   	 404  {object} models.Problem "Movie not found"
+// @Failure   	 412  {object} models.Problem "Movie was changed by someone else"
+// @Failure   	 415  {object} models.Problem "Body is not a merge patch"
+// @Failure   	 500  {object} models.Problem
+// @Router       /movies/{id} [patch]
+// @Security Bearer
+func (h *MoviesHandler) Patch(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+
+	if err != nil {
+		c.Error(models.NewInvalidFieldError("id", "Invalid movie id"))
+		return
+	}
+
+	if c.ContentType() != mergePatchContentType {
+		c.Error(models.NewUnsupportedMediaTypeError("unsupported_media_type", "Content-Type must be "+mergePatchContentType))
+		return
+	}
+
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.Error(models.NewValidationError("Couldn't read payload"))
+		return
+	}
+
+	patch, fields, err := parseMoviePatch(body)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	existing, err := h.moviesRepo.FindById(c, id, c.GetInt("userId"), c.GetStringSlice("locales"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	version, ok := ifMatchVersion(c, existing.Version)
+	if !ok {
+		return
+	}
+	patch.Version = version
+
+	if len(fields) == 0 && patch.IsEmpty() {
+		writeEntity(c, existing.Version, existing)
+		return
+	}
+
+	err = h.checkPatchGenres(c, existing, patch, &fields)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	if err := fields.err(); err != nil {
+		c.Error(err)
+		return
+	}
+
+	err = h.moviesRepo.Patch(c, id, patch, c.GetInt("userId"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	movie, err := h.moviesRepo.FindById(c, id, c.GetInt("userId"), c.GetStringSlice("locales"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	writeEntity(c, movie.Version, movie)
+}
+
+// parseMoviePatch reads a merge patch document and returns the members that
+// break its rules. Members set to null are removed, which only the trailer
+// allows.
+func parseMoviePatch(body []byte) (models.MoviePatch, violations, error) {
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(body, &members); err != nil || members == nil {
+		return models.MoviePatch{}, nil, models.NewValidationError("payload must be a JSON object")
+	}
+
+	var request patchMovieRequest
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&request); err != nil {
+		return models.MoviePatch{}, nil, bindingError(err)
+	}
+
+	fields, err := ruleViolations(binding.Validator.ValidateStruct(&request))
+	if err != nil {
+		return models.MoviePatch{}, nil, err
+	}
+
+	names := slices.Sorted(maps.Keys(members))
+	for _, name := range names {
+		if string(members[name]) != "null" {
+			continue
+		}
+		if name != "trailerUrl" {
+			fields.add(name, models.CodeInvalid, fmt.Sprintf("%s can't be removed", name))
+			continue
+		}
+		request.TrailerUrl = new(string)
+	}
+
+	return models.MoviePatch{
+		Title:          request.Title,
+		Description:    request.Description,
+		ReleaseYear:    request.ReleaseYear,
+		Director:       request.Director,
+		TrailerUrl:     request.TrailerUrl,
+		GenreIds:       request.GenreIds,
+		AddGenreIds:    request.AddGenreIds,
+		RemoveGenreIds: request.RemoveGenreIds,
+	}, fields, nil
+}
+
+// checkPatchGenres verifies that the genres the patch sets or adds exist and
+// that the movie keeps at least one genre.
+func (h *MoviesHandler) checkPatchGenres(c *gin.Context, existing models.Movie, patch models.MoviePatch, fields *violations) error {
+	genreIds := make([]int, 0, len(existing.Genres))
+	if patch.GenreIds != nil {
+		genreIds = append(genreIds, patch.GenreIds...)
+	} else {
+		for _, genre := range existing.Genres {
+			genreIds = append(genreIds, genre.Id)
+		}
+	}
+	genreIds = append(genreIds, patch.AddGenreIds...)
+
+	if _, err := h.findGenres(c, "genreIds", patch.GenreIds, fields); err != nil {
+		return err
+	}
+	if _, err := h.findGenres(c, "addGenreIds", patch.AddGenreIds, fields); err != nil {
+		return err
+	}
+
+	genreIds = slices.DeleteFunc(genreIds, func(id int) bool {
+		return slices.Contains(patch.RemoveGenreIds, id)
+	})
+	if len(genreIds) == 0 {
+		fields.add("genreIds", models.CodeInvalid, "a movie needs at least one genre")
+	}
+	return nil
+}
+
+// SetCredits godoc
+// @Summary      Replace movie credits
+// @Description  Replaces the directors, actors, writers and composers of the movie. Credits are billed in the order they are listed within their role, only actors have a character. The director of the movie becomes the names of its directors.
+// @Tags movies
+// @Accept       json
+// @Produce      json
+// @Param id path int true "Movie id"
+// @Param request body handlers.setCreditsRequest true "All credits of the movie"
+// @Param If-Match header string false "ETag of the movie, the change fails with 412 when the movie was changed since"
+// @Success      200  {object} models.Movie "Updated movie"
+// @Header       200  {string} ETag "New version of the movie"
+// @Failure   	 400  {object} models.Problem "Invalid data"
+// @Failure   	 403  {object} models.Problem "Insufficient permissions"
+// @Failure   	 404  {object} models.Problem "Movie not found"
+// @Failure   	 412  {object} models.Problem "Movie was changed by someone else"
+// @Failure   	 500  {object} models.Problem
+// @Router       /movies/{id}/credits [put]
+// @Security Bearer
+func (h *MoviesHandler) SetCredits(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+
+	if err != nil {
+		c.Error(models.NewInvalidFieldError("id", "Invalid movie id"))
+		return
+	}
+
+	existing, err := h.moviesRepo.FindById(c, id, c.GetInt("userId"), c.GetStringSlice("locales"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	version, ok := ifMatchVersion(c, existing.Version)
+	if !ok {
+		return
+	}
+
+	var request setCreditsRequest
+	fields, err := ruleViolations(c.ShouldBindJSON(&request))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	credits, err := h.checkCredits(c, request.Credits, &fields)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	if err := fields.err(); err != nil {
+		c.Error(err)
+		return
+	}
+
+	err = h.moviesRepo.SetCredits(c, id, credits, version, c.GetInt("userId"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	movie, err := h.moviesRepo.FindById(c, id, c.GetInt("userId"), c.GetStringSlice("locales"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	writeEntity(c, movie.Version, movie)
+}
+
+// checkCredits verifies that the credited people exist, that only actors
+// have a character and that nobody is credited twice for the same part.
+func (h *MoviesHandler) checkCredits(c *gin.Context, requests []creditRequest, fields *violations) ([]models.Credit, error) {
+	personIds := make([]int, 0, len(requests))
+	for _, request := range requests {
+		personIds = append(personIds, request.PersonId)
+	}
+
+	people, err := h.peopleRepo.FindAllByIds(c, personIds)
+	if err != nil {
+		return nil, err
+	}
+
+	credits := make([]models.Credit, 0, len(requests))
+	for i, request := range requests {
+		credit := models.Credit{PersonId: request.PersonId, Role: request.Role, Character: strings.TrimSpace(request.Character)}
+		path := fmt.Sprintf("credits[%d]", i)
+
+		// Ids that aren't positive already broke a binding rule.
+		exists := slices.ContainsFunc(people, func(person models.Person) bool { return person.Id == credit.PersonId })
+		if credit.PersonId > 0 && !exists {
+			fields.add(path+".personId", models.CodeNotFound, fmt.Sprintf("person %d doesn't exist", credit.PersonId))
+		}
+		if credit.Character != "" && credit.Role != models.CreditActor {
+			fields.add(path+".character", models.CodeInvalid, "only actors have a character")
+		}
+		if slices.Contains(credits, credit) {
+			fields.add(path, models.CodeInvalid, "the person is already credited for this part")
+		}
+		credits = append(credits, credit)
+	}
+	return credits, nil
+}
+
+// UpdatePoster godoc
+// @Summary      Replace movie poster
+// @Tags movies
+// @Accept       multipart/form-data
+// @Produce      json
+// @Param id path int true "Movie id"
+// @Param poster formData file true "Poster image: JPEG, PNG or WebP, at most 10 MB, 200x200 to 8000x8000 pixels"
+// @Param If-Match header string false "ETag of the movie, the change fails with 412 when the movie was changed since"
+// @Success      200  {object} object{posterUrl=string} "OK"
+// @Failure   	 400  {object} models.Problem "Invalid poster, see code"
+// @Failure   	 403  {object} models.Problem "Insufficient permissions"
+// @Failure   	 404  {object} models.Problem "Movie not found"
+// @Failure   	 412  {object} models.Problem "Movie was changed by someone else"
+// @Failure   	 500  {object} models.Problem
+// @Router       /movies/{id}/poster [put]
+// @Security Bearer
+func (h *MoviesHandler) UpdatePoster(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+
+	if err != nil {
+		c.Error(models.NewInvalidFieldError("id", "Invalid movie id"))
+		return
+	}
+
+	existing, err := h.moviesRepo.FindById(c, id, c.GetInt("userId"), c.GetStringSlice("locales"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	version, ok := ifMatchVersion(c, existing.Version)
+	if !ok {
+		return
+	}
+
+	// A missing file is reported by readMoviePoster.
+	upload, _ := c.FormFile("poster")
+	poster, err := readMoviePoster(upload)
+	if err != nil {
+		c.Error(posterError(err))
+		return
+	}
+
+	filename, err := h.saveMoviePoster(c, poster)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	err = h.moviesRepo.SetPoster(c, id, filename, version, c.GetInt("userId"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"posterUrl": filename,
+	})
+}
+
 // Delete godoc
 // @Summary      Delete movie
+// @Description  Moves the movie to the trash. Its ratings, watchlist items and watch history come back when it is restored.
 // @Tags movies
 // @Accept       json
 // @Produce      json
 // @Param id path int true "Movie id"
+// @Param If-Match header string false "ETag of the movie, the change fails with 412 when the movie was changed since"
 // @Success      200  "OK"
-// @Failure   	 400  {object} models.ApiError "Invalid data"
-// @Failure   	 500  {object} models.ApiError
+// @Failure   	 400  {object} models.Problem "Invalid data"
+// @Failure   	 403  {object} models.Problem "Insufficient permissions"
+// @Failure   	 412  {object} models.Problem "Movie was changed by someone else"
+// @Failure   	 404  {object} models.Problem "Movie not found"
+// @Failure   	 500  {object} models.Problem
 // @Router       /movies/{id} [delete]
 // @Security Bearer
 func (h *MoviesHandler) Delete(c *gin.Context) {
@@ -253,51 +808,93 @@ func (h *MoviesHandler) Delete(c *gin.Context) {
 	id, err := strconv.Atoi(idStr)
 
 	if err != nil {
-		c.JSON(http.StatusBadRequest, models.NewApiError("Invalid movie id"))
+		c.Error(models.NewInvalidFieldError("id", "Invalid movie id"))
 		return
 	}
 
-	_, err = h.moviesRepo.FindById(c, id)
+	existing, err := h.moviesRepo.FindById(c, id, c.GetInt("userId"), c.GetStringSlice("locales"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, models.NewApiError(err.Error()))
+		c.Error(err)
+		return
+	}
+
+	version, ok := ifMatchVersion(c, existing.Version)
+	if !ok {
+		return
+	}
+
+	err = h.moviesRepo.Delete(c, id, version, c.GetInt("userId"))
+	if err != nil {
+		c.Error(err)
 		return
 	}
-	
-	h.moviesRepo.Delete(c, id)
 	c.Status(http.StatusOK)
 }
 
 // HandleSetRating godoc
 // @Summary      Set movie rating
+// @Description  Sets the current user's own rating. Other users' ratings are not affected.
 // @Tags movies
 // @Accept       json
 // @Produce      json
 // @Param id path int true "Movie id"
 // @Param rating query int true "Movie rating"
 // @Success      200  "OK"
-// @Failure   	 400  {object} models.ApiError "Invalid data"
-// @Failure   	 500  {object} models.ApiError
+// @Failure   	 400  {object} models.Problem "Invalid data"
+// @Failure   	 404  {object} models.Problem "Movie not found"
+// @Failure   	 500  {object} models.Problem
 // @Router       /movies/{id}/rate [patch]
 // @Security Bearer
 func (h *MoviesHandler) SetRating(c *gin.Context) {
-	idStr := c.Param("movieId")
+	idStr := c.Param("id")
 	id, err := strconv.Atoi(idStr)
 
 	if err != nil {
-		c.JSON(http.StatusBadRequest, models.NewApiError("Invalid movie id"))
+		c.Error(models.NewInvalidFieldError("id", "Invalid movie id"))
 		return
 	}
 
 	ratingStr := c.Query("rating")
 	rating, err := strconv.Atoi(ratingStr)
 	if err != nil || rating < 1 || rating > 5 {
-		c.JSON(http.StatusBadRequest, models.NewApiError("Invalid rating value"))
+		c.Error(models.NewInvalidFieldError("rating", "Invalid rating value"))
+		return
+	}
+
+	err = h.moviesRepo.SetRating(c, id, c.GetInt("userId"), rating)
+	if err != nil {
+		c.Error(err)
 		return
 	}
 
-	h.moviesRepo.SetRating(c, id, rating)
+	c.Status(http.StatusOK)
+}
+
+// HandleDeleteRating godoc
+// @Summary      Remove movie rating
+// @Description  Removes the current user's own rating.
+// @Tags movies
+// @Accept       json
+// @Produce      json
+// @Param id path int true "Movie id"
+// @Success      200  "OK"
+// @Failure   	 400  {object} models.Problem "Invalid data"
+// @Failure   	 404  {object} models.Problem "Movie not found"
+// @Failure   	 500  {object} models.Problem
+// @Router       /movies/{id}/rate [delete]
+// @Security Bearer
+func (h *MoviesHandler) DeleteRating(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		c.Error(models.NewInvalidFieldError("id", "Invalid movie id"))
+		return
+	}
+
+	err = h.moviesRepo.DeleteRating(c, id, c.GetInt("userId"))
+	if err != nil {
+		c.Error(err)
 		return
 	}
 
@@ -306,38 +903,137 @@ func (h *MoviesHandler) SetRating(c *gin.Context) {
 
 // HandleSetWatched godoc
 // @Summary      Mark movie as watched
+// @Description  Updates the current user's watched state. Marking a movie as watched adds an entry to the user's watch history. Series are marked through their seasons and episodes instead.
 // @Tags movies
 // @Accept       json
 // @Produce      json
 // @Param id path int true "Movie id"
 // @Param isWatched query bool true "Flag value"
 // @Success      200  "OK"
-// @Failure   	 400  {object} models.ApiError "Invalid data"
-// @Failure   	 500  {object} models.ApiError
+// @Failure   	 400  {object} models.Problem "Invalid data, or the movie is a series"
+// @Failure   	 404  {object} models.Problem "Movie not found"
+// @Failure   	 500  {object} models.Problem
 // @Router       /movies/{id}/setWatched [patch]
 // @Security Bearer
 func (h *MoviesHandler) SetWatched(c *gin.Context) {
-	idStr := c.Param("movieId")
+	idStr := c.Param("id")
 	id, err := strconv.Atoi(idStr)
 
 	if err != nil {
-		c.JSON(http.StatusBadRequest, models.NewApiError("Invalid movie id"))
+		c.Error(models.NewInvalidFieldError("id", "Invalid movie id"))
 		return
 	}
 
 	isWatchedStr := c.Query("isWatched")
 	isWatched, err := strconv.ParseBool(isWatchedStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, models.NewApiError("Invalid IsWhatched Value"))
+		c.Error(models.NewInvalidFieldError("isWatched", "Invalid isWatched value"))
 		return
 	}
 
-	err = h.moviesRepo.SetWatched(c, id, isWatched)
+	err = h.moviesRepo.SetWatched(c, id, c.GetInt("userId"), isWatched)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		c.Error(err)
 		return
 	}
 
 	c.Status(http.StatusOK)
 	
-}
\ No newline at end of file
+}
+
+func parseMovieFilters(c *gin.Context) (models.MovieFilters, error) {
+	filters := models.MovieFilters{
+		SearchTerm: c.Query("search"),
+		Director:   c.Query("director"),
+		GenreMatch: c.DefaultQuery("genrematch", models.GenreMatchAny),
+	}
+
+	if filters.GenreMatch != models.GenreMatchAny && filters.GenreMatch != models.GenreMatchAll {
+		return filters, models.NewInvalidFieldError("genrematch", "genrematch must be any or all")
+	}
+
+	if value := c.Query("genreids"); value != "" {
+		for _, part := range strings.Split(value, ",") {
+			id, err := strconv.Atoi(strings.TrimSpace(part))
+			if err != nil {
+				return filters, models.NewInvalidFieldError("genreids", fmt.Sprintf("invalid genre id %q", part))
+			}
+			if !slices.Contains(filters.GenreIds, id) {
+				filters.GenreIds = append(filters.GenreIds, id)
+			}
+		}
+	}
+
+	filters.Kind = c.Query("kind")
+	if filters.Kind != "" && !slices.Contains(models.MovieKinds, filters.Kind) {
+		return filters, models.NewInvalidFieldError("kind", "kind must be one of: "+strings.Join(models.MovieKinds, ", "))
+	}
+
+	var err error
+	if filters.YearFrom, err = intQuery(c, "yearfrom"); err != nil {
+		return filters, err
+	}
+	if filters.YearTo, err = intQuery(c, "yearto"); err != nil {
+		return filters, err
+	}
+	if filters.YearFrom != 0 && filters.YearTo != 0 && filters.YearFrom > filters.YearTo {
+		return filters, models.NewInvalidFieldError("yearfrom", "yearfrom must not be after yearto")
+	}
+
+	if filters.PersonId, err = intQuery(c, "personid"); err != nil {
+		return filters, err
+	}
+	filters.PersonRole = c.Query("personrole")
+	if filters.PersonRole != "" && !slices.Contains(models.CreditRoles, filters.PersonRole) {
+		return filters, models.NewInvalidFieldError("personrole", "personrole must be one of: "+strings.Join(models.CreditRoles, ", "))
+	}
+	if filters.PersonRole != "" && filters.PersonId == 0 {
+		return filters, models.NewInvalidFieldError("personrole", "personrole needs personid")
+	}
+
+	if value := c.Query("minrating"); value != "" {
+		filters.MinRating, err = strconv.ParseFloat(value, 64)
+		if err != nil || filters.MinRating < 0 || filters.MinRating > 5 {
+			return filters, models.NewInvalidFieldError("minrating", "minrating must be a number between 0 and 5")
+		}
+	}
+
+	if filters.HasTrailer, err = boolQuery(c, "hastrailer"); err != nil {
+		return filters, err
+	}
+	if filters.IsWatched, err = boolQuery(c, "iswatched"); err != nil {
+		return filters, err
+	}
+
+	filters.Sort, err = models.ParseSort(c.Query("sort"), models.MovieSortKeys)
+	if err != nil {
+		return filters, models.NewInvalidFieldError("sort", err.Error())
+	}
+	return filters, nil
+}
+
+func intQuery(c *gin.Context, name string) (int, error) {
+	value := c.Query(name)
+	if value == "" {
+		return 0, nil
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, models.NewInvalidFieldError(name, fmt.Sprintf("%s must be a whole number", name))
+	}
+	return n, nil
+}
+
+func boolQuery(c *gin.Context, name string) (*bool, error) {
+	value := c.Query(name)
+	if value == "" {
+		return nil, nil
+	}
+
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return nil, models.NewInvalidFieldError(name, fmt.Sprintf("%s must be true or false", name))
+	}
+	return &b, nil
+}
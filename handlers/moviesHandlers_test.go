@@ -0,0 +1,189 @@
+package handlers
+
+import (
+	"errors"
+	"goozinshe/models"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newQueryContext(query string) *gin.Context {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("GET", "/movies?"+query, nil)
+	return c
+}
+
+func TestParseMovieFilters(t *testing.T) {
+	yes, no := true, false
+
+	tests := []struct {
+		name  string
+		query string
+		want  models.MovieFilters
+	}{
+		{
+			name:  "no filters",
+			query: "",
+			want:  models.MovieFilters{GenreMatch: models.GenreMatchAny},
+		},
+		{
+			name:  "search and director",
+			query: "search=matrix&director=wachowski",
+			want:  models.MovieFilters{SearchTerm: "matrix", Director: "wachowski", GenreMatch: models.GenreMatchAny},
+		},
+		{
+			name:  "genres are deduplicated",
+			query: "genreids=3,%201,3&genrematch=all",
+			want:  models.MovieFilters{GenreIds: []int{3, 1}, GenreMatch: models.GenreMatchAll},
+		},
+		{
+			name:  "year range and rating",
+			query: "yearfrom=1990&yearto=1999&minrating=3.5",
+			want:  models.MovieFilters{GenreMatch: models.GenreMatchAny, YearFrom: 1990, YearTo: 1999, MinRating: 3.5},
+		},
+		{
+			name:  "single year",
+			query: "yearfrom=1999&yearto=1999",
+			want:  models.MovieFilters{GenreMatch: models.GenreMatchAny, YearFrom: 1999, YearTo: 1999},
+		},
+		{
+			name:  "booleans",
+			query: "hastrailer=true&iswatched=false",
+			want:  models.MovieFilters{GenreMatch: models.GenreMatchAny, HasTrailer: &yes, IsWatched: &no},
+		},
+		{
+			name:  "sort",
+			query: "sort=-rating,title",
+			want: models.MovieFilters{
+				GenreMatch: models.GenreMatchAny,
+				Sort:       []models.SortField{{Key: "rating", Desc: true}, {Key: "title"}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filters, err := parseMovieFilters(newQueryContext(tt.query))
+			if err != nil {
+				t.Fatalf("parseMovieFilters(%q) returned error %v", tt.query, err)
+			}
+			if !reflect.DeepEqual(filters, tt.want) {
+				t.Errorf("parseMovieFilters(%q) = %+v, want %+v", tt.query, filters, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseMovieFiltersRejectsInvalidValues(t *testing.T) {
+	tests := []string{
+		"genrematch=some",
+		"genreids=1,action",
+		"genreids=1,",
+		"yearfrom=nineties",
+		"yearto=1999.5",
+		"yearfrom=2000&yearto=1999",
+		"minrating=high",
+		"minrating=-1",
+		"minrating=5.5",
+		"hastrailer=maybe",
+		"iswatched=2",
+		"sort=director",
+	}
+
+	for _, query := range tests {
+		if filters, err := parseMovieFilters(newQueryContext(query)); err == nil {
+			t.Errorf("parseMovieFilters(%q) = %+v, want an error", query, filters)
+		}
+	}
+}
+
+func TestParseMoviePatch(t *testing.T) {
+	title, year, empty := "Heat", 1995, ""
+
+	tests := []struct {
+		name string
+		body string
+		want models.MoviePatch
+	}{
+		{name: "empty", body: `{}`, want: models.MoviePatch{}},
+		{
+			name: "fields",
+			body: `{"title": "Heat", "releaseYear": 1995}`,
+			want: models.MoviePatch{Title: &title, ReleaseYear: &year},
+		},
+		{
+			name: "trailer removed",
+			body: `{"trailerUrl": null}`,
+			want: models.MoviePatch{TrailerUrl: &empty},
+		},
+		{
+			name: "genres",
+			body: `{"genreIds": [], "addGenreIds": [1, 2], "removeGenreIds": [3]}`,
+			want: models.MoviePatch{GenreIds: []int{}, AddGenreIds: []int{1, 2}, RemoveGenreIds: []int{3}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			patch, fields, err := parseMoviePatch([]byte(tt.body))
+			if err != nil || len(fields) != 0 {
+				t.Fatalf("parseMoviePatch(%s) returned %v, %v", tt.body, fields, err)
+			}
+			if !reflect.DeepEqual(patch, tt.want) {
+				t.Errorf("parseMoviePatch(%s) = %+v, want %+v", tt.body, patch, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseMoviePatchViolations(t *testing.T) {
+	tests := []struct {
+		body string
+		want []string
+	}{
+		{`{"title": null}`, []string{"title"}},
+		{`{"title": "  ", "director": null}`, []string{"title", "director"}},
+		{`{"genreIds": null, "description": null}`, []string{"description", "genreIds"}},
+		{`{"trailerUrl": "ftp://example.com"}`, []string{"trailerUrl"}},
+		{`{"addGenreIds": [1, 1], "removeGenreIds": [0]}`, []string{"addGenreIds", "removeGenreIds[0]"}},
+		{`{"releaseYear": 1800}`, []string{"releaseYear"}},
+	}
+
+	for _, tt := range tests {
+		_, fields, err := parseMoviePatch([]byte(tt.body))
+		if err != nil {
+			t.Errorf("parseMoviePatch(%s) returned error %v", tt.body, err)
+			continue
+		}
+		got := make([]string, 0, len(fields))
+		for _, field := range fields {
+			got = append(got, field.Field)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("parseMoviePatch(%s) rejected %v, want %v", tt.body, got, tt.want)
+		}
+	}
+}
+
+func TestParseMoviePatchRejectsInvalidDocuments(t *testing.T) {
+	tests := []string{
+		``,
+		`null`,
+		`[]`,
+		`"title"`,
+		`{"title": 1}`,
+		`{"rating": 5}`,
+		`{"title": "Heat"`,
+	}
+
+	for _, body := range tests {
+		var validationErr *models.ValidationError
+		if patch, _, err := parseMoviePatch([]byte(body)); !errors.As(err, &validationErr) {
+			t.Errorf("parseMoviePatch(%s) = %+v, %v, want a validation error", body, patch, err)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"fmt"
+	"goozinshe/models"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+)
+
+// bindPageRequest reads the page, limit and cursor query parameters. A cursor
+// takes precedence over the page number. It records a validation error and
+// returns false when a parameter is invalid.
+func bindPageRequest(c *gin.Context) (models.PageRequest, bool) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		c.Error(models.NewInvalidFieldError("page", "Invalid page"))
+		return models.PageRequest{}, false
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultPageLimit)))
+	if err != nil || limit < 1 || limit > maxPageLimit {
+		c.Error(models.NewInvalidFieldError("limit", "Invalid limit"))
+		return models.PageRequest{}, false
+	}
+
+	request := models.PageRequest{Page: page, Limit: limit}
+	if value := c.Query("cursor"); value != "" {
+		cursor, err := models.ParseCursor(value)
+		if err != nil {
+			c.Error(models.NewInvalidFieldError("cursor", "Invalid cursor"))
+			return models.PageRequest{}, false
+		}
+		request.Page = 1
+		request.Cursor = &cursor
+	}
+
+	return request, true
+}
+
+// writePageHeaders sets X-Total-Count and a Link header with the next and
+// previous pages. Links use the cursors of info. Page requests without a
+// cursor in that direction, e.g. to lists that don't issue cursors, link page
+// numbers instead.
+func writePageHeaders(c *gin.Context, request models.PageRequest, info models.PageInfo) {
+	c.Header("X-Total-Count", strconv.Itoa(info.Total))
+
+	links := make([]string, 0, 2)
+	link := func(rel string, param string, value string) {
+		query := c.Request.URL.Query()
+		query.Del("page")
+		query.Del("cursor")
+		query.Set("limit", strconv.Itoa(request.Limit))
+		query.Set(param, value)
+
+		url := *c.Request.URL
+		url.RawQuery = query.Encode()
+		links = append(links, fmt.Sprintf(`<%s>; rel="%s"`, url.RequestURI(), rel))
+	}
+
+	if info.NextCursor != "" {
+		link("next", "cursor", info.NextCursor)
+	} else if request.Cursor == nil && request.Page*request.Limit < info.Total {
+		link("next", "page", strconv.Itoa(request.Page+1))
+	}
+	if info.PrevCursor != "" {
+		link("prev", "cursor", info.PrevCursor)
+	} else if request.Cursor == nil && request.Page > 1 {
+		link("prev", "page", strconv.Itoa(request.Page-1))
+	}
+
+	if len(links) > 0 {
+		c.Header("Link", strings.Join(links, ", "))
+	}
+}
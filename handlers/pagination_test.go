@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"goozinshe/models"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestWritePageHeaders(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	next := models.Cursor{After: 40}.Encode()
+	prev := models.Cursor{Before: 21}.Encode()
+
+	tests := []struct {
+		name    string
+		request models.PageRequest
+		info    models.PageInfo
+		want    string
+	}{
+		{
+			name:    "cursors",
+			request: models.PageRequest{Page: 2, Limit: 20},
+			info:    models.PageInfo{Total: 50, NextCursor: next, PrevCursor: prev},
+			want:    `</movies?cursor=` + next + `&limit=20&sort=title>; rel="next", </movies?cursor=` + prev + `&limit=20&sort=title>; rel="prev"`,
+		},
+		{
+			name:    "page numbers without cursors",
+			request: models.PageRequest{Page: 2, Limit: 20},
+			info:    models.PageInfo{Total: 50},
+			want:    `</movies?limit=20&page=3&sort=title>; rel="next", </movies?limit=20&page=1&sort=title>; rel="prev"`,
+		},
+		{
+			name:    "past the last page",
+			request: models.PageRequest{Page: 9, Limit: 20},
+			info:    models.PageInfo{Total: 50},
+			want:    `</movies?limit=20&page=8&sort=title>; rel="prev"`,
+		},
+		{
+			name:    "single page",
+			request: models.PageRequest{Page: 1, Limit: 20},
+			info:    models.PageInfo{Total: 5},
+			want:    "",
+		},
+		{
+			name:    "last page of a cursor request",
+			request: models.PageRequest{Page: 1, Limit: 20, Cursor: &models.Cursor{After: 20}},
+			info:    models.PageInfo{Total: 50, PrevCursor: prev},
+			want:    `</movies?cursor=` + prev + `&limit=20&sort=title>; rel="prev"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(recorder)
+			c.Request = httptest.NewRequest(http.MethodGet, "/movies?page=2&cursor=old&sort=title", nil)
+
+			writePageHeaders(c, tt.request, tt.info)
+
+			if got := recorder.Header().Get("Link"); got != tt.want {
+				t.Errorf("Link =\n%s\nwant\n%s", got, tt.want)
+			}
+			if got := recorder.Header().Get("X-Total-Count"); got == "" {
+				t.Errorf("X-Total-Count is missing")
+			}
+		})
+	}
+}
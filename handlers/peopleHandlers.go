@@ -0,0 +1,240 @@
+package handlers
+
+import (
+	"goozinshe/models"
+	"goozinshe/repositories"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PeopleHandler struct {
+	peopleRepo *repositories.PeopleRepository
+}
+
+type personRequest struct {
+	Name string `json:"name" binding:"required,notblank,max=200"`
+}
+
+func NewPeopleHandler(peopleRepo *repositories.PeopleRepository) *PeopleHandler {
+	return &PeopleHandler{peopleRepo: peopleRepo}
+}
+
+// FindAll godoc
+// @Tags people
+// @Summary      Get people list
+// @Description  Returns directors, actors and crew ordered by name.
+// @Accept       json
+// @Produce      json
+// @Param search query string false "Only people whose name contains it"
+// @Param page query int false "Page number, starting from 1"
+// @Param limit query int false "Page size (max 100)"
+// @Param cursor query string false "Opaque cursor from a Link header, takes precedence over page"
+// @Success      200  {array} models.Person "OK"
+// @Header       200  {int} X-Total-Count "Total number of items"
+// @Header       200  {string} Link "Next and previous pages"
+// @Failure   	 400  {object} models.Problem "Invalid paging parameters"
+// @Failure   	 500  {object} models.Problem
+// @Router       /people [get]
+// @Security Bearer
+func (h *PeopleHandler) FindAll(c *gin.Context) {
+	page, ok := bindPageRequest(c)
+	if !ok {
+		return
+	}
+
+	people, info, err := h.peopleRepo.FindAll(c, c.Query("search"), page)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	writePageHeaders(c, page, info)
+	c.JSON(http.StatusOK, people)
+}
+
+// FindById godoc
+// @Tags people
+// @Summary      Find person by id
+// @Accept       json
+// @Produce      json
+// @Param id path int true "Person id"
+// @Param If-None-Match header string false "ETag of a cached copy"
+// @Success      200  {object} models.Person "OK"
+// @Header       200  {string} ETag "Version of the person, for If-Match and If-None-Match"
+// @Success      304  "Not modified"
+// @Failure   	 400  {object} models.Problem "Invalid person id"
+// @Failure   	 404  {object} models.Problem "Person not found"
+// @Failure   	 500  {object} models.Problem
+// @Router       /people/{id} [get]
+// @Security Bearer
+func (h *PeopleHandler) FindById(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(models.NewInvalidFieldError("id", "Invalid person id"))
+		return
+	}
+
+	person, err := h.peopleRepo.FindById(c, id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	writeEntity(c, person.Version, person)
+}
+
+// Filmography godoc
+// @Tags people
+// @Summary      Get filmography
+// @Description  Returns the credits of the person, newest movies first. A person credited with several roles in a movie is listed once per role.
+// @Accept       json
+// @Produce      json
+// @Param id path int true "Person id"
+// @Success      200  {array} models.FilmographyEntry "OK"
+// @Failure   	 400  {object} models.Problem "Invalid person id"
+// @Failure   	 404  {object} models.Problem "Person not found"
+// @Failure   	 500  {object} models.Problem
+// @Router       /people/{id}/filmography [get]
+// @Security Bearer
+func (h *PeopleHandler) Filmography(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(models.NewInvalidFieldError("id", "Invalid person id"))
+		return
+	}
+
+	if _, err := h.peopleRepo.FindById(c, id); err != nil {
+		c.Error(err)
+		return
+	}
+
+	entries, err := h.peopleRepo.Filmography(c, id, c.GetStringSlice("locales"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, entries)
+}
+
+// Create godoc
+// @Tags people
+// @Summary      Create person
+// @Accept       json
+// @Produce      json
+// @Param request body handlers.personRequest true "Person data"
+// @Success      200  {object} object{id=int} "OK"
+// @Failure   	 400  {object} models.Problem "Invalid data"
+// @Failure   	 403  {object} models.Problem "Insufficient permissions"
+// @Failure   	 500  {object} models.Problem
+// @Router       /people [post]
+// @Security Bearer
+func (h *PeopleHandler) Create(c *gin.Context) {
+	var request personRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.Error(bindingError(err))
+		return
+	}
+
+	id, err := h.peopleRepo.Create(c, models.Person{Name: request.Name})
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"id": id})
+}
+
+// Update godoc
+// @Tags people
+// @Summary      Update person
+// @Description  Renaming a director also changes the director of their movies.
+// @Accept       json
+// @Produce      json
+// @Param id path int true "Person id"
+// @Param request body handlers.personRequest true "Person data"
+// @Param If-Match header string false "ETag of the person, the change fails with 412 when the person was changed since"
+// @Success      200  "OK"
+// @Failure   	 400  {object} models.Problem "Invalid data"
+// @Failure   	 403  {object} models.Problem "Insufficient permissions"
+// @Failure   	 404  {object} models.Problem "Person not found"
+// @Failure   	 412  {object} models.Problem "Person was changed by someone else"
+// @Failure   	 500  {object} models.Problem
+// @Router       /people/{id} [put]
+// @Security Bearer
+func (h *PeopleHandler) Update(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(models.NewInvalidFieldError("id", "Invalid person id"))
+		return
+	}
+
+	existing, err := h.peopleRepo.FindById(c, id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	version, ok := ifMatchVersion(c, existing.Version)
+	if !ok {
+		return
+	}
+
+	var request personRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.Error(bindingError(err))
+		return
+	}
+
+	err = h.peopleRepo.Update(c, id, models.Person{Name: request.Name, Version: version})
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// Delete godoc
+// @Tags people
+// @Summary      Delete person
+// @Description  Only people who aren't credited in any movie, including movies in the trash, can be deleted.
+// @Accept       json
+// @Produce      json
+// @Param id path int true "Person id"
+// @Param If-Match header string false "ETag of the person, the change fails with 412 when the person was changed since"
+// @Success      200  "OK"
+// @Failure   	 400  {object} models.Problem "Invalid person id"
+// @Failure   	 403  {object} models.Problem "Insufficient permissions"
+// @Failure   	 404  {object} models.Problem "Person not found"
+// @Failure   	 409  {object} models.Problem "Person is credited in a movie"
+// @Failure   	 412  {object} models.Problem "Person was changed by someone else"
+// @Failure   	 500  {object} models.Problem
+// @Router       /people/{id} [delete]
+// @Security Bearer
+func (h *PeopleHandler) Delete(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(models.NewInvalidFieldError("id", "Invalid person id"))
+		return
+	}
+
+	existing, err := h.peopleRepo.FindById(c, id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	version, ok := ifMatchVersion(c, existing.Version)
+	if !ok {
+		return
+	}
+
+	err = h.peopleRepo.Delete(c, id, version)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.Status(http.StatusOK)
+}
@@ -0,0 +1,211 @@
+package handlers
+
+import (
+	"goozinshe/models"
+	"goozinshe/repositories"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type RevisionsHandler struct {
+	revisionsRepo *repositories.RevisionsRepository
+	moviesRepo    *repositories.MoviesRepository
+	genresRepo    *repositories.GenresRepository
+}
+
+func NewRevisionsHandler(
+	revisionsRepo *repositories.RevisionsRepository,
+	moviesRepo *repositories.MoviesRepository,
+	genresRepo *repositories.GenresRepository) *RevisionsHandler {
+	return &RevisionsHandler{
+		revisionsRepo: revisionsRepo,
+		moviesRepo:    moviesRepo,
+		genresRepo:    genresRepo,
+	}
+}
+
+// FindMovieRevisions godoc
+// @Summary      Get movie revisions
+// @Description  Lists the recorded changes of a movie, newest first. Each revision has a snapshot of the movie after the change and the fields that changed. Revisions of deleted movies are kept.
+// @Tags movies
+// @Accept       json
+// @Produce      json
+// @Param id path int true "Movie id"
+// @Param page query int false "Page number, starting from 1"
+// @Param limit query int false "Page size (max 100)"
+// @Success      200  {array} models.Revision "OK"
+// @Header       200  {int} X-Total-Count "Total number of items"
+// @Header       200  {string} Link "Next and previous pages"
+// @Failure   	 400  {object} models.Problem "Invalid movie id or paging parameters"
+// @Failure   	 403  {object} models.Problem "Insufficient permissions"
+// @Failure   	 500  {object} models.Problem
+// @Router       /movies/{id}/revisions [get]
+// @Security Bearer
+func (h *RevisionsHandler) FindMovieRevisions(c *gin.Context) {
+	h.findRevisions(c, models.EntityMovie)
+}
+
+// RestoreMovieRevision godoc
+// @Summary      Restore movie revision
+// @Description  Rolls the movie back to a revision, which is recorded as a new revision. A poster that has been deleted since is not restored, neither are deleted genres.
+// @Tags movies
+// @Accept       json
+// @Produce      json
+// @Param id path int true "Movie id"
+// @Param rev path int true "Revision id"
+// @Param If-Match header string false "ETag of the movie, the change fails with 412 when the movie was changed since"
+// @Success      200  {object} models.Movie "Restored movie"
+// @Header       200  {string} ETag "New version of the movie"
+// @Failure   	 400  {object} models.Problem "Invalid movie or revision id"
+// @Failure   	 403  {object} models.Problem "Insufficient permissions"
+// @Failure   	 404  {object} models.Problem "Movie or revision not found"
+// @Failure   	 412  {object} models.Problem "Movie was changed by someone else"
+// @Failure   	 500  {object} models.Problem
+// @Router       /movies/{id}/revisions/{rev}/restore [post]
+// @Security Bearer
+func (h *RevisionsHandler) RestoreMovieRevision(c *gin.Context) {
+	id, revisionId, ok := parseRevisionParams(c)
+	if !ok {
+		return
+	}
+
+	existing, err := h.moviesRepo.FindById(c, id, c.GetInt("userId"), c.GetStringSlice("locales"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	version, ok := ifMatchVersion(c, existing.Version)
+	if !ok {
+		return
+	}
+
+	err = h.moviesRepo.RestoreRevision(c, id, revisionId, version, c.GetInt("userId"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	movie, err := h.moviesRepo.FindById(c, id, c.GetInt("userId"), c.GetStringSlice("locales"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	writeEntity(c, movie.Version, movie)
+}
+
+// FindGenreRevisions godoc
+// @Summary      Get genre revisions
+// @Description  Lists the recorded changes of a genre, newest first.
+// @Tags genres
+// @Accept       json
+// @Produce      json
+// @Param id path int true "Genre id"
+// @Param page query int false "Page number, starting from 1"
+// @Param limit query int false "Page size (max 100)"
+// @Success      200  {array} models.Revision "OK"
+// @Header       200  {int} X-Total-Count "Total number of items"
+// @Header       200  {string} Link "Next and previous pages"
+// @Failure   	 400  {object} models.Problem "Invalid genre id or paging parameters"
+// @Failure   	 403  {object} models.Problem "Insufficient permissions"
+// @Failure   	 500  {object} models.Problem
+// @Router       /genres/{id}/revisions [get]
+// @Security Bearer
+func (h *RevisionsHandler) FindGenreRevisions(c *gin.Context) {
+	h.findRevisions(c, models.EntityGenre)
+}
+
+// RestoreGenreRevision godoc
+// @Summary      Restore genre revision
+// @Description  Rolls the genre back to a revision, which is recorded as a new revision.
+// @Tags genres
+// @Accept       json
+// @Produce      json
+// @Param id path int true "Genre id"
+// @Param rev path int true "Revision id"
+// @Param If-Match header string false "ETag of the genre, the change fails with 412 when the genre was changed since"
+// @Success      200  {object} models.Genre "Restored genre"
+// @Header       200  {string} ETag "New version of the genre"
+// @Failure   	 400  {object} models.Problem "Invalid genre or revision id"
+// @Failure   	 403  {object} models.Problem "Insufficient permissions"
+// @Failure   	 404  {object} models.Problem "Genre or revision not found"
+// @Failure   	 412  {object} models.Problem "Genre was changed by someone else"
+// @Failure   	 500  {object} models.Problem
+// @Router       /genres/{id}/revisions/{rev}/restore [post]
+// @Security Bearer
+func (h *RevisionsHandler) RestoreGenreRevision(c *gin.Context) {
+	id, revisionId, ok := parseRevisionParams(c)
+	if !ok {
+		return
+	}
+
+	existing, err := h.genresRepo.FindById(c, id, c.GetStringSlice("locales"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	version, ok := ifMatchVersion(c, existing.Version)
+	if !ok {
+		return
+	}
+
+	err = h.genresRepo.RestoreRevision(c, id, revisionId, version, c.GetInt("userId"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	genre, err := h.genresRepo.FindById(c, id, c.GetStringSlice("locales"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	writeEntity(c, genre.Version, genre)
+}
+
+func (h *RevisionsHandler) findRevisions(c *gin.Context, entity string) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(models.NewInvalidFieldError("id", "Invalid "+entity+" id"))
+		return
+	}
+
+	page, ok := bindPageRequest(c)
+	if !ok {
+		return
+	}
+	if page.Cursor != nil {
+		c.Error(models.NewInvalidFieldError("cursor", "Revisions are paged by page number, not by cursor"))
+		return
+	}
+
+	revisions, info, err := h.revisionsRepo.FindAll(c, entity, id, page)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	writePageHeaders(c, page, info)
+	c.JSON(http.StatusOK, revisions)
+}
+
+func parseRevisionParams(c *gin.Context) (int, int, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(models.NewInvalidFieldError("id", "Invalid id"))
+		return 0, 0, false
+	}
+
+	revisionId, err := strconv.Atoi(c.Param("rev"))
+	if err != nil {
+		c.Error(models.NewInvalidFieldError("rev", "Invalid revision id"))
+		return 0, 0, false
+	}
+
+	return id, revisionId, true
+}
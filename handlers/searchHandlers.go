@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"goozinshe/models"
+	"goozinshe/repositories"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultSuggestLimit = 10
+	maxSuggestLimit     = 20
+)
+
+var errSearchQueryRequired = models.NewValidationError("Search query is required",
+	models.FieldError{Field: "q", Code: "required", Message: "Search query is required"})
+
+type SearchHandler struct {
+	searchRepo *repositories.SearchRepository
+}
+
+func NewSearchHandler(searchRepo *repositories.SearchRepository) *SearchHandler {
+	return &SearchHandler{searchRepo: searchRepo}
+}
+
+// Search godoc
+// @Summary      Search movies
+// @Description  Full text search over titles, directors and descriptions that also tolerates typos in titles and directors. Series are also found by the titles and descriptions of their episodes, which are listed in Episodes. Results are ranked, best matches first, and carry HTML snippets with the matched words in <mark> tags.
+// @Tags search
+// @Accept       json
+// @Produce      json
+// @Param q query string true "Search query, supports quoted phrases, or and -word"
+// @Param kind query string false "movie or series, only returns that kind"
+// @Param page query int false "Page number, starting from 1"
+// @Param limit query int false "Page size (max 100)"
+// @Success      200  {array} models.SearchResult "OK"
+// @Header       200  {int} X-Total-Count "Total number of items"
+// @Header       200  {string} Link "Next and previous pages"
+// @Failure   	 400  {object} models.Problem "Missing query, invalid kind or invalid paging parameters"
+// @Failure   	 500  {object} models.Problem
+// @Router       /search [get]
+// @Security Bearer
+func (h *SearchHandler) Search(c *gin.Context) {
+	query := strings.TrimSpace(c.Query("q"))
+	if query == "" {
+		c.Error(errSearchQueryRequired)
+		return
+	}
+
+	kind := c.Query("kind")
+	if kind != "" && !slices.Contains(models.MovieKinds, kind) {
+		c.Error(models.NewInvalidFieldError("kind", "kind must be one of: "+strings.Join(models.MovieKinds, ", ")))
+		return
+	}
+
+	page, ok := bindPageRequest(c)
+	if !ok {
+		return
+	}
+	if page.Cursor != nil {
+		c.Error(models.NewInvalidFieldError("cursor", "Search results are paged by page number, not by cursor"))
+		return
+	}
+
+	results, info, err := h.searchRepo.Search(c, c.GetInt("userId"), c.GetStringSlice("locales"), query, kind, page)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	writePageHeaders(c, page, info)
+	c.JSON(http.StatusOK, results)
+}
+
+// Suggest godoc
+// @Summary      Suggest movie titles
+// @Description  Autocomplete for the search box. Titles starting with the query come first, followed by titles with similar words.
+// @Tags search
+// @Accept       json
+// @Produce      json
+// @Param q query string true "Beginning of a title"
+// @Param limit query int false "Number of suggestions (max 20)"
+// @Success      200  {array} models.Suggestion "OK"
+// @Failure   	 400  {object} models.Problem "Missing query or invalid limit"
+// @Failure   	 500  {object} models.Problem
+// @Router       /search/suggest [get]
+// @Security Bearer
+func (h *SearchHandler) Suggest(c *gin.Context) {
+	query := strings.TrimSpace(c.Query("q"))
+	if query == "" {
+		c.Error(errSearchQueryRequired)
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultSuggestLimit)))
+	if err != nil || limit < 1 || limit > maxSuggestLimit {
+		c.Error(models.NewInvalidFieldError("limit", "Invalid limit"))
+		return
+	}
+
+	suggestions, err := h.searchRepo.Suggest(c, c.GetStringSlice("locales"), query, limit)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, suggestions)
+}
@@ -0,0 +1,449 @@
+package handlers
+
+import (
+	"fmt"
+	"goozinshe/models"
+	"goozinshe/repositories"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SeriesHandler struct {
+	seriesRepo *repositories.SeriesRepository
+}
+
+type seasonRequest struct {
+	Number int    `json:"number" binding:"required,gt=0"`
+	Title  string `json:"title" binding:"omitempty,notblank,max=200"`
+}
+
+type episodeRequest struct {
+	Number      int    `json:"number" binding:"required,gt=0"`
+	Title       string `json:"title" binding:"required,notblank,max=200"`
+	Description string `json:"description" binding:"max=5000"`
+	Runtime     int    `json:"runtime" binding:"required,gt=0"`                 // minutes
+	AirDate     string `json:"airDate" binding:"omitempty,datetime=2006-01-02"` // YYYY-MM-DD
+}
+
+func NewSeriesHandler(seriesRepo *repositories.SeriesRepository) *SeriesHandler {
+	return &SeriesHandler{seriesRepo: seriesRepo}
+}
+
+// numberParam parses the path parameter of a season or episode number.
+func numberParam(c *gin.Context, name string) (int, error) {
+	n, err := strconv.Atoi(c.Param(name))
+	if err != nil || n <= 0 {
+		return 0, models.NewInvalidFieldError(name, fmt.Sprintf("Invalid %s number", name))
+	}
+	return n, nil
+}
+
+// seriesParams parses the series id and, in that order, the season and episode
+// numbers of the path.
+func seriesParams(c *gin.Context, names ...string) (int, []int, error) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return 0, nil, models.NewInvalidFieldError("id", "Invalid series id")
+	}
+
+	numbers := make([]int, 0, len(names))
+	for _, name := range names {
+		n, err := numberParam(c, name)
+		if err != nil {
+			return 0, nil, err
+		}
+		numbers = append(numbers, n)
+	}
+	return id, numbers, nil
+}
+
+// FindSeasons godoc
+// @Tags series
+// @Summary      Get seasons
+// @Description  Returns the seasons of a series with their episodes and the current user's progress through them.
+// @Accept       json
+// @Produce      json
+// @Param id path int true "Series id"
+// @Success      200  {array} models.Season "OK"
+// @Failure   	 400  {object} models.Problem "Invalid series id"
+// @Failure   	 404  {object} models.Problem "Series not found"
+// @Failure   	 500  {object} models.Problem
+// @Router       /movies/{id}/seasons [get]
+// @Security Bearer
+func (h *SeriesHandler) FindSeasons(c *gin.Context) {
+	id, _, err := seriesParams(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	seasons, err := h.seriesRepo.FindSeasons(c, id, c.GetInt("userId"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, seasons)
+}
+
+// FindSeason godoc
+// @Tags series
+// @Summary      Find season by number
+// @Description  Returns the season with its episodes and the current user's progress through them.
+// @Accept       json
+// @Produce      json
+// @Param id path int true "Series id"
+// @Param season path int true "Season number"
+// @Success      200  {object} models.Season "OK"
+// @Failure   	 400  {object} models.Problem "Invalid series id or season number"
+// @Failure   	 404  {object} models.Problem "Series or season not found"
+// @Failure   	 500  {object} models.Problem
+// @Router       /movies/{id}/seasons/{season} [get]
+// @Security Bearer
+func (h *SeriesHandler) FindSeason(c *gin.Context) {
+	id, numbers, err := seriesParams(c, "season")
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	season, err := h.seriesRepo.FindSeason(c, id, numbers[0], c.GetInt("userId"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, season)
+}
+
+// NextEpisode godoc
+// @Tags series
+// @Summary      Get next unwatched episode
+// @Description  Returns the episode the current user should watch next: the first unwatched episode after the furthest one they watched, or else the first one they skipped. Episodes that haven't aired yet are left out.
+// @Accept       json
+// @Produce      json
+// @Param id path int true "Series id"
+// @Success      200  {object} models.Episode "OK"
+// @Success      204  "Every aired episode is watched"
+// @Failure   	 400  {object} models.Problem "Invalid series id"
+// @Failure   	 404  {object} models.Problem "Series not found"
+// @Failure   	 500  {object} models.Problem
+// @Router       /movies/{id}/nextEpisode [get]
+// @Security Bearer
+func (h *SeriesHandler) NextEpisode(c *gin.Context) {
+	id, _, err := seriesParams(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	episode, err := h.seriesRepo.NextEpisode(c, id, c.GetInt("userId"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	if episode == nil {
+		c.Status(http.StatusNoContent)
+		return
+	}
+
+	c.JSON(http.StatusOK, episode)
+}
+
+// CreateSeason godoc
+// @Tags series
+// @Summary      Create season
+// @Accept       json
+// @Produce      json
+// @Param id path int true "Series id"
+// @Param request body handlers.seasonRequest true "Season data"
+// @Success      200  {object} object{id=int} "OK"
+// @Failure   	 400  {object} models.Problem "Invalid data or the number is taken"
+// @Failure   	 403  {object} models.Problem "Insufficient permissions"
+// @Failure   	 404  {object} models.Problem "Series not found"
+// @Failure   	 500  {object} models.Problem
+// @Router       /movies/{id}/seasons [post]
+// @Security Bearer
+func (h *SeriesHandler) CreateSeason(c *gin.Context) {
+	id, _, err := seriesParams(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	var request seasonRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.Error(bindingError(err))
+		return
+	}
+
+	seasonId, err := h.seriesRepo.CreateSeason(c, id, models.Season{Number: request.Number, Title: request.Title})
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"id": seasonId})
+}
+
+// UpdateSeason godoc
+// @Tags series
+// @Summary      Update season
+// @Description  Changes the number and title of a season.
+// @Accept       json
+// @Produce      json
+// @Param id path int true "Series id"
+// @Param season path int true "Season number"
+// @Param request body handlers.seasonRequest true "Season data"
+// @Success      200  "OK"
+// @Failure   	 400  {object} models.Problem "Invalid data or the number is taken"
+// @Failure   	 403  {object} models.Problem "Insufficient permissions"
+// @Failure   	 404  {object} models.Problem "Series or season not found"
+// @Failure   	 500  {object} models.Problem
+// @Router       /movies/{id}/seasons/{season} [put]
+// @Security Bearer
+func (h *SeriesHandler) UpdateSeason(c *gin.Context) {
+	id, numbers, err := seriesParams(c, "season")
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	var request seasonRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.Error(bindingError(err))
+		return
+	}
+
+	err = h.seriesRepo.UpdateSeason(c, id, numbers[0], models.Season{Number: request.Number, Title: request.Title})
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// DeleteSeason godoc
+// @Tags series
+// @Summary      Delete season
+// @Description  Deletes the season with its episodes and their watched state.
+// @Accept       json
+// @Produce      json
+// @Param id path int true "Series id"
+// @Param season path int true "Season number"
+// @Success      200  "OK"
+// @Failure   	 400  {object} models.Problem "Invalid series id or season number"
+// @Failure   	 403  {object} models.Problem "Insufficient permissions"
+// @Failure   	 404  {object} models.Problem "Series or season not found"
+// @Failure   	 500  {object} models.Problem
+// @Router       /movies/{id}/seasons/{season} [delete]
+// @Security Bearer
+func (h *SeriesHandler) DeleteSeason(c *gin.Context) {
+	id, numbers, err := seriesParams(c, "season")
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	err = h.seriesRepo.DeleteSeason(c, id, numbers[0])
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// SetSeasonWatched godoc
+// @Tags series
+// @Summary      Mark season as watched
+// @Description  Marks every episode of the season as watched or unwatched by the current user. A series is watched once all of its episodes are.
+// @Accept       json
+// @Produce      json
+// @Param id path int true "Series id"
+// @Param season path int true "Season number"
+// @Param isWatched query bool true "Flag value"
+// @Success      200  "OK"
+// @Failure   	 400  {object} models.Problem "Invalid data"
+// @Failure   	 404  {object} models.Problem "Series or season not found"
+// @Failure   	 500  {object} models.Problem
+// @Router       /movies/{id}/seasons/{season}/setWatched [patch]
+// @Security Bearer
+func (h *SeriesHandler) SetSeasonWatched(c *gin.Context) {
+	id, numbers, err := seriesParams(c, "season")
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	isWatched, err := strconv.ParseBool(c.Query("isWatched"))
+	if err != nil {
+		c.Error(models.NewInvalidFieldError("isWatched", "Invalid isWatched value"))
+		return
+	}
+
+	err = h.seriesRepo.SetSeasonWatched(c, id, numbers[0], c.GetInt("userId"), isWatched)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// CreateEpisode godoc
+// @Tags series
+// @Summary      Create episode
+// @Accept       json
+// @Produce      json
+// @Param id path int true "Series id"
+// @Param season path int true "Season number"
+// @Param request body handlers.episodeRequest true "Episode data"
+// @Success      200  {object} object{id=int} "OK"
+// @Failure   	 400  {object} models.Problem "Invalid data or the number is taken"
+// @Failure   	 403  {object} models.Problem "Insufficient permissions"
+// @Failure   	 404  {object} models.Problem "Series or season not found"
+// @Failure   	 500  {object} models.Problem
+// @Router       /movies/{id}/seasons/{season}/episodes [post]
+// @Security Bearer
+func (h *SeriesHandler) CreateEpisode(c *gin.Context) {
+	id, numbers, err := seriesParams(c, "season")
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	var request episodeRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.Error(bindingError(err))
+		return
+	}
+
+	episodeId, err := h.seriesRepo.CreateEpisode(c, id, numbers[0], request.episode())
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"id": episodeId})
+}
+
+// UpdateEpisode godoc
+// @Tags series
+// @Summary      Update episode
+// @Accept       json
+// @Produce      json
+// @Param id path int true "Series id"
+// @Param season path int true "Season number"
+// @Param episode path int true "Episode number"
+// @Param request body handlers.episodeRequest true "Episode data"
+// @Success      200  "OK"
+// @Failure   	 400  {object} models.Problem "Invalid data or the number is taken"
+// @Failure   	 403  {object} models.Problem "Insufficient permissions"
+// @Failure   	 404  {object} models.Problem "Series, season or episode not found"
+// @Failure   	 500  {object} models.Problem
+// @Router       /movies/{id}/seasons/{season}/episodes/{episode} [put]
+// @Security Bearer
+func (h *SeriesHandler) UpdateEpisode(c *gin.Context) {
+	id, numbers, err := seriesParams(c, "season", "episode")
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	var request episodeRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.Error(bindingError(err))
+		return
+	}
+
+	err = h.seriesRepo.UpdateEpisode(c, id, numbers[0], numbers[1], request.episode())
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// DeleteEpisode godoc
+// @Tags series
+// @Summary      Delete episode
+// @Accept       json
+// @Produce      json
+// @Param id path int true "Series id"
+// @Param season path int true "Season number"
+// @Param episode path int true "Episode number"
+// @Success      200  "OK"
+// @Failure   	 400  {object} models.Problem "Invalid series id, season or episode number"
+// @Failure   	 403  {object} models.Problem "Insufficient permissions"
+// @Failure   	 404  {object} models.Problem "Series, season or episode not found"
+// @Failure   	 500  {object} models.Problem
+// @Router       /movies/{id}/seasons/{season}/episodes/{episode} [delete]
+// @Security Bearer
+func (h *SeriesHandler) DeleteEpisode(c *gin.Context) {
+	id, numbers, err := seriesParams(c, "season", "episode")
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	err = h.seriesRepo.DeleteEpisode(c, id, numbers[0], numbers[1])
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// SetEpisodeWatched godoc
+// @Tags series
+// @Summary      Mark episode as watched
+// @Description  Updates the current user's watched state of the episode. A series is watched once all of its episodes are, which adds it to the user's watch history.
+// @Accept       json
+// @Produce      json
+// @Param id path int true "Series id"
+// @Param season path int true "Season number"
+// @Param episode path int true "Episode number"
+// @Param isWatched query bool true "Flag value"
+// @Success      200  "OK"
+// @Failure   	 400  {object} models.Problem "Invalid data"
+// @Failure   	 404  {object} models.Problem "Series, season or episode not found"
+// @Failure   	 500  {object} models.Problem
+// @Router       /movies/{id}/seasons/{season}/episodes/{episode}/setWatched [patch]
+// @Security Bearer
+func (h *SeriesHandler) SetEpisodeWatched(c *gin.Context) {
+	id, numbers, err := seriesParams(c, "season", "episode")
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	isWatched, err := strconv.ParseBool(c.Query("isWatched"))
+	if err != nil {
+		c.Error(models.NewInvalidFieldError("isWatched", "Invalid isWatched value"))
+		return
+	}
+
+	err = h.seriesRepo.SetEpisodeWatched(c, id, numbers[0], numbers[1], c.GetInt("userId"), isWatched)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+func (r episodeRequest) episode() models.Episode {
+	return models.Episode{
+		Number:      r.Number,
+		Title:       r.Title,
+		Description: r.Description,
+		Runtime:     r.Runtime,
+		AirDate:     r.AirDate,
+	}
+}
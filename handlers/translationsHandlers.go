@@ -0,0 +1,300 @@
+package handlers
+
+import (
+	"goozinshe/models"
+	"goozinshe/repositories"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TranslationsHandler struct {
+	translationsRepo *repositories.TranslationsRepository
+	moviesRepo       *repositories.MoviesRepository
+	genresRepo       *repositories.GenresRepository
+}
+
+type movieTranslationRequest struct {
+	Title       string `json:"title" binding:"required,notblank,max=200"`
+	Description string `json:"description" binding:"max=5000"`
+}
+
+type genreTranslationRequest struct {
+	Title string `json:"title" binding:"required,notblank,max=100"`
+}
+
+// translationRequest is the payload of a translation of an entity.
+type translationRequest interface {
+	translation() models.Translation
+}
+
+func (r *movieTranslationRequest) translation() models.Translation {
+	return models.Translation{Title: r.Title, Description: r.Description}
+}
+
+func (r *genreTranslationRequest) translation() models.Translation {
+	return models.Translation{Title: r.Title}
+}
+
+func NewTranslationsHandler(
+	translationsRepo *repositories.TranslationsRepository,
+	moviesRepo *repositories.MoviesRepository,
+	genresRepo *repositories.GenresRepository) *TranslationsHandler {
+	return &TranslationsHandler{
+		translationsRepo: translationsRepo,
+		moviesRepo:       moviesRepo,
+		genresRepo:       genresRepo,
+	}
+}
+
+// FindMovieTranslations godoc
+// @Summary      Get movie translations
+// @Description  Lists the translations of the title and description of a movie. The ETag is the one of the movie, which changes with its translations.
+// @Tags movies
+// @Accept       json
+// @Produce      json
+// @Param id path int true "Movie id"
+// @Success      200  {array} models.Translation "OK"
+// @Header       200  {string} ETag "Version of the movie, for If-Match"
+// @Failure   	 400  {object} models.Problem "Invalid movie id"
+// @Failure   	 403  {object} models.Problem "Insufficient permissions"
+// @Failure   	 404  {object} models.Problem "Movie not found"
+// @Failure   	 500  {object} models.Problem
+// @Router       /movies/{id}/translations [get]
+// @Security Bearer
+func (h *TranslationsHandler) FindMovieTranslations(c *gin.Context) {
+	h.findTranslations(c, models.EntityMovie)
+}
+
+// SetMovieTranslation godoc
+// @Summary      Set movie translation
+// @Description  Adds or replaces the translation of a movie to a locale. An empty description falls back to the next locale. The change is recorded as a revision of the movie.
+// @Tags movies
+// @Accept       json
+// @Produce      json
+// @Param id path int true "Movie id"
+// @Param locale path string true "kk, ru or en"
+// @Param request body handlers.movieTranslationRequest true "Translation"
+// @Param If-Match header string false "ETag of the movie, the change fails with 412 when the movie was changed since"
+// @Success      200  {array} models.Translation "All translations of the movie"
+// @Header       200  {string} ETag "New version of the movie"
+// @Failure   	 400  {object} models.Problem "Invalid data or locale"
+// @Failure   	 403  {object} models.Problem "Insufficient permissions"
+// @Failure   	 404  {object} models.Problem "Movie not found"
+// @Failure   	 412  {object} models.Problem "Movie was changed by someone else"
+// @Failure   	 500  {object} models.Problem
+// @Router       /movies/{id}/translations/{locale} [put]
+// @Security Bearer
+func (h *TranslationsHandler) SetMovieTranslation(c *gin.Context) {
+	h.setTranslation(c, models.EntityMovie, &movieTranslationRequest{})
+}
+
+// DeleteMovieTranslation godoc
+// @Summary      Delete movie translation
+// @Description  Removes the translation of a movie to a locale. The change is recorded as a revision of the movie.
+// @Tags movies
+// @Accept       json
+// @Produce      json
+// @Param id path int true "Movie id"
+// @Param locale path string true "kk, ru or en"
+// @Param If-Match header string false "ETag of the movie, the change fails with 412 when the movie was changed since"
+// @Success      200  {array} models.Translation "Remaining translations of the movie"
+// @Header       200  {string} ETag "New version of the movie"
+// @Failure   	 400  {object} models.Problem "Invalid movie id or locale"
+// @Failure   	 403  {object} models.Problem "Insufficient permissions"
+// @Failure   	 404  {object} models.Problem "Movie or translation not found"
+// @Failure   	 412  {object} models.Problem "Movie was changed by someone else"
+// @Failure   	 500  {object} models.Problem
+// @Router       /movies/{id}/translations/{locale} [delete]
+// @Security Bearer
+func (h *TranslationsHandler) DeleteMovieTranslation(c *gin.Context) {
+	h.deleteTranslation(c, models.EntityMovie)
+}
+
+// FindGenreTranslations godoc
+// @Summary      Get genre translations
+// @Description  Lists the translations of the title of a genre. The ETag is the one of the genre, which changes with its translations.
+// @Tags genres
+// @Accept       json
+// @Produce      json
+// @Param id path int true "Genre id"
+// @Success      200  {array} models.Translation "OK"
+// @Header       200  {string} ETag "Version of the genre, for If-Match"
+// @Failure   	 400  {object} models.Problem "Invalid genre id"
+// @Failure   	 403  {object} models.Problem "Insufficient permissions"
+// @Failure   	 404  {object} models.Problem "Genre not found"
+// @Failure   	 500  {object} models.Problem
+// @Router       /genres/{id}/translations [get]
+// @Security Bearer
+func (h *TranslationsHandler) FindGenreTranslations(c *gin.Context) {
+	h.findTranslations(c, models.EntityGenre)
+}
+
+// SetGenreTranslation godoc
+// @Summary      Set genre translation
+// @Description  Adds or replaces the translation of a genre to a locale. The change is recorded as a revision of the genre.
+// @Tags genres
+// @Accept       json
+// @Produce      json
+// @Param id path int true "Genre id"
+// @Param locale path string true "kk, ru or en"
+// @Param request body handlers.genreTranslationRequest true "Translation"
+// @Param If-Match header string false "ETag of the genre, the change fails with 412 when the genre was changed since"
+// @Success      200  {array} models.Translation "All translations of the genre"
+// @Header       200  {string} ETag "New version of the genre"
+// @Failure   	 400  {object} models.Problem "Invalid data or locale"
+// @Failure   	 403  {object} models.Problem "Insufficient permissions"
+// @Failure   	 404  {object} models.Problem "Genre not found"
+// @Failure   	 412  {object} models.Problem "Genre was changed by someone else"
+// @Failure   	 500  {object} models.Problem
+// @Router       /genres/{id}/translations/{locale} [put]
+// @Security Bearer
+func (h *TranslationsHandler) SetGenreTranslation(c *gin.Context) {
+	h.setTranslation(c, models.EntityGenre, &genreTranslationRequest{})
+}
+
+// DeleteGenreTranslation godoc
+// @Summary      Delete genre translation
+// @Description  Removes the translation of a genre to a locale. The change is recorded as a revision of the genre.
+// @Tags genres
+// @Accept       json
+// @Produce      json
+// @Param id path int true "Genre id"
+// @Param locale path string true "kk, ru or en"
+// @Param If-Match header string false "ETag of the genre, the change fails with 412 when the genre was changed since"
+// @Success      200  {array} models.Translation "Remaining translations of the genre"
+// @Header       200  {string} ETag "New version of the genre"
+// @Failure   	 400  {object} models.Problem "Invalid genre id or locale"
+// @Failure   	 403  {object} models.Problem "Insufficient permissions"
+// @Failure   	 404  {object} models.Problem "Genre or translation not found"
+// @Failure   	 412  {object} models.Problem "Genre was changed by someone else"
+// @Failure   	 500  {object} models.Problem
+// @Router       /genres/{id}/translations/{locale} [delete]
+// @Security Bearer
+func (h *TranslationsHandler) DeleteGenreTranslation(c *gin.Context) {
+	h.deleteTranslation(c, models.EntityGenre)
+}
+
+func (h *TranslationsHandler) findTranslations(c *gin.Context, entity string) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(models.NewInvalidFieldError("id", "Invalid "+entity+" id"))
+		return
+	}
+
+	version, err := h.version(c, entity, id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	h.writeTranslations(c, entity, id, version)
+}
+
+func (h *TranslationsHandler) setTranslation(c *gin.Context, entity string, request translationRequest) {
+	id, locale, ok := parseTranslationParams(c, entity)
+	if !ok {
+		return
+	}
+
+	current, err := h.version(c, entity, id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	version, ok := ifMatchVersion(c, current)
+	if !ok {
+		return
+	}
+
+	if err := c.ShouldBindJSON(request); err != nil {
+		c.Error(bindingError(err))
+		return
+	}
+
+	translation := request.translation()
+	translation.Locale = locale
+	err = h.translationsRepo.Set(c, entity, id, translation, version, c.GetInt("userId"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	h.writeChangedTranslations(c, entity, id)
+}
+
+func (h *TranslationsHandler) deleteTranslation(c *gin.Context, entity string) {
+	id, locale, ok := parseTranslationParams(c, entity)
+	if !ok {
+		return
+	}
+
+	current, err := h.version(c, entity, id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	version, ok := ifMatchVersion(c, current)
+	if !ok {
+		return
+	}
+
+	err = h.translationsRepo.Delete(c, entity, id, locale, version, c.GetInt("userId"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	h.writeChangedTranslations(c, entity, id)
+}
+
+// version returns the current version of the movie or genre with the id.
+func (h *TranslationsHandler) version(c *gin.Context, entity string, id int) (int, error) {
+	if entity == models.EntityGenre {
+		genre, err := h.genresRepo.FindById(c, id, nil)
+		return genre.Version, err
+	}
+
+	movie, err := h.moviesRepo.FindById(c, id, c.GetInt("userId"), nil)
+	return movie.Version, err
+}
+
+func (h *TranslationsHandler) writeChangedTranslations(c *gin.Context, entity string, id int) {
+	version, err := h.version(c, entity, id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	h.writeTranslations(c, entity, id, version)
+}
+
+func (h *TranslationsHandler) writeTranslations(c *gin.Context, entity string, id int, version int) {
+	translations, err := h.translationsRepo.FindAll(c, entity, id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	writeEntity(c, version, translations)
+}
+
+func parseTranslationParams(c *gin.Context, entity string) (int, string, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(models.NewInvalidFieldError("id", "Invalid "+entity+" id"))
+		return 0, "", false
+	}
+
+	locale := strings.ToLower(c.Param("locale"))
+	if !slices.Contains(models.Locales, locale) {
+		c.Error(models.NewInvalidFieldError("locale", "locale must be one of: "+strings.Join(models.Locales, ", ")))
+		return 0, "", false
+	}
+
+	return id, locale, true
+}
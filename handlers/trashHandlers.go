@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"goozinshe/models"
+	"goozinshe/repositories"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TrashHandler struct {
+	trashRepo *repositories.TrashRepository
+	retention time.Duration
+}
+
+func NewTrashHandler(trashRepo *repositories.TrashRepository, retention time.Duration) *TrashHandler {
+	return &TrashHandler{trashRepo: trashRepo, retention: retention}
+}
+
+// FindAll godoc
+// @Summary      Get trash
+// @Description  Lists deleted movies, genres and users, most recently deleted first. They can be restored until they are purged at PurgeAt.
+// @Tags trash
+// @Accept       json
+// @Produce      json
+// @Param page query int false "Page number, starting from 1"
+// @Param limit query int false "Page size (max 100)"
+// @Success      200  {array} models.TrashItem "OK"
+// @Header       200  {int} X-Total-Count "Total number of items"
+// @Header       200  {string} Link "Next and previous pages"
+// @Failure   	 400  {object} models.Problem "Invalid paging parameters"
+// @Failure   	 403  {object} models.Problem "Insufficient permissions"
+// @Failure   	 500  {object} models.Problem
+// @Router       /trash [get]
+// @Security Bearer
+func (h *TrashHandler) FindAll(c *gin.Context) {
+	page, ok := bindPageRequest(c)
+	if !ok {
+		return
+	}
+	if page.Cursor != nil {
+		c.Error(models.NewInvalidFieldError("cursor", "Trash is paged by page number, not by cursor"))
+		return
+	}
+
+	items, info, err := h.trashRepo.FindAll(c, page)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	for i := range items {
+		items[i].PurgeAt = items[i].DeletedAt.Add(h.retention)
+	}
+
+	writePageHeaders(c, page, info)
+	c.JSON(http.StatusOK, items)
+}
+
+// RestoreMovie godoc
+// @Summary      Restore movie from trash
+// @Description  Restores a deleted movie together with its ratings, watchlist items and watch history.
+// @Tags trash
+// @Accept       json
+// @Produce      json
+// @Param id path int true "Movie id"
+// @Success      200  "OK"
+// @Failure   	 400  {object} models.Problem "Invalid movie id"
+// @Failure   	 403  {object} models.Problem "Insufficient permissions"
+// @Failure   	 404  {object} models.Problem "Movie is not in the trash"
+// @Failure   	 500  {object} models.Problem
+// @Router       /movies/{id}/restore [post]
+// @Security Bearer
+func (h *TrashHandler) RestoreMovie(c *gin.Context) {
+	h.restore(c, models.EntityMovie)
+}
+
+// RestoreGenre godoc
+// @Summary      Restore genre from trash
+// @Description  Restores a deleted genre, movies that had it get it back.
+// @Tags trash
+// @Accept       json
+// @Produce      json
+// @Param id path int true "Genre id"
+// @Success      200  "OK"
+// @Failure   	 400  {object} models.Problem "Invalid genre id"
+// @Failure   	 403  {object} models.Problem "Insufficient permissions"
+// @Failure   	 404  {object} models.Problem "Genre is not in the trash"
+// @Failure   	 500  {object} models.Problem
+// @Router       /genres/{id}/restore [post]
+// @Security Bearer
+func (h *TrashHandler) RestoreGenre(c *gin.Context) {
+	h.restore(c, models.EntityGenre)
+}
+
+// RestoreUser godoc
+// @Summary      Restore user from trash
+// @Description  Restores a deleted user with their watchlists, ratings and history. The user has to sign in again.
+// @Tags trash
+// @Accept       json
+// @Produce      json
+// @Param id path int true "User id"
+// @Success      200  "OK"
+// @Failure   	 400  {object} models.Problem "Invalid user id"
+// @Failure   	 403  {object} models.Problem "Insufficient permissions"
+// @Failure   	 404  {object} models.Problem "User is not in the trash"
+// @Failure   	 500  {object} models.Problem
+// @Router       /users/{id}/restore [post]
+// @Security Bearer
+func (h *TrashHandler) RestoreUser(c *gin.Context) {
+	h.restore(c, models.EntityUser)
+}
+
+func (h *TrashHandler) restore(c *gin.Context, entity string) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(models.NewInvalidFieldError("id", "Invalid "+entity+" id"))
+		return
+	}
+
+	err = h.trashRepo.Restore(c, entity, id, c.GetInt("userId"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
@@ -4,6 +4,7 @@ import (
 	"goozinshe/models"
 	"goozinshe/repositories"
 	"net/http"
+	"slices"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
@@ -11,28 +12,35 @@ import (
 )
 
 type UsersHandler struct {
-	userRepo *repositories.UsersRepository
+	userRepo     *repositories.UsersRepository
+	sessionsRepo *repositories.SessionsRepository
 }
 
-func NewUsersHandler(repo *repositories.UsersRepository) *UsersHandler {
-	return &UsersHandler{userRepo: repo}
+func NewUsersHandler(repo *repositories.UsersRepository, sessionsRepo *repositories.SessionsRepository) *UsersHandler {
+	return &UsersHandler{userRepo: repo, sessionsRepo: sessionsRepo}
 }
 
 type createUserRequest struct {
-	Name     string `json:"name" binding:"required,min=2,max=50"`
+	Name     string `json:"name" binding:"required,notblank,min=2,max=50"`
 	Email    string `json:"email" binding:"required,email"`
 	Password string `json:"password" binding:"required,min=8"`
+	Role     string `json:"role" binding:"omitempty,oneof=admin editor viewer"`
 }
 
 type updateUserRequest struct {
-	Name  string
-	Email string
+	Name  string `json:"name" binding:"required,notblank,min=2,max=50"`
+	Email string `json:"email" binding:"required,email"`
 }
 
 type userResponse struct {
 	Id    int    `json:"id"`
 	Name  string `json:"name"`
 	Email string `json:"email"`
+	Role  string `json:"role"`
+}
+
+type changeRoleRequest struct {
+	Role string `json:"role" binding:"required,oneof=admin editor viewer"`
 }
 
 type ChangePasswordRequest struct {
@@ -44,20 +52,33 @@ type ChangePasswordRequest struct {
 // @Summary      Get users list
 // @Accept       json
 // @Produce      json
+// @Param page query int false "Page number, starting from 1"
+// @Param limit query int false "Page size (max 100)"
+// @Param cursor query string false "Opaque cursor from a Link header, takes precedence over page"
 // @Success      200  {array} handlers.userResponse "OK"
-// @Failure   	 500  {object} models.ApiError
+// @Header       200  {int} X-Total-Count "Total number of items"
+// @Header       200  {string} Link "Next and previous pages"
+// @Failure   	 400  {object} models.Problem "Invalid paging parameters"
+// @Failure   	 403  {object} models.Problem "Insufficient permissions"
+// @Failure   	 500  {object} models.Problem
 // @Router       /users [get]
 // @Security Bearer
 func (h *UsersHandler) FindAll(c *gin.Context) {
-	users, err := h.userRepo.FindAll(c)
+	page, ok := bindPageRequest(c)
+	if !ok {
+		return
+	}
+
+	users, info, err := h.userRepo.FindAll(c, page)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.NewApiError("couldn't load users"))
+		c.Error(err)
 		return
 	}
 	dtos := make([]userResponse, 0, len(users))
 	for _, u := range users {
-		dtos = append(dtos, userResponse{Id: u.Id, Name: u.Name, Email: u.Email})
+		dtos = append(dtos, userResponse{Id: u.Id, Name: u.Name, Email: u.Email, Role: u.Role})
 	}
+	writePageHeaders(c, page, info)
 	c.JSON(http.StatusOK, dtos)
 }
 
@@ -67,26 +88,30 @@ func (h *UsersHandler) FindAll(c *gin.Context) {
 // @Accept       json
 // @Produce      json
 // @Param id path int true "User id"
-// @Success      200  {array} handlers.userResponse "OK"
-// @Failure   	 400  {object} models.ApiError "Invalid user id"
-// @Failure   	 404  {object} models.ApiError "User not found"
-// @Failure   	 500  {object} models.ApiError
+// @Param If-None-Match header string false "ETag of a cached copy"
+// @Success      200  {object} handlers.userResponse "OK"
+// @Header       200  {string} ETag "Version of the user, for If-Match and If-None-Match"
+// @Success      304  "Not modified"
+// @Failure   	 400  {object} models.Problem "Invalid user id"
+// @Failure   	 404  {object} models.Problem "User not found"
+// @Failure   	 403  {object} models.Problem "Insufficient permissions"
+// @Failure   	 500  {object} models.Problem
 // @Router       /users/{id} [get]
 // @Security Bearer
 func (h *UsersHandler) FindById(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, models.NewApiError("Invalid user id"))
+		c.Error(models.NewInvalidFieldError("id", "Invalid user id"))
 		return
 	}
 
 	user, err := h.userRepo.FindById(c, id)
 	if err != nil {
-		c.JSON(http.StatusNotFound, models.NewApiError(err.Error()))
+		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, userResponse{Id: user.Id, Name: user.Name, Email: user.Email})
+	writeEntity(c, user.Version, userResponse{Id: user.Id, Name: user.Name, Email: user.Email, Role: user.Role})
 }
 
 // Create godoc
@@ -96,28 +121,44 @@ func (h *UsersHandler) FindById(c *gin.Context) {
 // @Produce      json
 // @Param request body handlers.createUserRequest true "User data"
 // @Success      200  {object} object{id=int} "OK"
-// @Failure   	 400  {object} models.ApiError "Invalid data"
-// @Failure   	 500  {object} models.ApiError
+// @Failure   	 400  {object} models.Problem "Invalid data"
+// @Failure   	 403  {object} models.Problem "Insufficient permissions"
+// @Failure   	 500  {object} models.Problem
 // @Router       /users [post]
 // @Security Bearer
 func (h *UsersHandler) Create(c *gin.Context) {
 	var request createUserRequest
-	if err := c.ShouldBindJSON(&request); err != nil {
-		c.JSON(http.StatusBadRequest, models.NewApiError(err.Error()))
+	fields, err := ruleViolations(c.ShouldBindJSON(&request))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	if err := h.checkEmail(c, request.Email, 0, &fields); err != nil {
+		c.Error(err)
+		return
+	}
+	if err := fields.err(); err != nil {
+		c.Error(err)
 		return
 	}
 
 	passwordHash, err := bcrypt.GenerateFromPassword([]byte(request.Password), bcrypt.DefaultCost)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.NewApiError("Failed to hash password"))
+		c.Error(err)
 		return
 	}
 
+	role := request.Role
+	if role == "" {
+		role = models.RoleViewer
+	}
+
 	id, err := h.userRepo.Create(c, models.User{
-		Name: request.Name, Email: request.Email, PasswordHash: string(passwordHash),
+		Name: request.Name, Email: request.Email, PasswordHash: string(passwordHash), Role: role,
 	})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.NewApiError("Couldn't create user"))
+		c.Error(err)
 		return
 	}
 
@@ -131,36 +172,55 @@ func (h *UsersHandler) Create(c *gin.Context) {
 // @Produce      json
 // @Param id path int true "User id"
 // @Param request body handlers.updateUserRequest true "User data"
+// @Param If-Match header string false "ETag of the user, the change fails with 412 when the user was changed since"
 // @Success      200  {object} object{id=int} "OK"
-// @Failure   	 400  {object} models.ApiError "Invalid data"
-// @Failure   	 404  {object} models.ApiError "User not found"
-// @Failure   	 500  {object} models.ApiError
+// @Failure   	 400  {object} models.Problem "Invalid data"
+// @Failure   	 404  {object} models.Problem "User not found"
+// @Failure   	 403  {object} models.Problem "Insufficient permissions"
+// @Failure   	 412  {object} models.Problem "User was changed by someone else"
+// @Failure   	 500  {object} models.Problem
 // @Router       /users/{id} [put]
 // @Security Bearer
 func (h *UsersHandler) Update(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, models.NewApiError("Invalid user id"))
+		c.Error(models.NewInvalidFieldError("id", "Invalid user id"))
 		return
 	}
 
 	var request updateUserRequest
-	if err := c.ShouldBindJSON(&request); err != nil {
-		c.JSON(http.StatusBadRequest, models.NewApiError("Couldn't bind json"))
+	fields, err := ruleViolations(c.ShouldBindJSON(&request))
+	if err != nil {
+		c.Error(err)
 		return
 	}
 
 	user, err := h.userRepo.FindById(c, id)
 	if err != nil {
-		c.JSON(http.StatusNotFound, models.NewApiError("User not found"))
+		c.Error(err)
+		return
+	}
+
+	version, ok := ifMatchVersion(c, user.Version)
+	if !ok {
+		return
+	}
+
+	if err := h.checkEmail(c, request.Email, id, &fields); err != nil {
+		c.Error(err)
+		return
+	}
+	if err := fields.err(); err != nil {
+		c.Error(err)
 		return
 	}
 
 	user.Name = request.Name
 	user.Email = request.Email
+	user.Version = version
 
 	if err := h.userRepo.Update(c, id, user); err != nil {
-		c.JSON(http.StatusNotFound, models.NewApiError(err.Error()))
+		c.Error(err)
 		return
 	}
 
@@ -170,42 +230,114 @@ func (h *UsersHandler) Update(c *gin.Context) {
 // ChangePassword godoc
 // @Tags users
 // @Summary      Change user password
+// @Description  Signs the user out everywhere except in the session that made the change.
 // @Accept       json
 // @Produce      json
 // @Param id path int true "User id"
 // @Param request body handlers.ChangePasswordRequest true "Password data"
+// @Param If-Match header string false "ETag of the user, the change fails with 412 when the user was changed since"
 // @Success      200  "OK"
-// @Failure   	 400  {object} models.ApiError "Invalid data"
-// @Failure   	 404  {object} models.ApiError "User not found"
-// @Failure   	 500  {object} models.ApiError
+// @Failure   	 400  {object} models.Problem "Invalid data"
+// @Failure   	 404  {object} models.Problem "User not found"
+// @Failure   	 403  {object} models.Problem "Insufficient permissions"
+// @Failure   	 412  {object} models.Problem "User was changed by someone else"
+// @Failure   	 500  {object} models.Problem
 // @Router       /users/{id}/changePassword [patch]
 // @Security Bearer
 func (h *UsersHandler) ChangePasswordHash(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, models.NewApiError("Invalid user id"))
+		c.Error(models.NewInvalidFieldError("id", "Invalid user id"))
+		return
+	}
+
+	user, err := h.userRepo.FindById(c, id)
+	if err != nil {
+		c.Error(err)
 		return
 	}
 
-	if _, err := h.userRepo.FindById(c, id); err != nil {
-		c.JSON(http.StatusNotFound, models.NewApiError("User not found"))
+	version, ok := ifMatchVersion(c, user.Version)
+	if !ok {
 		return
 	}
 
 	var request ChangePasswordRequest
 	if err := c.ShouldBindJSON(&request); err != nil {
-		c.JSON(http.StatusBadRequest, models.NewApiError(err.Error()))
+		c.Error(bindingError(err))
 		return
 	}
 
 	newPasswordHash, err := bcrypt.GenerateFromPassword([]byte(request.Password), bcrypt.DefaultCost)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.NewApiError("Failed to hash password"))
+		c.Error(err)
+		return
+	}
+
+	if err := h.userRepo.ChangePasswordHash(c, id, string(newPasswordHash), version); err != nil {
+		c.Error(err)
+		return
+	}
+
+	keepSessionId := ""
+	if id == c.GetInt("userId") {
+		keepSessionId = c.GetString("sessionId")
+	}
+	if err := h.sessionsRepo.RevokeAllForUser(c, id, keepSessionId); err != nil {
+		c.Error(err)
+		return
+	}
+	c.Status(http.StatusOK)
+}
+
+// ChangeRole godoc
+// @Tags users
+// @Summary      Change user role
+// @Description  Only admins can change roles. The new role takes effect once the user's access token is refreshed.
+// @Accept       json
+// @Produce      json
+// @Param id path int true "User id"
+// @Param request body handlers.changeRoleRequest true "Role data"
+// @Param If-Match header string false "ETag of the user, the change fails with 412 when the user was changed since"
+// @Success      200  "OK"
+// @Failure   	 400  {object} models.Problem "Invalid data"
+// @Failure   	 404  {object} models.Problem "User not found"
+// @Failure   	 403  {object} models.Problem "Insufficient permissions"
+// @Failure   	 412  {object} models.Problem "User was changed by someone else"
+// @Failure   	 500  {object} models.Problem
+// @Router       /users/{id}/role [patch]
+// @Security Bearer
+func (h *UsersHandler) ChangeRole(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(models.NewInvalidFieldError("id", "Invalid user id"))
+		return
+	}
+
+	var request changeRoleRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.Error(bindingError(err))
+		return
+	}
+
+	if id == c.GetInt("userId") && request.Role != models.RoleAdmin {
+		c.Error(models.NewInvalidFieldError("role", "Admins can't revoke their own admin role"))
+		return
+	}
+
+	user, err := h.userRepo.FindById(c, id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	version, ok := ifMatchVersion(c, user.Version)
+	if !ok {
 		return
 	}
 
-	if err := h.userRepo.ChangePasswordHash(c, id, string(newPasswordHash)); err != nil {
-		c.JSON(http.StatusNotFound, models.NewApiError(err.Error()))
+	if err := h.userRepo.ChangeRole(c, id, request.Role, version); err != nil {
+		c.Error(err)
 		return
 	}
 	c.Status(http.StatusOK)
@@ -214,30 +346,62 @@ func (h *UsersHandler) ChangePasswordHash(c *gin.Context) {
 // Delete godoc
 // @Tags users
 // @Summary      Delete user
+// @Description  Moves the user to the trash and signs them out everywhere.
 // @Accept       json
 // @Produce      json
 // @Param id path int true "User id"
+// @Param If-Match header string false "ETag of the user, the change fails with 412 when the user was changed since"
 // @Success      200  "OK"
-// @Failure   	 400  {object} models.ApiError "Invalid data"
-// @Failure   	 404  {object} models.ApiError "User not found"
-// @Failure   	 500  {object} models.ApiError
+// @Failure   	 400  {object} models.Problem "Invalid data"
+// @Failure   	 404  {object} models.Problem "User not found"
+// @Failure   	 403  {object} models.Problem "Insufficient permissions"
+// @Failure   	 412  {object} models.Problem "User was changed by someone else"
+// @Failure   	 500  {object} models.Problem
 // @Router       /users/{id} [delete]
 // @Security Bearer
 func (h *UsersHandler) Delete(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, models.NewApiError("Invalid user id"))
+		c.Error(models.NewInvalidFieldError("id", "Invalid user id"))
+		return
+	}
+
+	user, err := h.userRepo.FindById(c, id)
+	if err != nil {
+		c.Error(err)
 		return
 	}
 
-	if _, err := h.userRepo.FindById(c, id); err != nil {
-		c.JSON(http.StatusNotFound, models.NewApiError("User not found"))
+	version, ok := ifMatchVersion(c, user.Version)
+	if !ok {
 		return
 	}
 
-	if err := h.userRepo.Delete(c, id); err != nil {
-		c.JSON(http.StatusNotFound, models.NewApiError(err.Error()))
+	if err := h.userRepo.Delete(c, id, version); err != nil {
+		c.Error(err)
+		return
+	}
+
+	if err := h.sessionsRepo.RevokeAllForUser(c, id, ""); err != nil {
+		c.Error(err)
 		return
 	}
 	c.Status(http.StatusOK)
 }
+
+// checkEmail adds a violation when another user already has the email. An
+// email that broke a binding rule isn't looked up.
+func (h *UsersHandler) checkEmail(c *gin.Context, email string, userId int, fields *violations) error {
+	if slices.ContainsFunc(*fields, func(field models.FieldError) bool { return field.Field == "email" }) {
+		return nil
+	}
+
+	taken, err := h.userRepo.IsEmailTaken(c, email, userId)
+	if err != nil {
+		return err
+	}
+	if taken {
+		fields.add("email", models.CodeTaken, "email is already taken")
+	}
+	return nil
+}
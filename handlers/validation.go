@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"goozinshe/models"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"github.com/go-playground/validator/v10/non-standard/validators"
+)
+
+// The first motion picture is from 1888, movies are announced a few years
+// before their release.
+const (
+	firstReleaseYear  = 1888
+	releaseYearsAhead = 5
+)
+
+func init() {
+	// Report rejected fields by the names clients send them under.
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(payloadFieldName)
+		v.RegisterValidation("notblank", validators.NotBlank)
+		v.RegisterValidation("release_year", isReleaseYear)
+	}
+}
+
+func payloadFieldName(field reflect.StructField) string {
+	for _, key := range []string{"json", "form"} {
+		name, _, _ := strings.Cut(field.Tag.Get(key), ",")
+		if name == "-" {
+			return ""
+		}
+		if name != "" {
+			return name
+		}
+	}
+	return field.Name
+}
+
+func isReleaseYear(fl validator.FieldLevel) bool {
+	year := fl.Field().Int()
+	return year >= firstReleaseYear && year <= int64(lastReleaseYear())
+}
+
+func lastReleaseYear() int {
+	return time.Now().Year() + releaseYearsAhead
+}
+
+// violations collects the rejected fields of a payload, so that its binding
+// rules and the checks against stored data are reported together.
+type violations []models.FieldError
+
+func (v *violations) add(field string, code string, message string) {
+	*v = append(*v, models.FieldError{Field: field, Code: code, Message: message})
+}
+
+// err rejects the payload with all collected violations, or returns nil when
+// there are none.
+func (v violations) err() error {
+	if len(v) == 0 {
+		return nil
+	}
+	return models.NewValidationError("Invalid payload", v...)
+}
+
+// ruleViolations splits the error of a binding or validation into the fields
+// that broke a rule and errors that prevented binding at all.
+func ruleViolations(err error) (violations, error) {
+	if err == nil {
+		return nil, nil
+	}
+
+	var validationErrs validator.ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		return nil, bindingError(err)
+	}
+
+	fields := make(violations, 0, len(validationErrs))
+	for _, e := range validationErrs {
+		path := fieldPath(e)
+		fields.add(path, e.Tag(), ruleMessage(path, e))
+	}
+	return fields, nil
+}
+
+// fieldPath returns the path of the field within the payload, such as
+// genreIds[1], without the name of the request type.
+func fieldPath(e validator.FieldError) string {
+	_, path, found := strings.Cut(e.Namespace(), ".")
+	if !found {
+		return e.Field()
+	}
+	return path
+}
+
+// bindingError describes why ShouldBind rejected a payload, listing every
+// field that failed its binding rules.
+func bindingError(err error) error {
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		fields, _ := ruleViolations(err)
+		return fields.err()
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		return models.NewInvalidFieldError(typeErr.Field, fmt.Sprintf("%s must be of type %s", typeErr.Field, typeErr.Type))
+	}
+
+	return models.NewValidationError("Couldn't bind payload")
+}
+
+func ruleMessage(field string, e validator.FieldError) string {
+	switch e.Tag() {
+	case "required":
+		return fmt.Sprintf("%s is required", field)
+	case "notblank":
+		return fmt.Sprintf("%s must not be blank", field)
+	case "email":
+		return fmt.Sprintf("%s must be an email address", field)
+	case "http_url":
+		return fmt.Sprintf("%s must be an http or https URL", field)
+	case "oneof":
+		return fmt.Sprintf("%s must be one of: %s", field, e.Param())
+	case "unique":
+		return fmt.Sprintf("%s must not contain duplicates", field)
+	case "datetime":
+		return fmt.Sprintf("%s must be a date formatted as YYYY-MM-DD", field)
+	case "release_year":
+		return fmt.Sprintf("%s must be between %d and %d", field, firstReleaseYear, lastReleaseYear())
+	case "gt":
+		return fmt.Sprintf("%s must be greater than %s", field, e.Param())
+	case "min", "max":
+		bound := "at least"
+		if e.Tag() == "max" {
+			bound = "at most"
+		}
+		switch e.Kind() {
+		case reflect.String:
+			return fmt.Sprintf("%s must be %s %s characters long", field, bound, e.Param())
+		case reflect.Slice, reflect.Array, reflect.Map:
+			return fmt.Sprintf("%s must have %s %s items", field, bound, e.Param())
+		default:
+			return fmt.Sprintf("%s must be %s %s", field, bound, e.Param())
+		}
+	default:
+		return fmt.Sprintf("%s is invalid", field)
+	}
+}
@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"goozinshe/models"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/gin-gonic/gin/binding"
+)
+
+type testPayload struct {
+	Title      string   `json:"title" binding:"required,notblank,max=5"`
+	Year       int      `json:"releaseYear" binding:"release_year"`
+	GenreIds   []int    `json:"genreIds" binding:"min=1,dive,gt=0"`
+	TrailerUrl string   `form:"trailerUrl" binding:"omitempty,http_url"`
+	Tags       []string `json:"tags" binding:"max=1,unique"`
+	Internal   string   `json:"-" binding:"omitempty,email"`
+}
+
+func TestRuleViolations(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload testPayload
+		want    violations
+	}{
+		{
+			name:    "valid",
+			payload: testPayload{Title: "Heat", Year: 1995, GenreIds: []int{1}},
+			want:    nil,
+		},
+		{
+			name:    "missing title",
+			payload: testPayload{Year: 1995, GenreIds: []int{1}},
+			want:    violations{{Field: "title", Code: "required", Message: "title is required"}},
+		},
+		{
+			name:    "blank title",
+			payload: testPayload{Title: "  ", Year: 1995, GenreIds: []int{1}},
+			want:    violations{{Field: "title", Code: "notblank", Message: "title must not be blank"}},
+		},
+		{
+			name: "every field",
+			payload: testPayload{
+				Title:      "Heat 2",
+				Year:       1800,
+				GenreIds:   []int{1, 0},
+				TrailerUrl: "ftp://example.com",
+				Tags:       []string{"a", "a"},
+				Internal:   "nobody",
+			},
+			want: violations{
+				{Field: "title", Code: "max", Message: "title must be at most 5 characters long"},
+				{Field: "releaseYear", Code: "release_year", Message: fmt.Sprintf("releaseYear must be between 1888 and %d", lastReleaseYear())},
+				{Field: "genreIds[1]", Code: "gt", Message: "genreIds[1] must be greater than 0"},
+				{Field: "trailerUrl", Code: "http_url", Message: "trailerUrl must be an http or https URL"},
+				{Field: "tags", Code: "max", Message: "tags must have at most 1 items"},
+				{Field: "Internal", Code: "email", Message: "Internal must be an email address"},
+			},
+		},
+		{
+			name:    "too few genres",
+			payload: testPayload{Title: "Heat", Year: 1995, GenreIds: []int{}},
+			want:    violations{{Field: "genreIds", Code: "min", Message: "genreIds must have at least 1 items"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ruleViolations(binding.Validator.ValidateStruct(tt.payload))
+			if err != nil {
+				t.Fatalf("ruleViolations returned error %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ruleViolations =\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRuleViolationsOfBindingErrors(t *testing.T) {
+	if fields, err := ruleViolations(nil); fields != nil || err != nil {
+		t.Errorf("ruleViolations(nil) = %v, %v, want nil, nil", fields, err)
+	}
+
+	fields, err := ruleViolations(io.EOF)
+	var validationErr *models.ValidationError
+	if fields != nil || !errors.As(err, &validationErr) || len(validationErr.Fields) != 0 {
+		t.Errorf("ruleViolations(EOF) = %v, %v, want a validation error without fields", fields, err)
+	}
+}
+
+func TestBindingError(t *testing.T) {
+	var payload testPayload
+	typeErr := json.Unmarshal([]byte(`{"releaseYear": "soon"}`), &payload)
+
+	tests := []struct {
+		name string
+		err  error
+		want []models.FieldError
+	}{
+		{
+			name: "wrong type",
+			err:  typeErr,
+			want: []models.FieldError{{Field: "releaseYear", Code: models.CodeInvalid, Message: "releaseYear must be of type int"}},
+		},
+		{
+			name: "broken rules",
+			err:  binding.Validator.ValidateStruct(testPayload{Year: 1995, GenreIds: []int{1}}),
+			want: []models.FieldError{{Field: "title", Code: "required", Message: "title is required"}},
+		},
+		{
+			name: "malformed payload",
+			err:  io.ErrUnexpectedEOF,
+			want: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var validationErr *models.ValidationError
+			if err := bindingError(tt.err); !errors.As(err, &validationErr) {
+				t.Fatalf("bindingError = %v, want a validation error", err)
+			}
+			if validationErr.Code != models.CodeValidationFailed {
+				t.Errorf("Code = %q, want %q", validationErr.Code, models.CodeValidationFailed)
+			}
+			if !reflect.DeepEqual(validationErr.Fields, tt.want) {
+				t.Errorf("Fields =\n%+v\nwant\n%+v", validationErr.Fields, tt.want)
+			}
+		})
+	}
+}
+
+func TestViolationsErr(t *testing.T) {
+	var v violations
+	if err := v.err(); err != nil {
+		t.Errorf("err() without violations = %v, want nil", err)
+	}
+
+	v.add("title", "notblank", "title must not be blank")
+	v.add("genreIds", "exists", "genre 7 doesn't exist")
+
+	var validationErr *models.ValidationError
+	if err := v.err(); !errors.As(err, &validationErr) || len(validationErr.Fields) != 2 {
+		t.Errorf("err() = %v, want a validation error with both fields", err)
+	}
+}
@@ -8,6 +8,8 @@ import (
 	"goozinshe/models"
 )
 
+var errDefaultWatchlist = models.NewConflictError("default_watchlist", "The default watchlist can't be deleted")
+
 type WatchlistHandler struct {
 	watchlistRepo *repositories.WatchlistRepository
 }
@@ -19,49 +21,80 @@ func NewWatchlistHandler(
 	}
 }
 
+type watchlistRequest struct {
+	Title      string `json:"title" binding:"required,notblank,max=100"`
+	Visibility string `json:"visibility" binding:"omitempty,oneof=private link public"`
+}
+
+type addWatchlistItemRequest struct {
+	Note string `json:"note" binding:"max=1000"`
+}
+
+type updateWatchlistItemRequest struct {
+	Note     *string `json:"note" binding:"omitempty,max=1000"`
+	Position *int    `json:"position" binding:"omitempty,min=1"`
+}
+
+type reorderWatchlistRequest struct {
+	MovieIds []int `json:"movieIds" binding:"required,unique,dive,gt=0"`
+}
+
 // FindAll godoc
-// @Summary      Add movie to watchlist
+// @Summary      Get default watchlist
+// @Description  Returns the movies of the current user's default watchlist in list order.
 // @Tags watchlist
 // @Accept       json
 // @Produce      json
-// @Param movieId path int true "Movie id"
-// @Success      200 "OK"
-// @Failure   	 400  {object} models.ApiError "Invalid data"
-// @Failure   	 500  {object} models.ApiError
-// @Router       /watchlist/:movieId [post]
+// @Param page query int false "Page number, starting from 1"
+// @Param limit query int false "Page size (max 100)"
+// @Param cursor query string false "Opaque cursor from a Link header, takes precedence over page"
+// @Success      200  {array} models.Movie "OK"
+// @Header       200  {int} X-Total-Count "Total number of items"
+// @Header       200  {string} Link "Next and previous pages"
+// @Failure   	 400  {object} models.Problem "Invalid paging parameters"
+// @Failure   	 500  {object} models.Problem
+// @Router       /watchlist [get]
 // @Security Bearer
 func (h *WatchlistHandler) FindAll(c *gin.Context) {
-	movies, err := h.watchlistRepo.FindAll(c)
+	page, ok := bindPageRequest(c)
+	if !ok {
+		return
+	}
+
+	movies, info, err := h.watchlistRepo.FindAll(c, c.GetInt("userId"), c.GetStringSlice("locales"), page)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, err)
+		c.Error(err)
 		return
 	}
+	writePageHeaders(c, page, info)
 	c.JSON(http.StatusOK, movies)
 }
 
 // AddToWatchlist godoc
 // @Summary      Add movie to watchlist
+// @Description  Adds a movie to the current user's default watchlist.
 // @Tags watchlist
 // @Accept       json
 // @Produce      json
 // @Param movieId path int true "Movie id"
 // @Success      200 "OK"
-// @Failure   	 400  {object} models.ApiError "Invalid data"
-// @Failure   	 500  {object} models.ApiError
-// @Router       /watchlist/:movieId [post]
+// @Failure   	 400  {object} models.Problem "Invalid data"
+// @Failure   	 404  {object} models.Problem "Movie not found"
+// @Failure   	 500  {object} models.Problem
+// @Router       /watchlist/{movieId} [post]
 // @Security Bearer
 func (h *WatchlistHandler) AddToWatchlist(c *gin.Context) {
 	idStr := c.Param("movieId")
 	movieId, err := strconv.Atoi(idStr)
 
 	if err != nil {
-		c.JSON(http.StatusBadRequest, models.NewApiError("Invalid Movie Id"))
+		c.Error(models.NewInvalidFieldError("movieId", "Invalid movie id"))
 		return
 	}
 
-	err = h.watchlistRepo.AddToWatchlist(c, movieId)
+	err = h.watchlistRepo.AddToWatchlist(c, c.GetInt("userId"), movieId)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "Movie added to watchlist"})
@@ -69,29 +102,414 @@ func (h *WatchlistHandler) AddToWatchlist(c *gin.Context) {
 
 // Delete godoc
 // @Summary      Remove movie from watchlist
+// @Description  Removes a movie from the current user's default watchlist.
 // @Tags watchlist
 // @Accept       json
 // @Produce      json
 // @Param movieId path int true "Movie id"
 // @Success      200 "OK"
-// @Failure   	 400  {object} models.ApiError "Invalid data"
-// @Failure   	 500  {object} models.ApiError
-// @Router       /watchlist/:movieId [delete]
+// @Failure   	 400  {object} models.Problem "Invalid data"
+// @Failure   	 404  {object} models.Problem "Movie is not in the watchlist"
+// @Failure   	 500  {object} models.Problem
+// @Router       /watchlist/{movieId} [delete]
 // @Security Bearer
 func (h *WatchlistHandler) Delete(c *gin.Context) {
 	idStr := c.Param("movieId")
 	movieid, err := strconv.Atoi(idStr)
 
 	if err != nil {
-		c.JSON(http.StatusBadRequest, models.NewApiError("Invalid Movie Id"))
+		c.Error(models.NewInvalidFieldError("movieId", "Invalid movie id"))
+		return
+	}
+
+	err = h.watchlistRepo.Delete(c, c.GetInt("userId"), movieid)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// FindLists godoc
+// @Summary      Get watchlists
+// @Description  Returns all watchlists of the current user. The default list comes first.
+// @Tags watchlist
+// @Accept       json
+// @Produce      json
+// @Success      200  {array} models.Watchlist "OK"
+// @Failure   	 500  {object} models.Problem
+// @Router       /watchlists [get]
+// @Security Bearer
+func (h *WatchlistHandler) FindLists(c *gin.Context) {
+	watchlists, err := h.watchlistRepo.FindAllByUser(c, c.GetInt("userId"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, watchlists)
+}
+
+// CreateList godoc
+// @Summary      Create watchlist
+// @Tags watchlist
+// @Accept       json
+// @Produce      json
+// @Param request body handlers.watchlistRequest true "Watchlist data"
+// @Success      200  {object} object{id=int} "OK"
+// @Failure   	 400  {object} models.Problem "Invalid data"
+// @Failure   	 500  {object} models.Problem
+// @Router       /watchlists [post]
+// @Security Bearer
+func (h *WatchlistHandler) CreateList(c *gin.Context) {
+	var request watchlistRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.Error(bindingError(err))
+		return
+	}
+
+	id, err := h.watchlistRepo.Create(c, models.Watchlist{
+		UserId:     c.GetInt("userId"),
+		Title:      request.Title,
+		Visibility: visibilityOrDefault(request.Visibility),
+	})
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"id": id})
+}
+
+// FindListById godoc
+// @Summary      Get watchlist
+// @Description  Returns a watchlist with its items. Other users' lists are only visible when they are public.
+// @Tags watchlist
+// @Accept       json
+// @Produce      json
+// @Param listId path int true "Watchlist id"
+// @Success      200  {object} models.Watchlist "OK"
+// @Failure   	 400  {object} models.Problem "Invalid watchlist id"
+// @Failure   	 404  {object} models.Problem "Watchlist not found"
+// @Failure   	 500  {object} models.Problem
+// @Router       /watchlists/{listId} [get]
+// @Security Bearer
+func (h *WatchlistHandler) FindListById(c *gin.Context) {
+	watchlist, ok := h.findVisibleList(c)
+	if !ok {
+		return
+	}
+
+	h.respondWithItems(c, watchlist)
+}
+
+// FindShared godoc
+// @Summary      Get shared watchlist
+// @Description  Returns a watchlist shared by link. No authentication is required.
+// @Tags watchlist
+// @Accept       json
+// @Produce      json
+// @Param token path string true "Share token"
+// @Success      200  {object} models.Watchlist "OK"
+// @Failure   	 404  {object} models.Problem "Watchlist not found"
+// @Failure   	 500  {object} models.Problem
+// @Router       /shared/watchlists/{token} [get]
+func (h *WatchlistHandler) FindShared(c *gin.Context) {
+	watchlist, err := h.watchlistRepo.FindByShareToken(c, c.Param("token"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	watchlist.ShareToken = ""
+	h.respondWithItems(c, watchlist)
+}
+
+// UpdateList godoc
+// @Summary      Update watchlist
+// @Description  Renames a watchlist or changes its visibility. Switching to "link" issues a share token; switching away from it revokes the token.
+// @Tags watchlist
+// @Accept       json
+// @Produce      json
+// @Param listId path int true "Watchlist id"
+// @Param request body handlers.watchlistRequest true "Watchlist data"
+// @Success      200  "OK"
+// @Failure   	 400  {object} models.Problem "Invalid data"
+// @Failure   	 404  {object} models.Problem "Watchlist not found"
+// @Failure   	 500  {object} models.Problem
+// @Router       /watchlists/{listId} [put]
+// @Security Bearer
+func (h *WatchlistHandler) UpdateList(c *gin.Context) {
+	watchlist, ok := h.findOwnList(c)
+	if !ok {
+		return
+	}
+
+	var request watchlistRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.Error(bindingError(err))
+		return
+	}
+
+	watchlist.Title = request.Title
+	watchlist.Visibility = visibilityOrDefault(request.Visibility)
+
+	if err := h.watchlistRepo.Update(c, watchlist.Id, watchlist); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// DeleteList godoc
+// @Summary      Delete watchlist
+// @Description  Deletes a watchlist with all its items. The default watchlist can't be deleted.
+// @Tags watchlist
+// @Accept       json
+// @Produce      json
+// @Param listId path int true "Watchlist id"
+// @Success      200  "OK"
+// @Failure   	 400  {object} models.Problem "Invalid data"
+// @Failure   	 404  {object} models.Problem "Watchlist not found"
+// @Failure   	 409  {object} models.Problem "The default watchlist can't be deleted"
+// @Failure   	 500  {object} models.Problem
+// @Router       /watchlists/{listId} [delete]
+// @Security Bearer
+func (h *WatchlistHandler) DeleteList(c *gin.Context) {
+	watchlist, ok := h.findOwnList(c)
+	if !ok {
+		return
+	}
+
+	if watchlist.IsDefault {
+		c.Error(errDefaultWatchlist)
+		return
+	}
+
+	if err := h.watchlistRepo.DeleteList(c, watchlist.Id); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// AddItem godoc
+// @Summary      Add movie to a watchlist
+// @Description  Appends a movie to the end of the list. Adding a movie that is already in the list only updates its note.
+// @Tags watchlist
+// @Accept       json
+// @Produce      json
+// @Param listId path int true "Watchlist id"
+// @Param movieId path int true "Movie id"
+// @Param request body handlers.addWatchlistItemRequest false "Item data"
+// @Success      200  "OK"
+// @Failure   	 400  {object} models.Problem "Invalid data"
+// @Failure   	 404  {object} models.Problem "Watchlist or movie not found"
+// @Failure   	 500  {object} models.Problem
+// @Router       /watchlists/{listId}/items/{movieId} [post]
+// @Security Bearer
+func (h *WatchlistHandler) AddItem(c *gin.Context) {
+	watchlist, ok := h.findOwnList(c)
+	if !ok {
+		return
+	}
+
+	movieId, err := strconv.Atoi(c.Param("movieId"))
+	if err != nil {
+		c.Error(models.NewInvalidFieldError("movieId", "Invalid movie id"))
+		return
+	}
+
+	var request addWatchlistItemRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.Error(bindingError(err))
+			return
+		}
+	}
+
+	err = h.watchlistRepo.AddItem(c, watchlist.Id, movieId, request.Note)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// UpdateItem godoc
+// @Summary      Update watchlist item
+// @Description  Changes the note of a watchlist item and/or moves it to a new 1-based position.
+// @Tags watchlist
+// @Accept       json
+// @Produce      json
+// @Param listId path int true "Watchlist id"
+// @Param movieId path int true "Movie id"
+// @Param request body handlers.updateWatchlistItemRequest true "Item data"
+// @Success      200  "OK"
+// @Failure   	 400  {object} models.Problem "Invalid data"
+// @Failure   	 404  {object} models.Problem "Watchlist or item not found"
+// @Failure   	 500  {object} models.Problem
+// @Router       /watchlists/{listId}/items/{movieId} [patch]
+// @Security Bearer
+func (h *WatchlistHandler) UpdateItem(c *gin.Context) {
+	watchlist, ok := h.findOwnList(c)
+	if !ok {
 		return
 	}
 
-	err = h.watchlistRepo.Delete(c, movieid)
+	movieId, err := strconv.Atoi(c.Param("movieId"))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		c.Error(models.NewInvalidFieldError("movieId", "Invalid movie id"))
+		return
+	}
+
+	var request updateWatchlistItemRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.Error(bindingError(err))
 		return
 	}
 
+	if request.Note != nil {
+		err = h.watchlistRepo.UpdateItemNote(c, watchlist.Id, movieId, *request.Note)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+	}
+
+	if request.Position != nil {
+		err = h.watchlistRepo.MoveItem(c, watchlist.Id, movieId, *request.Position)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+	}
+
 	c.Status(http.StatusOK)
-}
\ No newline at end of file
+}
+
+// ReorderItems godoc
+// @Summary      Reorder watchlist
+// @Description  Sets the order of all items of a watchlist at once. movieIds must list every movie of the watchlist exactly once.
+// @Tags watchlist
+// @Accept       json
+// @Produce      json
+// @Param listId path int true "Watchlist id"
+// @Param request body handlers.reorderWatchlistRequest true "New order"
+// @Success      200  "OK"
+// @Failure   	 400  {object} models.Problem "Invalid data"
+// @Failure   	 404  {object} models.Problem "Watchlist not found"
+// @Failure   	 500  {object} models.Problem
+// @Router       /watchlists/{listId}/items [put]
+// @Security Bearer
+func (h *WatchlistHandler) ReorderItems(c *gin.Context) {
+	watchlist, ok := h.findOwnList(c)
+	if !ok {
+		return
+	}
+
+	var request reorderWatchlistRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.Error(bindingError(err))
+		return
+	}
+
+	err := h.watchlistRepo.Reorder(c, watchlist.Id, request.MovieIds)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// RemoveItem godoc
+// @Summary      Remove movie from a watchlist
+// @Tags watchlist
+// @Accept       json
+// @Produce      json
+// @Param listId path int true "Watchlist id"
+// @Param movieId path int true "Movie id"
+// @Success      200  "OK"
+// @Failure   	 400  {object} models.Problem "Invalid data"
+// @Failure   	 404  {object} models.Problem "Watchlist not found, or movie is not in it"
+// @Failure   	 500  {object} models.Problem
+// @Router       /watchlists/{listId}/items/{movieId} [delete]
+// @Security Bearer
+func (h *WatchlistHandler) RemoveItem(c *gin.Context) {
+	watchlist, ok := h.findOwnList(c)
+	if !ok {
+		return
+	}
+
+	movieId, err := strconv.Atoi(c.Param("movieId"))
+	if err != nil {
+		c.Error(models.NewInvalidFieldError("movieId", "Invalid movie id"))
+		return
+	}
+
+	if err := h.watchlistRepo.RemoveItem(c, watchlist.Id, movieId); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// findVisibleList loads the watchlist from the listId path parameter if the
+// current user owns it or it is public. It records the error itself.
+func (h *WatchlistHandler) findVisibleList(c *gin.Context) (models.Watchlist, bool) {
+	id, err := strconv.Atoi(c.Param("listId"))
+	if err != nil {
+		c.Error(models.NewInvalidFieldError("listId", "Invalid watchlist id"))
+		return models.Watchlist{}, false
+	}
+
+	watchlist, err := h.watchlistRepo.FindById(c, id)
+	if err != nil {
+		c.Error(err)
+		return models.Watchlist{}, false
+	}
+
+	if watchlist.UserId != c.GetInt("userId") {
+		if watchlist.Visibility != models.WatchlistPublic {
+			c.Error(repositories.ErrWatchlistNotFound)
+			return models.Watchlist{}, false
+		}
+		watchlist.ShareToken = ""
+	}
+
+	return watchlist, true
+}
+
+// findOwnList is like findVisibleList but only accepts lists of the current user.
+func (h *WatchlistHandler) findOwnList(c *gin.Context) (models.Watchlist, bool) {
+	watchlist, ok := h.findVisibleList(c)
+	if ok && watchlist.UserId != c.GetInt("userId") {
+		c.Error(repositories.ErrWatchlistNotFound)
+		return models.Watchlist{}, false
+	}
+
+	return watchlist, ok
+}
+
+func (h *WatchlistHandler) respondWithItems(c *gin.Context, watchlist models.Watchlist) {
+	items, err := h.watchlistRepo.FindItems(c, watchlist.Id, c.GetInt("userId"), c.GetStringSlice("locales"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	watchlist.Items = items
+	c.JSON(http.StatusOK, watchlist)
+}
+
+func visibilityOrDefault(visibility string) string {
+	if visibility == "" {
+		return models.WatchlistPrivate
+	}
+	return visibility
+}
@@ -0,0 +1,191 @@
+// Package imaging generates the resized variants of uploaded posters.
+package imaging
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	_ "image/png"
+
+	"github.com/HugoSmits86/nativewebp"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+const (
+	FormatJPEG = "jpeg"
+	FormatWebP = "webp"
+)
+
+const jpegQuality = 82
+
+var (
+	ErrUnsupportedImage = errors.New("image could not be decoded")
+	ErrImageTooLarge    = errors.New("image is too large to be decoded")
+)
+
+// Variant is a resized version of a poster, at most Width pixels wide.
+type Variant struct {
+	Name  string
+	Width int
+}
+
+var Variants = []Variant{
+	{Name: "thumb", Width: 160},
+	{Name: "card", Width: 400},
+	{Name: "full", Width: 1200},
+}
+
+var Formats = []string{FormatWebP, FormatJPEG}
+
+// Rendition is an encoded variant.
+type Rendition struct {
+	Key         string
+	ContentType string
+	Data        []byte
+}
+
+func FindVariant(name string) (Variant, bool) {
+	for _, v := range Variants {
+		if v.Name == name {
+			return v, true
+		}
+	}
+	return Variant{}, false
+}
+
+func ContentType(format string) string {
+	if format == FormatWebP {
+		return "image/webp"
+	}
+	return "image/jpeg"
+}
+
+// VariantKey is the storage key of a variant of the image stored under key,
+// e.g. "poster.png" becomes "poster_card.webp".
+func VariantKey(key string, variant string, format string) string {
+	extension := "jpg"
+	if format == FormatWebP {
+		extension = "webp"
+	}
+	return fmt.Sprintf("%s_%s.%s", strings.TrimSuffix(key, filepath.Ext(key)), variant, extension)
+}
+
+var contentKeyPattern = regexp.MustCompile(`^([0-9a-f]{64})\.[a-z]+$`)
+
+// ContentKey is the storage key of an original image, the SHA-256 hash of its
+// content followed by the extension.
+func ContentKey(data []byte, extension string) string {
+	return fmt.Sprintf("%x%s", sha256.Sum256(data), extension)
+}
+
+// ParseContentKey returns the content hash of a key created by ContentKey.
+// Posters uploaded before keys were content hashes have random keys.
+func ParseContentKey(key string) (string, bool) {
+	match := contentKeyPattern.FindStringSubmatch(key)
+	if match == nil {
+		return "", false
+	}
+	return match[1], true
+}
+
+var variantKeyPattern = regexp.MustCompile(`^(.+)_(thumb|card|full)\.(jpg|webp)$`)
+
+// ParseVariantKey returns the key of the original image without its
+// extension, when key looks like the key of a variant.
+func ParseVariantKey(key string) (string, bool) {
+	match := variantKeyPattern.FindStringSubmatch(key)
+	if match == nil {
+		return "", false
+	}
+	return match[1], true
+}
+
+// Decode decodes an image and turns it upright according to its EXIF
+// orientation. Images beyond the poster dimension limits aren't decoded, so
+// a small file can't make it allocate a huge image.
+func Decode(data []byte) (image.Image, error) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedImage
+	}
+	if config.Width > MaxPosterWidth || config.Height > MaxPosterHeight {
+		return nil, ErrImageTooLarge
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedImage
+	}
+	return orient(img, jpegOrientation(data)), nil
+}
+
+// VariantKeys returns the storage keys of all variants of the image stored
+// under key.
+func VariantKeys(key string) []string {
+	keys := make([]string, 0, len(Variants)*len(Formats))
+	for _, variant := range Variants {
+		for _, format := range Formats {
+			keys = append(keys, VariantKey(key, variant.Name, format))
+		}
+	}
+	return keys
+}
+
+// Generate renders every variant of the image in every format. Images are
+// only scaled down. WebP variants are lossless, since there is no lossy WebP
+// encoder without cgo.
+func Generate(key string, src image.Image) ([]Rendition, error) {
+	renditions := make([]Rendition, 0, len(Variants)*len(Formats))
+	for _, variant := range Variants {
+		resized := resize(src, variant.Width)
+		for _, format := range Formats {
+			var buf bytes.Buffer
+			if err := encode(&buf, resized, format); err != nil {
+				return nil, err
+			}
+			renditions = append(renditions, Rendition{
+				Key:         VariantKey(key, variant.Name, format),
+				ContentType: ContentType(format),
+				Data:        buf.Bytes(),
+			})
+		}
+	}
+	return renditions, nil
+}
+
+func resize(src image.Image, width int) image.Image {
+	bounds := src.Bounds()
+	if bounds.Dx() <= width {
+		width = bounds.Dx()
+	}
+	height := bounds.Dy() * width / bounds.Dx()
+	if height < 1 {
+		height = 1
+	}
+
+	dst := image.NewNRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, bounds, draw.Src, nil)
+	return dst
+}
+
+func encode(w io.Writer, img image.Image, format string) error {
+	if format == FormatWebP {
+		return nativewebp.Encode(w, img, nil)
+	}
+
+	// JPEG has no alpha channel, transparent areas become white.
+	flat := image.NewRGBA(img.Bounds())
+	draw.Draw(flat, flat.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(flat, flat.Bounds(), img, img.Bounds().Min, draw.Over)
+	return jpeg.Encode(w, flat, &jpeg.Options{Quality: jpegQuality})
+}
@@ -0,0 +1,91 @@
+package imaging
+
+import "testing"
+
+func TestVariantKey(t *testing.T) {
+	tests := []struct {
+		key     string
+		variant string
+		format  string
+		want    string
+	}{
+		{"poster.png", "card", FormatWebP, "poster_card.webp"},
+		{"poster.png", "thumb", FormatJPEG, "poster_thumb.jpg"},
+		{"poster", "full", FormatJPEG, "poster_full.jpg"},
+		{"poster.final.jpeg", "full", FormatWebP, "poster.final_full.webp"},
+	}
+
+	for _, tt := range tests {
+		if got := VariantKey(tt.key, tt.variant, tt.format); got != tt.want {
+			t.Errorf("VariantKey(%q, %q, %q) = %q, want %q", tt.key, tt.variant, tt.format, got, tt.want)
+		}
+	}
+}
+
+func TestParseVariantKey(t *testing.T) {
+	tests := []struct {
+		key    string
+		want   string
+		wantOk bool
+	}{
+		{"poster_card.webp", "poster", true},
+		{"poster_thumb.jpg", "poster", true},
+		{"my_poster_full.jpg", "my_poster", true},
+		{"poster.png", "", false},
+		{"poster_card.png", "", false},
+		{"poster_large.jpg", "", false},
+		{"_card.webp", "", false},
+		{"poster_card.webp.png", "", false},
+	}
+
+	for _, tt := range tests {
+		got, ok := ParseVariantKey(tt.key)
+		if got != tt.want || ok != tt.wantOk {
+			t.Errorf("ParseVariantKey(%q) = %q, %v, want %q, %v", tt.key, got, ok, tt.want, tt.wantOk)
+		}
+	}
+}
+
+// TestParseVariantKeyRoundTrip checks that every generated key is recognized.
+func TestParseVariantKeyRoundTrip(t *testing.T) {
+	for _, variant := range Variants {
+		for _, format := range Formats {
+			key := VariantKey("3f1c9a7e.png", variant.Name, format)
+			if original, ok := ParseVariantKey(key); !ok || original != "3f1c9a7e" {
+				t.Errorf("ParseVariantKey(%q) = %q, %v, want %q, true", key, original, ok, "3f1c9a7e")
+			}
+		}
+	}
+}
+
+func TestContentKey(t *testing.T) {
+	want := "293b9207228b7854bc3ccb2959ebea1583e066d41983124a5b381d6fdf6575f8.png"
+	if key := ContentKey([]byte("poster"), ".png"); key != want {
+		t.Errorf("ContentKey = %q, want %q", key, want)
+	}
+}
+
+func TestParseContentKey(t *testing.T) {
+	hash := "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+
+	tests := []struct {
+		key    string
+		want   string
+		wantOk bool
+	}{
+		{hash + ".jpg", hash, true},
+		{hash + ".webp", hash, true},
+		{hash, "", false},
+		{hash + "_card.webp", "", false},
+		{hash[1:] + ".jpg", "", false},
+		{"9F86D081884C7D659A2FEAA0C55AD015A3BF4F1B2B0B822CD15D6C15B0F00A08.jpg", "", false},
+		{"3f1c9a7e-5b2d-4c8e-9a1f-0e6d7c8b9a0f.jpg", "", false},
+	}
+
+	for _, tt := range tests {
+		got, ok := ParseContentKey(tt.key)
+		if got != tt.want || ok != tt.wantOk {
+			t.Errorf("ParseContentKey(%q) = %q, %v, want %q, %v", tt.key, got, ok, tt.want, tt.wantOk)
+		}
+	}
+}
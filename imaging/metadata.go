@@ -0,0 +1,207 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/draw"
+)
+
+// jpegOrientation returns the EXIF orientation of a JPEG image, 1 when it has
+// none.
+func jpegOrientation(data []byte) int {
+	orientation := 1
+	walkJpegSegments(data, func(marker byte, segment []byte) {
+		if marker != 0xE1 || !bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return
+		}
+		if o := tiffOrientation(segment[6:]); o != 0 {
+			orientation = o
+		}
+	})
+	return orientation
+}
+
+func tiffOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 0
+	}
+
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 0
+	}
+
+	offset := int(order.Uint32(tiff[4:8]))
+	if offset+2 > len(tiff) {
+		return 0
+	}
+	count := int(order.Uint16(tiff[offset:]))
+	for i := 0; i < count; i++ {
+		entry := offset + 2 + i*12
+		if entry+12 > len(tiff) {
+			return 0
+		}
+		if order.Uint16(tiff[entry:]) == 0x0112 {
+			orientation := int(order.Uint16(tiff[entry+8:]))
+			if orientation < 1 || orientation > 8 {
+				return 0
+			}
+			return orientation
+		}
+	}
+	return 0
+}
+
+// walkJpegSegments calls fn for every marker segment before the image data.
+// It returns the offset of the start of scan marker, or -1 when the image is
+// malformed.
+func walkJpegSegments(data []byte, fn func(marker byte, segment []byte)) int {
+	i := 2
+	for i+4 <= len(data) {
+		if data[i] != 0xFF {
+			return -1
+		}
+		marker := data[i+1]
+		if marker == 0xFF {
+			// Fill byte.
+			i++
+			continue
+		}
+		if marker == 0xDA {
+			return i
+		}
+
+		length := int(binary.BigEndian.Uint16(data[i+2:]))
+		if length < 2 || i+2+length > len(data) {
+			return -1
+		}
+		fn(marker, data[i+4:i+2+length])
+		i += 2 + length
+	}
+	return -1
+}
+
+// stripJpegMetadata drops the EXIF, XMP, IPTC and comment segments. JFIF, ICC
+// profile and Adobe segments are kept, since they affect how colors decode.
+func stripJpegMetadata(data []byte) []byte {
+	out := make([]byte, 0, len(data))
+	out = append(out, data[:2]...)
+
+	sos := walkJpegSegments(data, func(marker byte, segment []byte) {
+		keep := marker == 0xE0 || marker == 0xE2 || marker == 0xEE || marker < 0xE0 && marker != 0xFE
+		if !keep {
+			return
+		}
+		out = append(out, 0xFF, marker)
+		out = binary.BigEndian.AppendUint16(out, uint16(len(segment)+2))
+		out = append(out, segment...)
+	})
+	if sos < 0 {
+		return data
+	}
+
+	return append(out, data[sos:]...)
+}
+
+var pngMetadataChunks = map[string]bool{"eXIf": true, "tEXt": true, "zTXt": true, "iTXt": true, "tIME": true}
+
+// stripPngMetadata drops the EXIF, text and time chunks.
+func stripPngMetadata(data []byte) []byte {
+	out := make([]byte, 0, len(data))
+	out = append(out, data[:8]...)
+
+	i := 8
+	for i+12 <= len(data) {
+		length := int(binary.BigEndian.Uint32(data[i:]))
+		end := i + 12 + length
+		if end > len(data) {
+			return data
+		}
+		if !pngMetadataChunks[string(data[i+4:i+8])] {
+			out = append(out, data[i:end]...)
+		}
+		i = end
+	}
+	return out
+}
+
+// stripWebpMetadata drops the EXIF and XMP chunks and clears their flags in
+// the extended format header.
+func stripWebpMetadata(data []byte) []byte {
+	out := make([]byte, 0, len(data))
+	out = append(out, data[:12]...)
+
+	i := 12
+	for i+8 <= len(data) {
+		fourcc := string(data[i : i+4])
+		size := int(binary.LittleEndian.Uint32(data[i+4:]))
+		end := i + 8 + size + size%2
+		if end > len(data) {
+			return data
+		}
+
+		switch fourcc {
+		case "EXIF", "XMP ":
+		case "VP8X":
+			start := len(out)
+			out = append(out, data[i:end]...)
+			if size > 0 {
+				out[start+8] &^= 0x08 | 0x04
+			}
+		default:
+			out = append(out, data[i:end]...)
+		}
+		i = end
+	}
+
+	binary.LittleEndian.PutUint32(out[4:], uint32(len(out)-8))
+	return out
+}
+
+// orient turns an image decoded from a JPEG with the given EXIF orientation
+// upright.
+func orient(src image.Image, orientation int) image.Image {
+	if orientation <= 1 || orientation > 8 {
+		return src
+	}
+
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if orientation >= 5 {
+		w, h = h, w
+	}
+
+	normalized := image.NewNRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(normalized, normalized.Bounds(), src, b.Min, draw.Src)
+
+	dst := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < b.Dy(); y++ {
+		for x := 0; x < b.Dx(); x++ {
+			var dx, dy int
+			switch orientation {
+			case 2:
+				dx, dy = b.Dx()-1-x, y
+			case 3:
+				dx, dy = b.Dx()-1-x, b.Dy()-1-y
+			case 4:
+				dx, dy = x, b.Dy()-1-y
+			case 5:
+				dx, dy = y, x
+			case 6:
+				dx, dy = b.Dy()-1-y, x
+			case 7:
+				dx, dy = b.Dy()-1-y, b.Dx()-1-x
+			case 8:
+				dx, dy = y, b.Dx()-1-x
+			}
+			copy(dst.Pix[dst.PixOffset(dx, dy):][:4], normalized.Pix[normalized.PixOffset(x, y):][:4])
+		}
+	}
+	return dst
+}
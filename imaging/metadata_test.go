@@ -0,0 +1,235 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"slices"
+	"testing"
+)
+
+// exifSegment builds the payload of an APP1 segment with one orientation
+// entry, in the byte order named by order ("II" or "MM").
+func exifSegment(order string, orientation uint16) []byte {
+	var byteOrder binary.AppendByteOrder = binary.LittleEndian
+	if order == "MM" {
+		byteOrder = binary.BigEndian
+	}
+
+	tiff := []byte(order)
+	tiff = byteOrder.AppendUint16(tiff, 42)
+	tiff = byteOrder.AppendUint32(tiff, 8)
+	tiff = byteOrder.AppendUint16(tiff, 1)
+	tiff = byteOrder.AppendUint16(tiff, 0x0112)
+	tiff = byteOrder.AppendUint16(tiff, 3)
+	tiff = byteOrder.AppendUint32(tiff, 1)
+	tiff = byteOrder.AppendUint16(tiff, orientation)
+	tiff = append(tiff, 0, 0)
+	return append([]byte("Exif\x00\x00"), tiff...)
+}
+
+func jpegSegment(marker byte, payload []byte) []byte {
+	segment := []byte{0xFF, marker}
+	segment = binary.BigEndian.AppendUint16(segment, uint16(len(payload)+2))
+	return append(segment, payload...)
+}
+
+// withJpegSegments inserts segments right after the start of image marker.
+func withJpegSegments(data []byte, segments ...[]byte) []byte {
+	out := slices.Clone(data[:2])
+	for _, segment := range segments {
+		out = append(out, segment...)
+	}
+	return append(out, data[2:]...)
+}
+
+func pngChunk(kind string, payload []byte) []byte {
+	chunk := binary.BigEndian.AppendUint32(nil, uint32(len(payload)))
+	chunk = append(chunk, kind...)
+	chunk = append(chunk, payload...)
+	return binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(chunk[4:]))
+}
+
+func riffChunk(fourcc string, payload []byte) []byte {
+	chunk := []byte(fourcc)
+	chunk = binary.LittleEndian.AppendUint32(chunk, uint32(len(payload)))
+	chunk = append(chunk, payload...)
+	if len(payload)%2 == 1 {
+		chunk = append(chunk, 0)
+	}
+	return chunk
+}
+
+func webpFile(chunks ...[]byte) []byte {
+	body := []byte("WEBP")
+	for _, chunk := range chunks {
+		body = append(body, chunk...)
+	}
+	data := []byte("RIFF")
+	data = binary.LittleEndian.AppendUint32(data, uint32(len(body)))
+	return append(data, body...)
+}
+
+func TestJpegOrientation(t *testing.T) {
+	soi := []byte{0xFF, 0xD8}
+	sos := []byte{0xFF, 0xDA, 0x00, 0x02}
+
+	tests := []struct {
+		name string
+		data []byte
+		want int
+	}{
+		{"no exif", withJpegSegments(append(soi, sos...), jpegSegment(0xE0, []byte("JFIF\x00"))), 1},
+		{"little endian", withJpegSegments(append(soi, sos...), jpegSegment(0xE1, exifSegment("II", 6))), 6},
+		{"big endian", withJpegSegments(append(soi, sos...), jpegSegment(0xE1, exifSegment("MM", 8))), 8},
+		{"out of range", withJpegSegments(append(soi, sos...), jpegSegment(0xE1, exifSegment("II", 9))), 1},
+		{"xmp instead of exif", withJpegSegments(append(soi, sos...), jpegSegment(0xE1, []byte("http://ns.adobe.com/xap/1.0/\x00"))), 1},
+		{"truncated exif", withJpegSegments(append(soi, sos...), jpegSegment(0xE1, exifSegment("II", 6)[:12])), 1},
+		{"malformed", []byte{0xFF, 0xD8, 0x00, 0x01, 0x02, 0x03}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := jpegOrientation(tt.data); got != tt.want {
+				t.Errorf("jpegOrientation() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStripJpegMetadata(t *testing.T) {
+	jfif := jpegSegment(0xE0, []byte("JFIF\x00\x01\x01"))
+	exif := jpegSegment(0xE1, exifSegment("II", 1))
+	icc := jpegSegment(0xE2, []byte("ICC_PROFILE\x00"))
+	iptc := jpegSegment(0xED, []byte("Photoshop 3.0\x00"))
+	adobe := jpegSegment(0xEE, []byte("Adobe"))
+	comment := jpegSegment(0xFE, []byte("taken by me"))
+	quantization := jpegSegment(0xDB, []byte{0x00, 0x01, 0x02})
+	scan := []byte{0xFF, 0xDA, 0x00, 0x02, 0x12, 0x34, 0xFF, 0xD9}
+
+	data := withJpegSegments(append([]byte{0xFF, 0xD8}, scan...), jfif, exif, icc, iptc, adobe, comment, quantization)
+	want := withJpegSegments(append([]byte{0xFF, 0xD8}, scan...), jfif, icc, adobe, quantization)
+
+	if got := stripJpegMetadata(data); !bytes.Equal(got, want) {
+		t.Errorf("stripJpegMetadata() =\n% x\nwant\n% x", got, want)
+	}
+
+	malformed := []byte{0xFF, 0xD8, 0xFF, 0xE1, 0x00, 0x40, 0x01}
+	if got := stripJpegMetadata(malformed); !bytes.Equal(got, malformed) {
+		t.Errorf("stripJpegMetadata(malformed) = % x, want the input unchanged", got)
+	}
+}
+
+func TestStripPngMetadata(t *testing.T) {
+	signature := []byte("\x89PNG\r\n\x1a\n")
+	header := pngChunk("IHDR", make([]byte, 13))
+	text := pngChunk("tEXt", []byte("Author\x00me"))
+	compressed := pngChunk("zTXt", []byte("Comment\x00\x00x"))
+	international := pngChunk("iTXt", []byte("Title\x00\x00\x00\x00\x00poster"))
+	exif := pngChunk("eXIf", exifSegment("MM", 1)[6:])
+	modified := pngChunk("tIME", make([]byte, 7))
+	palette := pngChunk("PLTE", []byte{0, 0, 0})
+	data := pngChunk("IDAT", []byte{1, 2, 3})
+	end := pngChunk("IEND", nil)
+
+	input := slices.Concat(signature, header, text, exif, palette, compressed, modified, data, international, end)
+	want := slices.Concat(signature, header, palette, data, end)
+	if got := stripPngMetadata(input); !bytes.Equal(got, want) {
+		t.Errorf("stripPngMetadata() =\n% x\nwant\n% x", got, want)
+	}
+
+	truncated := slices.Concat(signature, header, pngChunk("IDAT", []byte{1, 2, 3})[:14])
+	if got := stripPngMetadata(truncated); !bytes.Equal(got, truncated) {
+		t.Errorf("stripPngMetadata(truncated) = % x, want the input unchanged", got)
+	}
+}
+
+func TestStripWebpMetadata(t *testing.T) {
+	// VP8X flags: ICC (0x20), EXIF (0x08) and XMP (0x04).
+	flags := func(f byte) []byte { return []byte{f, 0, 0, 0, 9, 0, 0, 9, 0, 0} }
+	icc := riffChunk("ICCP", []byte{1, 2, 3, 4})
+	exif := riffChunk("EXIF", exifSegment("II", 1)[6:])
+	xmp := riffChunk("XMP ", []byte("<x/>\x00"))
+	bitstream := riffChunk("VP8L", []byte{0x2F, 1, 2, 3, 4})
+
+	input := webpFile(riffChunk("VP8X", flags(0x20|0x08|0x04)), icc, bitstream, exif, xmp)
+	want := webpFile(riffChunk("VP8X", flags(0x20)), icc, bitstream)
+	if got := stripWebpMetadata(input); !bytes.Equal(got, want) {
+		t.Errorf("stripWebpMetadata() =\n% x\nwant\n% x", got, want)
+	}
+
+	simple := webpFile(bitstream)
+	if got := stripWebpMetadata(simple); !bytes.Equal(got, simple) {
+		t.Errorf("stripWebpMetadata(simple) = % x, want the input unchanged", got)
+	}
+
+	truncated := simple[:len(simple)-2]
+	if got := stripWebpMetadata(truncated); !bytes.Equal(got, truncated) {
+		t.Errorf("stripWebpMetadata(truncated) = % x, want the input unchanged", got)
+	}
+}
+
+// grid builds an image whose pixels have the given gray levels, row by row.
+func grid(rows [][]uint8) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, len(rows[0]), len(rows)))
+	for y, row := range rows {
+		for x, v := range row {
+			img.SetNRGBA(x, y, color.NRGBA{R: v, G: v, B: v, A: 255})
+		}
+	}
+	return img
+}
+
+func levels(img image.Image) [][]uint8 {
+	b := img.Bounds()
+	rows := make([][]uint8, b.Dy())
+	for y := range rows {
+		rows[y] = make([]uint8, b.Dx())
+		for x := range rows[y] {
+			rows[y][x] = color.GrayModel.Convert(img.At(b.Min.X+x, b.Min.Y+y)).(color.Gray).Y
+		}
+	}
+	return rows
+}
+
+func TestOrient(t *testing.T) {
+	// a b c
+	// d e f
+	const a, b, c, d, e, f = 10, 20, 30, 40, 50, 60
+	src := [][]uint8{{a, b, c}, {d, e, f}}
+
+	tests := []struct {
+		orientation int
+		want        [][]uint8
+	}{
+		{0, src},
+		{1, src},
+		{2, [][]uint8{{c, b, a}, {f, e, d}}},
+		{3, [][]uint8{{f, e, d}, {c, b, a}}},
+		{4, [][]uint8{{d, e, f}, {a, b, c}}},
+		{5, [][]uint8{{a, d}, {b, e}, {c, f}}},
+		{6, [][]uint8{{d, a}, {e, b}, {f, c}}},
+		{7, [][]uint8{{f, c}, {e, b}, {d, a}}},
+		{8, [][]uint8{{c, f}, {b, e}, {a, d}}},
+		{9, src},
+	}
+
+	for _, tt := range tests {
+		got := levels(orient(grid(src), tt.orientation))
+		if !slices.EqualFunc(got, tt.want, slices.Equal) {
+			t.Errorf("orient(%d) = %v, want %v", tt.orientation, got, tt.want)
+		}
+	}
+}
+
+func TestOrientKeepsOffsetImages(t *testing.T) {
+	src := grid([][]uint8{{10, 20}, {30, 40}}).SubImage(image.Rect(1, 0, 2, 2))
+
+	got := levels(orient(src, 6))
+	want := [][]uint8{{40, 20}}
+	if !slices.EqualFunc(got, want, slices.Equal) {
+		t.Errorf("orient(sub image, 6) = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,107 @@
+package imaging
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/jpeg"
+
+	"github.com/gabriel-vasile/mimetype"
+)
+
+const (
+	MaxPosterBytes  = 10 << 20
+	MinPosterWidth  = 200
+	MinPosterHeight = 200
+	MaxPosterWidth  = 8000
+	MaxPosterHeight = 8000
+)
+
+// Codes of poster validation errors, returned to clients as the problem code.
+const (
+	CodePosterMissing    = "poster_missing"
+	CodePosterTooLarge   = "poster_too_large"
+	CodePosterType       = "poster_unsupported_type"
+	CodePosterCorrupt    = "poster_corrupt"
+	CodePosterDimensions = "poster_invalid_dimensions"
+)
+
+// ValidationError is returned for uploads that aren't acceptable posters.
+type ValidationError struct {
+	Code    string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return e.Message
+}
+
+var ErrPosterMissing = &ValidationError{Code: CodePosterMissing, Message: "poster is required"}
+
+var posterFormats = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/webp": ".webp",
+}
+
+// Poster is a validated upload, with metadata removed.
+type Poster struct {
+	Data        []byte
+	ContentType string
+	Extension   string
+	Image       image.Image
+}
+
+// PreparePoster checks the upload by its content rather than by the name or
+// type the client sent, and strips EXIF and other metadata. JPEGs taken
+// sideways are stored upright, since their orientation tag is removed too.
+func PreparePoster(data []byte) (*Poster, error) {
+	if len(data) > MaxPosterBytes {
+		return nil, &ValidationError{Code: CodePosterTooLarge, Message: fmt.Sprintf("poster must not be larger than %d MB", MaxPosterBytes>>20)}
+	}
+
+	contentType := mimetype.Detect(data).String()
+	extension, ok := posterFormats[contentType]
+	if !ok {
+		return nil, &ValidationError{Code: CodePosterType, Message: fmt.Sprintf("poster must be a JPEG, PNG or WebP image, got %s", contentType)}
+	}
+
+	// Check the dimensions before decoding, so huge images aren't decoded.
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, &ValidationError{Code: CodePosterCorrupt, Message: "poster could not be read as an image"}
+	}
+	if config.Width < MinPosterWidth || config.Height < MinPosterHeight || config.Width > MaxPosterWidth || config.Height > MaxPosterHeight {
+		return nil, &ValidationError{
+			Code: CodePosterDimensions,
+			Message: fmt.Sprintf(
+				"poster must be between %dx%d and %dx%d pixels, got %dx%d",
+				MinPosterWidth, MinPosterHeight, MaxPosterWidth, MaxPosterHeight, config.Width, config.Height),
+		}
+	}
+
+	img, err := Decode(data)
+	if err != nil {
+		return nil, &ValidationError{Code: CodePosterCorrupt, Message: "poster could not be read as an image"}
+	}
+
+	poster := &Poster{ContentType: contentType, Extension: extension, Image: img}
+	switch contentType {
+	case "image/jpeg":
+		if jpegOrientation(data) != 1 {
+			var buf bytes.Buffer
+			if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 92}); err != nil {
+				return nil, err
+			}
+			poster.Data = buf.Bytes()
+		} else {
+			poster.Data = stripJpegMetadata(data)
+		}
+	case "image/png":
+		poster.Data = stripPngMetadata(data)
+	case "image/webp":
+		poster.Data = stripWebpMetadata(data)
+	}
+
+	return poster, nil
+}
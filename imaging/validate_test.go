@@ -0,0 +1,146 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"slices"
+	"testing"
+
+	"github.com/HugoSmits86/nativewebp"
+)
+
+func testImage(width int, height int) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.SetNRGBA(x, y, color.NRGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+		}
+	}
+	return img
+}
+
+func encodePng(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func encodeJpeg(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, nil); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestPreparePoster(t *testing.T) {
+	pngData := encodePng(t, testImage(300, 450))
+	// Insert a text chunk after the IHDR chunk, which is 25 bytes long.
+	pngWithText := slices.Concat(pngData[:33], pngChunk("tEXt", []byte("Author\x00me")), pngData[33:])
+
+	jpegData := encodeJpeg(t, testImage(300, 450))
+	jpegWithExif := withJpegSegments(jpegData, jpegSegment(0xE1, exifSegment("II", 1)))
+	sideways := withJpegSegments(encodeJpeg(t, testImage(450, 300)), jpegSegment(0xE1, exifSegment("II", 6)))
+
+	var webpData bytes.Buffer
+	if err := nativewebp.Encode(&webpData, testImage(300, 450), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name          string
+		data          []byte
+		wantType      string
+		wantExtension string
+		wantData      []byte
+	}{
+		{"png", pngData, "image/png", ".png", pngData},
+		{"png with text", pngWithText, "image/png", ".png", pngData},
+		{"jpeg", jpegData, "image/jpeg", ".jpg", jpegData},
+		{"jpeg with exif", jpegWithExif, "image/jpeg", ".jpg", jpegData},
+		{"sideways jpeg", sideways, "image/jpeg", ".jpg", nil},
+		{"webp", webpData.Bytes(), "image/webp", ".webp", webpData.Bytes()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			poster, err := PreparePoster(tt.data)
+			if err != nil {
+				t.Fatalf("PreparePoster returned error %v", err)
+			}
+			if poster.ContentType != tt.wantType || poster.Extension != tt.wantExtension {
+				t.Errorf("PreparePoster = %s %s, want %s %s", poster.ContentType, poster.Extension, tt.wantType, tt.wantExtension)
+			}
+			if size := poster.Image.Bounds().Size(); size != image.Pt(300, 450) {
+				t.Errorf("image size = %v, want 300x450", size)
+			}
+			if tt.wantData != nil && !bytes.Equal(poster.Data, tt.wantData) {
+				t.Errorf("PreparePoster data differs from the image without metadata")
+			}
+			if jpegOrientation(poster.Data) != 1 {
+				t.Errorf("PreparePoster kept the orientation tag")
+			}
+		})
+	}
+}
+
+func TestPreparePosterRejectsInvalidUploads(t *testing.T) {
+	var gifData bytes.Buffer
+	if err := gif.Encode(&gifData, testImage(300, 450), nil); err != nil {
+		t.Fatal(err)
+	}
+	pngData := encodePng(t, testImage(300, 450))
+
+	tests := []struct {
+		name     string
+		data     []byte
+		wantCode string
+	}{
+		{"too many bytes", make([]byte, MaxPosterBytes+1), CodePosterTooLarge},
+		{"text", []byte("definitely not an image"), CodePosterType},
+		{"gif", gifData.Bytes(), CodePosterType},
+		{"corrupt png", pngData[:40], CodePosterCorrupt},
+		{"too narrow", encodePng(t, testImage(MinPosterWidth-1, 450)), CodePosterDimensions},
+		{"too short", encodePng(t, testImage(300, MinPosterHeight-1)), CodePosterDimensions},
+		{"too wide", encodePng(t, testImage(MaxPosterWidth+1, MinPosterHeight)), CodePosterDimensions},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := PreparePoster(tt.data)
+			var validationErr *ValidationError
+			if !errors.As(err, &validationErr) || validationErr.Code != tt.wantCode {
+				t.Errorf("PreparePoster error = %v, want code %s", err, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestDecode(t *testing.T) {
+	sideways := withJpegSegments(encodeJpeg(t, testImage(30, 20)), jpegSegment(0xE1, exifSegment("MM", 8)))
+	img, err := Decode(sideways)
+	if err != nil {
+		t.Fatalf("Decode returned error %v", err)
+	}
+	if size := img.Bounds().Size(); size != image.Pt(20, 30) {
+		t.Errorf("Decode size = %v, want 20x30", size)
+	}
+
+	if _, err := Decode([]byte("not an image")); !errors.Is(err, ErrUnsupportedImage) {
+		t.Errorf("Decode(text) error = %v, want %v", err, ErrUnsupportedImage)
+	}
+
+	tall := encodePng(t, image.NewGray(image.Rect(0, 0, 1, MaxPosterHeight+1)))
+	if _, err := Decode(tall); !errors.Is(err, ErrImageTooLarge) {
+		t.Errorf("Decode(1x%d) error = %v, want %v", MaxPosterHeight+1, err, ErrImageTooLarge)
+	}
+}
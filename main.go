@@ -2,18 +2,27 @@ package main
 
 import (
 	"context"
+	"flag"
+	"fmt"
+	"goozinshe/cleanup"
 	"goozinshe/config"
 	"goozinshe/docs"
 	"goozinshe/handlers"
 	"goozinshe/logger"
 	"goozinshe/middlewares"
+	"goozinshe/migrations"
+	"goozinshe/models"
 	"goozinshe/repositories"
+	"goozinshe/storage"
+	"os"
 	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/spf13/viper"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 
 	ginzap "github.com/gin-contrib/zap"
 	swaggerfiles "github.com/swaggo/files"
@@ -43,19 +52,35 @@ import (
 // @externalDocs.description  OpenAPI
 // @externalDocs.url          https://swagger.io/resources/open-api/
 func main() {
+    migrateOnStart := flag.Bool("migrate", false, "apply pending database migrations before starting the server")
+    flag.Usage = func() {
+        fmt.Fprintln(flag.CommandLine.Output(), commandsUsage)
+    }
+    flag.Parse()
+
 	r := gin.New()
 
     logger := logger.GetLogger()
     r.Use(
-        ginzap.Ginzap(logger, time.RFC3339, true),
-        ginzap.RecoveryWithZap(logger, true),
+        middlewares.RequestIdMiddleware(),
+        ginzap.GinzapWithConfig(logger, &ginzap.Config{
+            TimeFormat: time.RFC3339,
+            UTC:        true,
+            Context: func(c *gin.Context) []zapcore.Field {
+                return []zapcore.Field{zap.String("request_id", c.GetString("requestId"))}
+            },
+        }),
+        middlewares.ErrorMiddleware(),
+        ginzap.CustomRecoveryWithZap(logger, true, middlewares.Recover),
     )
-    
+    r.NoRoute(middlewares.NoRoute)
+
 
     corsConfig := cors.Config{
         AllowAllOrigins:    true,
         AllowHeaders:       []string{"*"},
         AllowMethods:       []string{"*"},
+        ExposeHeaders:      []string{"X-Total-Count", "Link", "ETag", "Content-Language", middlewares.RequestIdHeader},
     }
 
     r.Use(cors.New(corsConfig))
@@ -71,54 +96,168 @@ func main() {
         panic(err)
     }
 
+    if flag.NArg() > 0 {
+        if err := runCommand(conn, flag.Args()); err != nil {
+            fmt.Fprintln(os.Stderr, err)
+            os.Exit(1)
+        }
+        return
+    }
+
+    if *migrateOnStart {
+        migrator, err := migrations.NewMigrator(conn)
+        if err != nil {
+            panic(err)
+        }
+        if _, err := migrator.Up(context.Background()); err != nil {
+            panic(err)
+        }
+    }
+
     moviesRepository := repositories.NewMoviesRepository(conn)
     genresRepository := repositories.NewGenresRepository(conn)
     watchlistRepository := repositories.NewWatchlistRepository(conn)
     usersRepository := repositories.NewUsersRepository(conn)
+    historyRepository := repositories.NewHistoryRepository(conn)
+    searchRepository := repositories.NewSearchRepository(conn)
+    imagesRepository := repositories.NewImagesRepository(conn)
+    sessionsRepository := repositories.NewSessionsRepository(conn)
+    revisionsRepository := repositories.NewRevisionsRepository(conn)
+    trashRepository := repositories.NewTrashRepository(conn)
+    peopleRepository := repositories.NewPeopleRepository(conn)
+    seriesRepository := repositories.NewSeriesRepository(conn)
+    translationsRepository := repositories.NewTranslationsRepository(conn)
+
+    posterStorage, err := storage.New(config.Config)
+    if err != nil {
+        panic(err)
+    }
+
+    if config.Config.ImageGcInterval > 0 {
+        sweeper := cleanup.NewImageSweeper(imagesRepository, posterStorage, config.Config.ImageGcGracePeriod)
+        go sweeper.Run(context.Background(), config.Config.ImageGcInterval)
+    }
+
+    if config.Config.TrashPurgeInterval > 0 {
+        purger := cleanup.NewTrashPurger(trashRepository, config.Config.TrashRetention())
+        go purger.Run(context.Background(), config.Config.TrashPurgeInterval)
+    }
 
-    moviesHandler := handlers.NewMoviesHandler(genresRepository, moviesRepository)
+    moviesHandler := handlers.NewMoviesHandler(genresRepository, moviesRepository, peopleRepository, imagesRepository, posterStorage)
     genresHandler := handlers.NewGenresHandler(genresRepository)
+    peopleHandler := handlers.NewPeopleHandler(peopleRepository)
+    seriesHandler := handlers.NewSeriesHandler(seriesRepository)
     watchlistHandler := handlers.NewWatchlistHandler(watchlistRepository)
-    usersHandler := handlers.NewUsersHandler(usersRepository)
-    authHandler := handlers.NewAuthHandlers(usersRepository)
+    usersHandler := handlers.NewUsersHandler(usersRepository, sessionsRepository)
+    authHandler := handlers.NewAuthHandlers(usersRepository, sessionsRepository)
+    historyHandler := handlers.NewHistoryHandler(historyRepository)
+    searchHandler := handlers.NewSearchHandler(searchRepository)
+    revisionsHandler := handlers.NewRevisionsHandler(revisionsRepository, moviesRepository, genresRepository)
+    translationsHandler := handlers.NewTranslationsHandler(translationsRepository, moviesRepository, genresRepository)
+    trashHandler := handlers.NewTrashHandler(trashRepository, config.Config.TrashRetention())
 
-    imageHandler := handlers.NewImageHandlers()
+    imageHandler := handlers.NewImageHandlers(posterStorage)
+
+    r.Use(middlewares.LocaleMiddleware(config.Config.LocaleFallbacks()))
 
     authorized := r.Group("")
-    authorized.Use(middlewares.AuthMiddleware)
+    authorized.Use(middlewares.AuthMiddleware(sessionsRepository))
+
+    editors := authorized.Group("")
+    editors.Use(middlewares.RequireRoles(models.RoleAdmin, models.RoleEditor))
+
+    admins := authorized.Group("")
+    admins.Use(middlewares.RequireRoles(models.RoleAdmin))
+
+    selfOrAdmins := authorized.Group("")
+    selfOrAdmins.Use(middlewares.RequireSelfOrRoles("id", models.RoleAdmin))
 
     authorized.GET("/movies", moviesHandler.FindAll)     
     authorized.GET("/movies/:id", moviesHandler.FindById)
-    authorized.POST("/movies", moviesHandler.Create)
-    authorized.PUT("/movies/:id", moviesHandler.Update)
-    authorized.DELETE("/movies/:id", moviesHandler.Delete)
-    authorized.PATCH("/movies/:movieId/rate", moviesHandler.SetRating)
-    authorized.PATCH("/movies/:movieId/setWatched", moviesHandler.SetWatched)
+    editors.POST("/movies", moviesHandler.Create)
+    editors.PUT("/movies/:id", moviesHandler.Update)
+    editors.PATCH("/movies/:id", moviesHandler.Patch)
+    editors.PUT("/movies/:id/poster", moviesHandler.UpdatePoster)
+    editors.PUT("/movies/:id/credits", moviesHandler.SetCredits)
+    editors.GET("/movies/:id/revisions", revisionsHandler.FindMovieRevisions)
+    editors.POST("/movies/:id/revisions/:rev/restore", revisionsHandler.RestoreMovieRevision)
+    editors.GET("/movies/:id/translations", translationsHandler.FindMovieTranslations)
+    editors.PUT("/movies/:id/translations/:locale", translationsHandler.SetMovieTranslation)
+    admins.DELETE("/movies/:id/translations/:locale", translationsHandler.DeleteMovieTranslation)
+    admins.DELETE("/movies/:id", moviesHandler.Delete)
+    admins.POST("/movies/:id/restore", trashHandler.RestoreMovie)
+    authorized.PATCH("/movies/:id/rate", moviesHandler.SetRating)
+    authorized.DELETE("/movies/:id/rate", moviesHandler.DeleteRating)
+    authorized.PATCH("/movies/:id/setWatched", moviesHandler.SetWatched)
+    authorized.GET("/movies/:id/seasons", seriesHandler.FindSeasons)
+    authorized.GET("/movies/:id/seasons/:season", seriesHandler.FindSeason)
+    authorized.GET("/movies/:id/nextEpisode", seriesHandler.NextEpisode)
+    authorized.PATCH("/movies/:id/seasons/:season/setWatched", seriesHandler.SetSeasonWatched)
+    authorized.PATCH("/movies/:id/seasons/:season/episodes/:episode/setWatched", seriesHandler.SetEpisodeWatched)
+    editors.POST("/movies/:id/seasons", seriesHandler.CreateSeason)
+    editors.PUT("/movies/:id/seasons/:season", seriesHandler.UpdateSeason)
+    editors.POST("/movies/:id/seasons/:season/episodes", seriesHandler.CreateEpisode)
+    editors.PUT("/movies/:id/seasons/:season/episodes/:episode", seriesHandler.UpdateEpisode)
+    admins.DELETE("/movies/:id/seasons/:season", seriesHandler.DeleteSeason)
+    admins.DELETE("/movies/:id/seasons/:season/episodes/:episode", seriesHandler.DeleteEpisode)
 
     authorized.GET("/genres", genresHandler.FindAll)     
     authorized.GET("/genres/:id", genresHandler.FindById)
-    authorized.POST("/genres", genresHandler.Create)
-    authorized.PUT("/genres/:id", genresHandler.Update)
-    authorized.DELETE("/genres/:id", genresHandler.Delete)
+    editors.POST("/genres", genresHandler.Create)
+    editors.PUT("/genres/:id", genresHandler.Update)
+    admins.DELETE("/genres/:id", genresHandler.Delete)
+    admins.POST("/genres/:id/restore", trashHandler.RestoreGenre)
+    editors.GET("/genres/:id/revisions", revisionsHandler.FindGenreRevisions)
+    editors.POST("/genres/:id/revisions/:rev/restore", revisionsHandler.RestoreGenreRevision)
+    editors.GET("/genres/:id/translations", translationsHandler.FindGenreTranslations)
+    editors.PUT("/genres/:id/translations/:locale", translationsHandler.SetGenreTranslation)
+    admins.DELETE("/genres/:id/translations/:locale", translationsHandler.DeleteGenreTranslation)
 
-    
+    authorized.GET("/people", peopleHandler.FindAll)
+    authorized.GET("/people/:id", peopleHandler.FindById)
+    authorized.GET("/people/:id/filmography", peopleHandler.Filmography)
+    editors.POST("/people", peopleHandler.Create)
+    editors.PUT("/people/:id", peopleHandler.Update)
+    admins.DELETE("/people/:id", peopleHandler.Delete)
 
     authorized.GET("/watchlist", watchlistHandler.FindAll)
     authorized.POST("/watchlist/:movieId", watchlistHandler.AddToWatchlist)
     authorized.DELETE("/watchlist/:movieId", watchlistHandler.Delete)
 
-    authorized.GET("/users", usersHandler.FindAll)
-    authorized.GET("/users/:id", usersHandler.FindById)
-    authorized.POST("/users", usersHandler.Create)
-    authorized.PUT("/users/:id", usersHandler.Update)
-    authorized.PATCH("/users/:id/changePassword", usersHandler.ChangePasswordHash)
-    authorized.DELETE("/users/:id", usersHandler.Delete)
+    authorized.GET("/watchlists", watchlistHandler.FindLists)
+    authorized.POST("/watchlists", watchlistHandler.CreateList)
+    authorized.GET("/watchlists/:listId", watchlistHandler.FindListById)
+    authorized.PUT("/watchlists/:listId", watchlistHandler.UpdateList)
+    authorized.DELETE("/watchlists/:listId", watchlistHandler.DeleteList)
+    authorized.PUT("/watchlists/:listId/items", watchlistHandler.ReorderItems)
+    authorized.POST("/watchlists/:listId/items/:movieId", watchlistHandler.AddItem)
+    authorized.PATCH("/watchlists/:listId/items/:movieId", watchlistHandler.UpdateItem)
+    authorized.DELETE("/watchlists/:listId/items/:movieId", watchlistHandler.RemoveItem)
+
+    authorized.GET("/me/history", historyHandler.FindAll)
+
+    authorized.GET("/search", searchHandler.Search)
+    authorized.GET("/search/suggest", searchHandler.Suggest)
+
+    admins.GET("/users", usersHandler.FindAll)
+    selfOrAdmins.GET("/users/:id", usersHandler.FindById)
+    admins.POST("/users", usersHandler.Create)
+    selfOrAdmins.PUT("/users/:id", usersHandler.Update)
+    selfOrAdmins.PATCH("/users/:id/changePassword", usersHandler.ChangePasswordHash)
+    admins.PATCH("/users/:id/role", usersHandler.ChangeRole)
+    admins.DELETE("/users/:id", usersHandler.Delete)
+    admins.POST("/users/:id/restore", trashHandler.RestoreUser)
+
+    admins.GET("/trash", trashHandler.FindAll)
 
     authorized.POST("/auth/signOut", authHandler.SignOut)
     authorized.GET("/auth/userInfo", authHandler.GetUserInfo)
 
     unauthorized := r.Group("")
     unauthorized.POST("/auth/signIn", authHandler.SignIn)
+    unauthorized.POST("/auth/refresh", authHandler.Refresh)
+
+    unauthorized.GET("/shared/watchlists/:token", watchlistHandler.FindShared)
 
     unauthorized.GET("/images/:imageId", imageHandler.HandleGetImageById)
 
@@ -147,9 +286,28 @@ func loadConfig() error {
     }
     
     if err := viper.BindEnv("JWT_EXPIRE_DURATION"); err != nil {
-    viper.SetDefault("JWT_EXPIRE_DURATION", "24h")
+    viper.SetDefault("JWT_EXPIRE_DURATION", "15m")
     }
 
+    viper.SetDefault("REFRESH_TOKEN_EXPIRE_DURATION", "720h")
+
+    viper.SetDefault("STORAGE_DRIVER", "local")
+    viper.SetDefault("STORAGE_LOCAL_DIR", "images")
+    viper.SetDefault("S3_ENDPOINT", "")
+    viper.SetDefault("S3_REGION", "us-east-1")
+    viper.SetDefault("S3_BUCKET", "")
+    viper.SetDefault("S3_ACCESS_KEY", "")
+    viper.SetDefault("S3_SECRET_KEY", "")
+    viper.SetDefault("S3_USE_SSL", true)
+
+    viper.SetDefault("IMAGE_GC_INTERVAL", "1h")
+    viper.SetDefault("IMAGE_GC_GRACE_PERIOD", "24h")
+
+    viper.SetDefault("TRASH_RETENTION_DAYS", 30)
+    viper.SetDefault("TRASH_PURGE_INTERVAL", "1h")
+
+    viper.SetDefault("LOCALE_FALLBACK", "")
+
     err := viper.ReadInConfig()
     if err != nil {
         return err
@@ -3,41 +3,81 @@ package middlewares
 import (
 	"goozinshe/config"
 	"goozinshe/models"
-	"net/http"
+	"goozinshe/repositories"
 	"strconv"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 )
 
-func AuthMiddleware(c *gin.Context){
-	authHeader := c.GetHeader("Authorization")
-	if authHeader == "" {
-		c.JSON(http.StatusUnauthorized, models.NewApiError("authorization header required"))
-		c.Abort()
-		return
-	}
+var (
+	errAuthorizationRequired = models.NewUnauthorizedError("authorization_required", "Authorization header required")
+	errInvalidAuthorization  = models.NewUnauthorizedError("invalid_authorization", "Authorization header must be a Bearer token")
+	errInvalidToken          = models.NewUnauthorizedError("invalid_token", "Invalid token")
+	errTokenRevoked          = models.NewUnauthorizedError("token_revoked", "Token has been revoked")
+)
 
-	tokenString := strings.Split(authHeader, "Bearer ")[1]
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		return []byte(config.Config.JwtSecretKey), nil
-	})
+// AuthMiddleware accepts valid access tokens whose session is still active
+// and which were not revoked on sign out.
+func AuthMiddleware(sessionsRepo *repositories.SessionsRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		authHeader := c.GetHeader("Authorization")
+		if authHeader == "" {
+			abortWithError(c, errAuthorizationRequired)
+			return
+		}
 
-	if err != nil || !token.Valid {
-		c.JSON(http.StatusUnauthorized, models.NewApiError("invalid token"))
-		c.Abort()
-		return
-	}
+		tokenString, found := strings.CutPrefix(authHeader, "Bearer ")
+		if !found {
+			abortWithError(c, errInvalidAuthorization)
+			return
+		}
+
+		var claims models.AuthClaims
+		token, err := jwt.ParseWithClaims(tokenString, &claims, func(token *jwt.Token) (interface{}, error) {
+			return []byte(config.Config.JwtSecretKey), nil
+		}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+
+		if err != nil || !token.Valid {
+			abortWithError(c, errInvalidToken)
+			return
+		}
 
-	subject, err := token.Claims.GetSubject()
-	if err != nil {
-		c.JSON(http.StatusUnauthorized, models.NewApiError("error while getting subject"))
-		c.Abort()
-		return
+		subject, err := token.Claims.GetSubject()
+		if err != nil {
+			abortWithError(c, errInvalidToken)
+			return
+		}
+
+		if _, err := uuid.Parse(claims.SessionId); err != nil || claims.ID == "" || claims.ExpiresAt == nil {
+			abortWithError(c, errInvalidToken)
+			return
+		}
+
+		revoked, err := sessionsRepo.IsRevoked(c, claims.SessionId, claims.ID)
+		if err != nil {
+			abortWithError(c, err)
+			return
+		}
+		if revoked {
+			abortWithError(c, errTokenRevoked)
+			return
+		}
+
+		userId, _ := strconv.Atoi(subject)
+		c.Set("userId", userId)
+		c.Set("userRole", claims.Role)
+		c.Set("sessionId", claims.SessionId)
+		c.Set("tokenId", claims.ID)
+		c.Set("tokenExpiresAt", claims.ExpiresAt.Time)
+		c.Next()
 	}
+}
 
-	userId, _ := strconv.Atoi(subject)
-	c.Set("userId", userId)
-	c.Next()
+// abortWithError stops the request and leaves the response to ErrorMiddleware.
+func abortWithError(c *gin.Context, err error) {
+	c.Error(err)
+	c.Abort()
 }
@@ -0,0 +1,87 @@
+package middlewares
+
+import (
+	"errors"
+	"fmt"
+	"goozinshe/models"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ErrorMiddleware responds to requests whose handler recorded an error with
+// c.Error and didn't write a response. The last error is sent as an RFC 7807
+// problem. Errors other than the domain errors in models are internal: their
+// text is only logged, clients get a generic problem with the request id.
+func ErrorMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+
+		problem := problemFor(c.Errors.Last().Err)
+		problem.Instance = c.Request.URL.Path
+		problem.RequestId = c.GetString("requestId")
+
+		c.Header("Content-Type", models.ProblemContentType)
+		c.JSON(problem.Status, problem)
+	}
+}
+
+// Recover records a panic as an internal error, for ErrorMiddleware to
+// respond to. It is the recovery function of the recovery middleware, which
+// must run after ErrorMiddleware.
+func Recover(c *gin.Context, recovered any) {
+	c.Error(fmt.Errorf("panic: %v", recovered))
+	c.Abort()
+}
+
+// NoRoute responds to requests no route matches.
+func NoRoute(c *gin.Context) {
+	c.Error(models.NewNotFoundError("route_not_found", "No endpoint matches the request path"))
+}
+
+func problemFor(err error) models.Problem {
+	var (
+		validation         *models.ValidationError
+		unauthorized       *models.UnauthorizedError
+		forbidden          *models.ForbiddenError
+		notFound           *models.NotFoundError
+		conflict           *models.ConflictError
+		preconditionFailed *models.PreconditionFailedError
+		unsupportedType    *models.UnsupportedMediaTypeError
+	)
+
+	switch {
+	case errors.As(err, &validation):
+		problem := newProblem(http.StatusBadRequest, validation.Code, validation.Message)
+		problem.Errors = validation.Fields
+		return problem
+	case errors.As(err, &unauthorized):
+		return newProblem(http.StatusUnauthorized, unauthorized.Code, unauthorized.Message)
+	case errors.As(err, &forbidden):
+		return newProblem(http.StatusForbidden, forbidden.Code, forbidden.Message)
+	case errors.As(err, &notFound):
+		return newProblem(http.StatusNotFound, notFound.Code, notFound.Message)
+	case errors.As(err, &conflict):
+		return newProblem(http.StatusConflict, conflict.Code, conflict.Message)
+	case errors.As(err, &preconditionFailed):
+		return newProblem(http.StatusPreconditionFailed, preconditionFailed.Code, preconditionFailed.Message)
+	case errors.As(err, &unsupportedType):
+		return newProblem(http.StatusUnsupportedMediaType, unsupportedType.Code, unsupportedType.Message)
+	default:
+		return newProblem(http.StatusInternalServerError, models.CodeInternalError, "The server couldn't complete the request")
+	}
+}
+
+func newProblem(status int, code string, detail string) models.Problem {
+	return models.Problem{
+		Type:   models.ProblemTypePrefix + code,
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+		Code:   code,
+	}
+}
@@ -0,0 +1,91 @@
+package middlewares
+
+import (
+	"goozinshe/models"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+var errInvalidLang = models.NewInvalidFieldError("lang", "lang must be one of: "+strings.Join(models.Locales, ", "))
+
+// LocaleMiddleware picks the locale content is shown in from the lang query
+// parameter or else the Accept-Language header. The requested locale is
+// followed by the fallbacks, and stored as "locales". Translations are looked
+// up in that order, and the original text is shown when there is none.
+// Without a requested locale "locales" is empty, so the original text is
+// shown, which is what editors change.
+func LocaleMiddleware(fallbacks []string) gin.HandlerFunc {
+	supported := make([]string, 0, len(fallbacks))
+	for _, locale := range fallbacks {
+		if slices.Contains(models.Locales, locale) && !slices.Contains(supported, locale) {
+			supported = append(supported, locale)
+		}
+	}
+
+	return func(c *gin.Context) {
+		requested := strings.ToLower(c.Query("lang"))
+		if requested != "" && !slices.Contains(models.Locales, requested) {
+			abortWithError(c, errInvalidLang)
+			return
+		}
+		if requested == "" {
+			requested = acceptedLocale(c.GetHeader("Accept-Language"))
+		}
+
+		locales := make([]string, 0, len(supported)+1)
+		if requested != "" {
+			locales = append(locales, requested)
+			for _, locale := range supported {
+				if locale != requested {
+					locales = append(locales, locale)
+				}
+			}
+			c.Header("Content-Language", requested)
+		}
+
+		c.Set("locales", locales)
+		c.Header("Vary", "Accept-Language")
+		c.Next()
+	}
+}
+
+// acceptedLocale returns the supported locale the Accept-Language header
+// prefers most, or "" when it accepts none of them. Regions are ignored, so
+// ru-RU is ru.
+func acceptedLocale(header string) string {
+	type language struct {
+		locale  string
+		quality float64
+	}
+
+	languages := make([]language, 0)
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		locale, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(tag)), "-")
+		if !slices.Contains(models.Locales, locale) {
+			continue
+		}
+
+		quality := 1.0
+		if value, found := strings.CutPrefix(strings.TrimSpace(params), "q="); found {
+			q, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			quality = q
+		}
+		if quality > 0 {
+			languages = append(languages, language{locale: locale, quality: quality})
+		}
+	}
+
+	if len(languages) == 0 {
+		return ""
+	}
+	sort.SliceStable(languages, func(i, j int) bool { return languages[i].quality > languages[j].quality })
+	return languages[0].locale
+}
@@ -0,0 +1,144 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestAcceptedLocale(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		want   string
+	}{
+		{"no header", "", ""},
+		{"single locale", "ru", "ru"},
+		{"region is ignored", "ru-RU", "ru"},
+		{"case is ignored", "EN-us", "en"},
+		{"first of equal quality", "kk, ru", "kk"},
+		{"highest quality", "en;q=0.5, ru;q=0.8", "ru"},
+		{"quality defaults to 1", "en;q=0.9, kk", "kk"},
+		{"unsupported locales are skipped", "de-DE, fr;q=0.9, en;q=0.1", "en"},
+		{"only unsupported locales", "de, fr", ""},
+		{"wildcard is not a locale", "*", ""},
+		{"zero quality refuses a locale", "ru;q=0, en;q=0.1", "en"},
+		{"only refused locales", "kk;q=0", ""},
+		{"invalid quality is skipped", "ru;q=high, en;q=0.2", "en"},
+		{"spaces around parts", "  en ; q=0.3 ,  kk ; q=0.4 ", "kk"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := acceptedLocale(tt.header); got != tt.want {
+				t.Errorf("acceptedLocale(%q) = %q, want %q", tt.header, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLocaleMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name            string
+		fallbacks       []string
+		url             string
+		acceptLanguage  string
+		wantStatus      int
+		wantLocales     []string
+		wantContentLang string
+	}{
+		{
+			name:        "nothing requested shows the original",
+			fallbacks:   []string{"kk", "ru", "en"},
+			url:         "/movies",
+			wantStatus:  http.StatusOK,
+			wantLocales: []string{},
+		},
+		{
+			name:            "lang is followed by the other fallbacks",
+			fallbacks:       []string{"kk", "ru", "en"},
+			url:             "/movies?lang=ru",
+			wantStatus:      http.StatusOK,
+			wantLocales:     []string{"ru", "kk", "en"},
+			wantContentLang: "ru",
+		},
+		{
+			name:            "lang takes precedence over Accept-Language",
+			url:             "/movies?lang=EN",
+			acceptLanguage:  "kk",
+			wantStatus:      http.StatusOK,
+			wantLocales:     []string{"en"},
+			wantContentLang: "en",
+		},
+		{
+			name:            "Accept-Language without fallbacks",
+			url:             "/movies",
+			acceptLanguage:  "kk-KZ, ru;q=0.5",
+			wantStatus:      http.StatusOK,
+			wantLocales:     []string{"kk"},
+			wantContentLang: "kk",
+		},
+		{
+			name:            "unsupported and repeated fallbacks are dropped",
+			fallbacks:       []string{"de", "en", "en", "ru"},
+			url:             "/movies",
+			acceptLanguage:  "kk",
+			wantStatus:      http.StatusOK,
+			wantLocales:     []string{"kk", "en", "ru"},
+			wantContentLang: "kk",
+		},
+		{
+			name:           "unsupported Accept-Language shows the original",
+			fallbacks:      []string{"kk"},
+			url:            "/movies",
+			acceptLanguage: "de",
+			wantStatus:     http.StatusOK,
+			wantLocales:    []string{},
+		},
+		{
+			name:       "unsupported lang is rejected",
+			url:        "/movies?lang=de",
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var locales []string
+			r := gin.New()
+			r.Use(ErrorMiddleware(), LocaleMiddleware(tt.fallbacks))
+			r.GET("/movies", func(c *gin.Context) {
+				locales = c.GetStringSlice("locales")
+				c.Status(http.StatusOK)
+			})
+
+			request := httptest.NewRequest(http.MethodGet, tt.url, nil)
+			if tt.acceptLanguage != "" {
+				request.Header.Set("Accept-Language", tt.acceptLanguage)
+			}
+			recorder := httptest.NewRecorder()
+			r.ServeHTTP(recorder, request)
+
+			if recorder.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", recorder.Code, tt.wantStatus)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			if !slices.Equal(locales, tt.wantLocales) {
+				t.Errorf("locales = %v, want %v", locales, tt.wantLocales)
+			}
+			if got := recorder.Header().Get("Content-Language"); got != tt.wantContentLang {
+				t.Errorf("Content-Language = %q, want %q", got, tt.wantContentLang)
+			}
+			if got := recorder.Header().Get("Vary"); got != "Accept-Language" {
+				t.Errorf("Vary = %q, want Accept-Language", got)
+			}
+		})
+	}
+}
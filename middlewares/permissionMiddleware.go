@@ -0,0 +1,42 @@
+package middlewares
+
+import (
+	"goozinshe/models"
+	"slices"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+var errInsufficientPermissions = models.NewForbiddenError("insufficient_permissions", "Insufficient permissions")
+
+// RequireRoles only lets through users that have one of the given roles.
+// It must run after AuthMiddleware.
+func RequireRoles(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !slices.Contains(roles, c.GetString("userRole")) {
+			abortWithError(c, errInsufficientPermissions)
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireSelfOrRoles lets users through when the user id in the given path
+// parameter is their own, or when they have one of the given roles.
+// It must run after AuthMiddleware.
+func RequireSelfOrRoles(param string, roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.Atoi(c.Param(param))
+		if err == nil && id == c.GetInt("userId") {
+			c.Next()
+			return
+		}
+
+		if !slices.Contains(roles, c.GetString("userRole")) {
+			abortWithError(c, errInsufficientPermissions)
+			return
+		}
+		c.Next()
+	}
+}
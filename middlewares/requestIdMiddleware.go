@@ -0,0 +1,29 @@
+package middlewares
+
+import (
+	"regexp"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const RequestIdHeader = "X-Request-Id"
+
+// Request ids sent by clients or proxies are kept when they are short and
+// can't break the logs or headers they are written to.
+var requestIdPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// RequestIdMiddleware gives every request an id, which is sent back in the
+// X-Request-Id header and in error responses and is logged with the request.
+func RequestIdMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIdHeader)
+		if !requestIdPattern.MatchString(id) {
+			id = uuid.NewString()
+		}
+
+		c.Set("requestId", id)
+		c.Header(RequestIdHeader, id)
+		c.Next()
+	}
+}
@@ -0,0 +1,5 @@
+drop table if exists users;
+drop table if exists watchlist;
+drop table if exists movies_genres;
+drop table if exists genres;
+drop table if exists movies;
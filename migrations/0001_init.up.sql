@@ -1,35 +1,36 @@
-create table movies (
-    id serial primary key,
-    title text,
-    description text,
+create table if not exists movies
+(
+    id           serial primary key,
+    title        text,
+    description  text,
     release_year int,
-    director text,
-    rating int default 0,
-    is_watched bool default false,
-    trailer_url text,
-    poster_url text
+    director     text,
+    rating       int  default 0,
+    is_watched   bool default false,
+    trailer_url  text,
+    poster_url   text
 );
 
-create table genres
+create table if not exists genres
 (
     id    serial primary key,
     title text
 );
 
-create table movies_genres
+create table if not exists movies_genres
 (
     movie_id int references movies (id),
     genre_id int references genres (id),
     primary key (movie_id, genre_id)
 );
 
-create table watchlist
+create table if not exists watchlist
 (
     movie_id int primary key references movies (id),
     added_at timestamp not null
 );
 
-create table users
+create table if not exists users
 (
     id            serial primary key,
     name          text not null,
@@ -38,4 +39,5 @@ create table users
 );
 
 insert into users (name, email, password_hash)
-values ('admin', 'admin@admin.com', '$2y$10$iCCKNv39bVatC7HelfyfGOLWi9cNYP2zmbb59vIraMMXSnzP5Nczq');
\ No newline at end of file
+values ('admin', 'admin@admin.com', '$2y$10$iCCKNv39bVatC7HelfyfGOLWi9cNYP2zmbb59vIraMMXSnzP5Nczq')
+on conflict (email) do nothing;
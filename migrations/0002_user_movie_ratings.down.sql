@@ -0,0 +1,6 @@
+alter table movies add column rating int default 0;
+
+update movies m
+set rating = coalesce((select round(avg(r.rating)) from user_movie_ratings r where r.movie_id = m.id), 0);
+
+drop table user_movie_ratings;
@@ -0,0 +1,13 @@
+create table user_movie_ratings
+(
+    user_id  int references users (id) on delete cascade,
+    movie_id int references movies (id),
+    rating   int       not null check (rating between 1 and 5),
+    rated_at timestamp not null default now(),
+    primary key (user_id, movie_id)
+);
+
+create index user_movie_ratings_movie_id_idx on user_movie_ratings (movie_id);
+
+-- The old global rating can't be attributed to anyone, so it is dropped.
+alter table movies drop column rating;
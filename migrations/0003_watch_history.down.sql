@@ -0,0 +1,7 @@
+alter table movies add column is_watched bool default false;
+
+update movies m
+set is_watched = exists(select 1 from user_watched_movies w where w.movie_id = m.id);
+
+drop table watch_events;
+drop table user_watched_movies;
@@ -0,0 +1,27 @@
+create table user_watched_movies
+(
+    user_id    int references users (id) on delete cascade,
+    movie_id   int references movies (id),
+    watched_at timestamp not null default now(),
+    primary key (user_id, movie_id)
+);
+
+create table watch_events
+(
+    id         serial primary key,
+    user_id    int       not null references users (id) on delete cascade,
+    movie_id   int       not null references movies (id),
+    watched_at timestamp not null default now()
+);
+
+create index watch_events_user_id_watched_at_idx on watch_events (user_id, watched_at desc, id desc);
+
+-- The global flag used to apply to everyone, so every existing user keeps
+-- seeing those movies as watched.
+insert into user_watched_movies (user_id, movie_id)
+select u.id, m.id
+from users u
+cross join movies m
+where m.is_watched;
+
+alter table movies drop column is_watched;
@@ -0,0 +1,13 @@
+create table watchlist
+(
+    movie_id int primary key references movies (id),
+    added_at timestamp not null
+);
+
+insert into watchlist (movie_id, added_at)
+select movie_id, min(added_at)
+from watchlist_items
+group by movie_id;
+
+drop table watchlist_items;
+drop table watchlists;
@@ -0,0 +1,38 @@
+create table watchlists
+(
+    id          serial primary key,
+    user_id     int       not null references users (id) on delete cascade,
+    title       text      not null,
+    visibility  text      not null default 'private' check (visibility in ('private', 'link', 'public')),
+    share_token text unique,
+    is_default  bool      not null default false,
+    created_at  timestamp not null default now()
+);
+
+create index watchlists_user_id_idx on watchlists (user_id);
+create unique index watchlists_user_id_default_idx on watchlists (user_id) where is_default;
+
+create table watchlist_items
+(
+    watchlist_id int references watchlists (id) on delete cascade,
+    movie_id     int references movies (id),
+    position     int       not null,
+    note         text      not null default '',
+    added_at     timestamp not null default now(),
+    primary key (watchlist_id, movie_id)
+);
+
+create index watchlist_items_movie_id_idx on watchlist_items (movie_id);
+
+-- The old watchlist was shared by everyone, so it becomes the default list of
+-- every existing user.
+insert into watchlists (user_id, title, is_default)
+select id, 'Watchlist', true
+from users;
+
+insert into watchlist_items (watchlist_id, movie_id, position, added_at)
+select l.id, w.movie_id, row_number() over (partition by l.id order by w.added_at, w.movie_id), w.added_at
+from watchlists l
+cross join watchlist w;
+
+drop table watchlist;
@@ -0,0 +1,10 @@
+alter table users
+    add column role text not null default 'viewer' check (role in ('admin', 'editor', 'viewer'));
+
+-- Keep at least the seeded admin, or the oldest account when there is none,
+-- able to manage users.
+update users
+set role = 'admin'
+where email = 'admin@admin.com'
+   or (not exists(select 1 from users where email = 'admin@admin.com')
+       and id = (select min(id) from users));
@@ -0,0 +1,3 @@
+drop table revoked_access_tokens;
+drop table refresh_tokens;
+drop table auth_sessions;
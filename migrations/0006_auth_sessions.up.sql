@@ -0,0 +1,26 @@
+create table auth_sessions
+(
+    id         uuid primary key,
+    user_id    int       not null references users (id) on delete cascade,
+    created_at timestamp not null default now(),
+    revoked_at timestamp
+);
+
+create index auth_sessions_user_id_idx on auth_sessions (user_id);
+
+create table refresh_tokens
+(
+    token_hash text primary key,
+    session_id uuid      not null references auth_sessions (id) on delete cascade,
+    expires_at timestamp not null,
+    used_at    timestamp,
+    created_at timestamp not null default now()
+);
+
+create index refresh_tokens_session_id_idx on refresh_tokens (session_id);
+
+create table revoked_access_tokens
+(
+    jti        text primary key,
+    expires_at timestamp not null
+);
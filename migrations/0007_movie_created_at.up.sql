@@ -0,0 +1,5 @@
+-- Existing movies get the time of the migration, there is no better guess.
+alter table movies
+    add column created_at timestamp not null default now();
+
+create index movies_created_at_idx on movies (created_at);
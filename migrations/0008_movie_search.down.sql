@@ -0,0 +1,5 @@
+drop index if exists movies_director_trgm_idx;
+drop index if exists movies_title_trgm_idx;
+drop index if exists movies_search_vector_idx;
+
+alter table movies drop column search_vector;
@@ -0,0 +1,15 @@
+create extension if not exists pg_trgm;
+
+-- The 'simple' configuration only lowercases and splits words, without any
+-- language specific stemming, so Kazakh, Russian and English titles are all
+-- tokenized the same way.
+alter table movies
+    add column search_vector tsvector generated always as (
+        setweight(to_tsvector('simple', coalesce(title, '')), 'A') ||
+        setweight(to_tsvector('simple', coalesce(director, '')), 'B') ||
+        setweight(to_tsvector('simple', coalesce(description, '')), 'C')
+    ) stored;
+
+create index movies_search_vector_idx on movies using gin (search_vector);
+create index movies_title_trgm_idx on movies using gin (lower(title) gin_trgm_ops);
+create index movies_director_trgm_idx on movies using gin (lower(director) gin_trgm_ops);
@@ -0,0 +1,3 @@
+alter table movies drop constraint movies_poster_url_fkey;
+
+drop table images;
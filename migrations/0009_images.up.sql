@@ -0,0 +1,18 @@
+-- Every stored poster is tracked here. Images no movie references are marked
+-- as orphaned by the sweeper and deleted once the grace period has passed.
+create table images
+(
+    id           text primary key,
+    content_type text      not null default '',
+    size         bigint    not null default 0,
+    created_at   timestamp not null default now(),
+    orphaned_at  timestamp
+);
+
+insert into images(id)
+select distinct poster_url
+from movies
+where poster_url is not null;
+
+alter table movies
+    add constraint movies_poster_url_fkey foreign key (poster_url) references images (id);
@@ -0,0 +1,3 @@
+alter table users drop column version;
+alter table genres drop column version;
+alter table movies drop column version;
@@ -0,0 +1,9 @@
+-- Incremented on every change, the version is the basis of ETags and If-Match checks.
+alter table movies
+    add column version int not null default 1;
+
+alter table genres
+    add column version int not null default 1;
+
+alter table users
+    add column version int not null default 1;
@@ -0,0 +1,36 @@
+-- Every change of a movie or genre is recorded as an immutable revision with
+-- a snapshot of the entity after the change and the fields that changed.
+create table revisions
+(
+    id          serial primary key,
+    entity_type text      not null check (entity_type in ('movie', 'genre')),
+    entity_id   int       not null,
+    version     int       not null,
+    action      text      not null check (action in ('create', 'update', 'delete', 'restore')),
+    user_id     int references users (id) on delete set null,
+    snapshot    jsonb     not null,
+    changes     jsonb     not null default '{}',
+    created_at  timestamp not null default now()
+);
+
+create index revisions_entity_idx on revisions (entity_type, entity_id, id);
+
+-- Existing movies and genres get a first revision, so the changes of their
+-- next revision are computed against their current state.
+insert into revisions (entity_type, entity_id, version, action, snapshot)
+select 'movie', m.id, m.version, 'create', jsonb_build_object(
+    'Title', m.title,
+    'Description', m.description,
+    'ReleaseYear', m.release_year,
+    'Director', m.director,
+    'TrailerUrl', m.trailer_url,
+    'PosterUrl', m.poster_url,
+    'GenreIds', coalesce((select jsonb_agg(mg.genre_id order by mg.genre_id) from movies_genres mg where mg.movie_id = m.id), '[]')
+)
+from movies m
+order by m.id;
+
+insert into revisions (entity_type, entity_id, version, action, snapshot)
+select 'genre', g.id, g.version, 'create', jsonb_build_object('Title', g.title)
+from genres g
+order by g.id;
@@ -0,0 +1,14 @@
+-- Trashed rows would become visible again, so they are purged.
+delete from movies_genres where movie_id in (select id from movies where deleted_at is not null)
+                             or genre_id in (select id from genres where deleted_at is not null);
+delete from user_movie_ratings where movie_id in (select id from movies where deleted_at is not null);
+delete from watchlist_items where movie_id in (select id from movies where deleted_at is not null);
+delete from user_watched_movies where movie_id in (select id from movies where deleted_at is not null);
+delete from watch_events where movie_id in (select id from movies where deleted_at is not null);
+delete from movies where deleted_at is not null;
+delete from genres where deleted_at is not null;
+delete from users where deleted_at is not null;
+
+alter table users drop column deleted_at;
+alter table genres drop column deleted_at;
+alter table movies drop column deleted_at;
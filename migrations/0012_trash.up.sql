@@ -0,0 +1,14 @@
+-- Deleted movies, genres and users are moved to the trash by setting
+-- deleted_at. They are purged for good once the retention period has passed.
+alter table movies
+    add column deleted_at timestamp;
+
+alter table genres
+    add column deleted_at timestamp;
+
+alter table users
+    add column deleted_at timestamp;
+
+create index movies_deleted_at_idx on movies (deleted_at) where deleted_at is not null;
+create index genres_deleted_at_idx on genres (deleted_at) where deleted_at is not null;
+create index users_deleted_at_idx on users (deleted_at) where deleted_at is not null;
@@ -0,0 +1,4 @@
+update revisions set snapshot = snapshot - 'Credits' where entity_type = 'movie';
+
+drop table movie_credits;
+drop table people;
@@ -0,0 +1,53 @@
+-- Directors, actors and crew are people, credited on movies with a role.
+-- movies.director is kept as the names of the director credits, so that
+-- search and clients that only know the director string keep working.
+create table people
+(
+    id         serial primary key,
+    name       text      not null,
+    version    int       not null default 1,
+    created_at timestamp not null default now()
+);
+
+create index people_name_idx on people (lower(name));
+
+-- billing_order is the order of a credit among the credits of the movie with
+-- the same role, starting from 1. Only actors have a character.
+create table movie_credits
+(
+    movie_id      int  not null references movies (id),
+    person_id     int  not null references people (id),
+    role          text not null check (role in ('director', 'actor', 'writer', 'composer')),
+    character     text not null default '',
+    billing_order int  not null,
+    primary key (movie_id, person_id, role, character)
+);
+
+create index movie_credits_person_id_idx on movie_credits (person_id);
+
+-- Every distinct director string becomes a person. Strings that only differ
+-- in case or surrounding spaces are the same person.
+insert into people (name)
+select distinct on (lower(trim(director))) trim(director)
+from movies
+where trim(coalesce(director, '')) <> ''
+order by lower(trim(director)), trim(director);
+
+insert into movie_credits (movie_id, person_id, role, billing_order)
+select m.id, p.id, 'director', 1
+from movies m
+join people p on lower(p.name) = lower(trim(m.director));
+
+update movies set director = trim(director) where director <> trim(director);
+
+-- The latest revision of every movie gets its credits, so that the next
+-- revision doesn't report them as changed.
+update revisions r
+set snapshot = r.snapshot || jsonb_build_object('Credits', coalesce((
+    select jsonb_agg(jsonb_build_object('PersonId', mc.person_id, 'Role', mc.role, 'Character', mc.character)
+                     order by mc.role, mc.billing_order)
+    from movie_credits mc
+    where mc.movie_id = r.entity_id
+), '[]'))
+where r.entity_type = 'movie'
+  and r.id = (select max(latest.id) from revisions latest where latest.entity_type = 'movie' and latest.entity_id = r.entity_id);
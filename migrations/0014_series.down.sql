@@ -0,0 +1,6 @@
+-- Series are kept as films without episodes.
+drop table user_watched_episodes;
+drop table episodes;
+drop table seasons;
+
+alter table movies drop column kind;
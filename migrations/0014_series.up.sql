@@ -0,0 +1,41 @@
+-- Series are movies of the kind 'series', so they share genres, posters,
+-- credits, watchlists and search with films. Their episodes are grouped into
+-- numbered seasons.
+alter table movies
+    add column kind text not null default 'movie' check (kind in ('movie', 'series'));
+
+create table seasons
+(
+    id        serial primary key,
+    series_id int  not null references movies (id),
+    number    int  not null check (number > 0),
+    title     text not null default '',
+    unique (series_id, number)
+);
+
+-- runtime is in minutes. Episodes without an air date count as aired.
+create table episodes
+(
+    id            serial primary key,
+    season_id     int  not null references seasons (id) on delete cascade,
+    number        int  not null check (number > 0),
+    title         text not null,
+    description   text not null default '',
+    runtime       int  not null check (runtime > 0),
+    air_date      date,
+    search_vector tsvector generated always as (
+        setweight(to_tsvector('simple', title), 'A') ||
+        setweight(to_tsvector('simple', description), 'C')
+    ) stored,
+    unique (season_id, number)
+);
+
+create index episodes_search_vector_idx on episodes using gin (search_vector);
+
+create table user_watched_episodes
+(
+    user_id    int       not null references users (id) on delete cascade,
+    episode_id int       not null references episodes (id) on delete cascade,
+    watched_at timestamp not null default now(),
+    primary key (user_id, episode_id)
+);
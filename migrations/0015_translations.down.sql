@@ -0,0 +1,4 @@
+update revisions set snapshot = snapshot - 'Translations' where entity_type in ('movie', 'genre');
+
+drop table genre_translations;
+drop table movie_translations;
@@ -0,0 +1,26 @@
+-- Titles and descriptions of movies and genres translated to Kazakh, Russian
+-- and English. The columns of movies and genres keep the original text, which
+-- is shown when there is no translation to any of the requested languages.
+create table movie_translations
+(
+    movie_id      int  not null references movies (id),
+    locale        text not null check (locale in ('kk', 'ru', 'en')),
+    title         text not null,
+    description   text not null default '',
+    search_vector tsvector generated always as (
+        setweight(to_tsvector('simple', title), 'A') ||
+        setweight(to_tsvector('simple', description), 'C')
+    ) stored,
+    primary key (movie_id, locale)
+);
+
+create index movie_translations_search_vector_idx on movie_translations using gin (search_vector);
+create index movie_translations_title_trgm_idx on movie_translations using gin (lower(title) gin_trgm_ops);
+
+create table genre_translations
+(
+    genre_id int  not null references genres (id),
+    locale   text not null check (locale in ('kk', 'ru', 'en')),
+    title    text not null,
+    primary key (genre_id, locale)
+);
@@ -0,0 +1,257 @@
+// Package migrations contains the database schema as ordered SQL migrations
+// embedded into the binary, and the migrator that applies them.
+//
+// Every migration is a pair of files named <version>_<name>.up.sql and
+// <version>_<name>.down.sql. Versions must be unique and are applied in
+// ascending order. Applied versions are recorded in schema_migrations.
+package migrations
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"goozinshe/logger"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+//go:embed *.sql
+var files embed.FS
+
+// lockId is the key of the advisory lock that keeps several instances
+// starting at the same time from migrating concurrently.
+const lockId = 4_872_112_309
+
+var fileNamePattern = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+type MigrationStatus struct {
+	Version   int
+	Name      string
+	AppliedAt *time.Time
+}
+
+type Migrator struct {
+	db         *pgxpool.Pool
+	migrations []Migration
+}
+
+func NewMigrator(conn *pgxpool.Pool) (*Migrator, error) {
+	migrations, err := load(files)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{db: conn, migrations: migrations}, nil
+}
+
+// load reads the migrations in fsys, ordered by version.
+func load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		match := fileNamePattern.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("unexpected migration file name %q", entry.Name())
+		}
+
+		version, _ := strconv.Atoi(match[1])
+		content, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		}
+		if migration.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has files with different names", version)
+		}
+
+		if match[3] == "up" {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %d must have both an up and a down file", migration.Version)
+		}
+		migrations = append(migrations, *migration)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+// Up applies all pending migrations and returns how many were applied.
+func (m *Migrator) Up(c context.Context) (int, error) {
+	logger := logger.GetLogger()
+	count := 0
+
+	err := m.withLock(c, func(conn *pgxpool.Conn) error {
+		applied, err := m.applied(c, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			if _, ok := applied[migration.Version]; ok {
+				continue
+			}
+
+			logger.Info("Applying migration", zap.Int("version", migration.Version), zap.String("name", migration.Name))
+			err := pgx.BeginFunc(c, conn, func(tx pgx.Tx) error {
+				if _, err := tx.Exec(c, migration.Up); err != nil {
+					return err
+				}
+				_, err := tx.Exec(c, "insert into schema_migrations(version, name) values($1, $2)", migration.Version, migration.Name)
+				return err
+			})
+			if err != nil {
+				logger.Error("Could not apply migration", zap.Int("version", migration.Version), zap.Error(err))
+				return fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+			count++
+		}
+		return nil
+	})
+	if err != nil {
+		return count, err
+	}
+
+	logger.Info("Database is up to date", zap.Int("applied", count))
+	return count, nil
+}
+
+// Down rolls back the given number of most recently applied migrations and
+// returns how many were rolled back.
+func (m *Migrator) Down(c context.Context, steps int) (int, error) {
+	logger := logger.GetLogger()
+	count := 0
+
+	err := m.withLock(c, func(conn *pgxpool.Conn) error {
+		applied, err := m.applied(c, conn)
+		if err != nil {
+			return err
+		}
+
+		for i := len(m.migrations) - 1; i >= 0 && count < steps; i-- {
+			migration := m.migrations[i]
+			if _, ok := applied[migration.Version]; !ok {
+				continue
+			}
+
+			logger.Info("Rolling back migration", zap.Int("version", migration.Version), zap.String("name", migration.Name))
+			err := pgx.BeginFunc(c, conn, func(tx pgx.Tx) error {
+				if _, err := tx.Exec(c, migration.Down); err != nil {
+					return err
+				}
+				_, err := tx.Exec(c, "delete from schema_migrations where version = $1", migration.Version)
+				return err
+			})
+			if err != nil {
+				logger.Error("Could not roll back migration", zap.Int("version", migration.Version), zap.Error(err))
+				return fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+			count++
+		}
+		return nil
+	})
+
+	return count, err
+}
+
+// Status lists all known migrations together with the time they were applied.
+func (m *Migrator) Status(c context.Context) ([]MigrationStatus, error) {
+	var statuses []MigrationStatus
+
+	err := m.withLock(c, func(conn *pgxpool.Conn) error {
+		applied, err := m.applied(c, conn)
+		if err != nil {
+			return err
+		}
+
+		statuses = make([]MigrationStatus, 0, len(m.migrations))
+		for _, migration := range m.migrations {
+			status := MigrationStatus{Version: migration.Version, Name: migration.Name}
+			if appliedAt, ok := applied[migration.Version]; ok {
+				status.AppliedAt = &appliedAt
+			}
+			statuses = append(statuses, status)
+		}
+		return nil
+	})
+
+	return statuses, err
+}
+
+func (m *Migrator) withLock(c context.Context, fn func(conn *pgxpool.Conn) error) error {
+	conn, err := m.db.Acquire(c)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(c, "select pg_advisory_lock($1)", lockId); err != nil {
+		return err
+	}
+	defer conn.Exec(context.Background(), "select pg_advisory_unlock($1)", lockId)
+
+	_, err = conn.Exec(c, `
+create table if not exists schema_migrations
+(
+    version    bigint primary key,
+    name       text      not null,
+    applied_at timestamp not null default now()
+)`)
+	if err != nil {
+		return err
+	}
+
+	return fn(conn)
+}
+
+func (m *Migrator) applied(c context.Context, conn *pgxpool.Conn) (map[int]time.Time, error) {
+	rows, err := conn.Query(c, "select version, applied_at from schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int]time.Time)
+	for rows.Next() {
+		var version int
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = appliedAt
+	}
+
+	return applied, rows.Err()
+}
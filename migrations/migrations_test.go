@@ -0,0 +1,104 @@
+package migrations
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoad(t *testing.T) {
+	fsys := fstest.MapFS{
+		"0010_watchlists.up.sql":   {Data: []byte("create table watchlists();")},
+		"0010_watchlists.down.sql": {Data: []byte("drop table watchlists;")},
+		"0002_ratings.up.sql":      {Data: []byte("create table ratings();")},
+		"0002_ratings.down.sql":    {Data: []byte("drop table ratings;")},
+	}
+
+	migrations, err := load(fsys)
+	if err != nil {
+		t.Fatalf("load returned error %v", err)
+	}
+
+	want := []Migration{
+		{Version: 2, Name: "ratings", Up: "create table ratings();", Down: "drop table ratings;"},
+		{Version: 10, Name: "watchlists", Up: "create table watchlists();", Down: "drop table watchlists;"},
+	}
+	if len(migrations) != len(want) {
+		t.Fatalf("load returned %d migrations, want %d", len(migrations), len(want))
+	}
+	for i := range want {
+		if migrations[i] != want[i] {
+			t.Errorf("migration %d = %+v, want %+v", i, migrations[i], want[i])
+		}
+	}
+}
+
+func TestLoadRejectsInvalidFiles(t *testing.T) {
+	up := &fstest.MapFile{Data: []byte("select 1;")}
+
+	tests := []struct {
+		name    string
+		fsys    fstest.MapFS
+		wantErr string
+	}{
+		{
+			name:    "unexpected name",
+			fsys:    fstest.MapFS{"0001_init.sql": up},
+			wantErr: "unexpected migration file name",
+		},
+		{
+			name:    "no version",
+			fsys:    fstest.MapFS{"init.up.sql": up, "init.down.sql": up},
+			wantErr: "unexpected migration file name",
+		},
+		{
+			name:    "missing down file",
+			fsys:    fstest.MapFS{"0001_init.up.sql": up},
+			wantErr: "must have both an up and a down file",
+		},
+		{
+			name:    "missing up file",
+			fsys:    fstest.MapFS{"0001_init.down.sql": up},
+			wantErr: "must have both an up and a down file",
+		},
+		{
+			name:    "empty up file",
+			fsys:    fstest.MapFS{"0001_init.up.sql": {}, "0001_init.down.sql": up},
+			wantErr: "must have both an up and a down file",
+		},
+		{
+			name:    "different names for a version",
+			fsys:    fstest.MapFS{"0001_init.up.sql": up, "0001_setup.down.sql": up},
+			wantErr: "has files with different names",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := load(tt.fsys)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("load error = %v, want one containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestEmbeddedMigrations checks the migrations shipped in the binary.
+func TestEmbeddedMigrations(t *testing.T) {
+	migrations, err := load(files)
+	if err != nil {
+		t.Fatalf("load returned error %v", err)
+	}
+	if len(migrations) == 0 {
+		t.Fatal("no migrations are embedded")
+	}
+
+	for i, migration := range migrations {
+		if i > 0 && migration.Version <= migrations[i-1].Version {
+			t.Errorf("migration %d_%s is not after %d_%s", migration.Version, migration.Name, migrations[i-1].Version, migrations[i-1].Name)
+		}
+		if strings.TrimSpace(migration.Up) == "" || strings.TrimSpace(migration.Down) == "" {
+			t.Errorf("migration %d_%s has an empty up or down file", migration.Version, migration.Name)
+		}
+	}
+}
@@ -0,0 +1,10 @@
+package models
+
+import "github.com/golang-jwt/jwt/v5"
+
+// AuthClaims are the claims of the access tokens issued on sign in.
+type AuthClaims struct {
+	Role      string `json:"role"`
+	SessionId string `json:"sid"`
+	jwt.RegisteredClaims
+}
@@ -1,9 +1,139 @@
 package models
 
-type ApiError struct {
-	Error string
+// Errors that repositories and handlers return to describe why a request
+// can't be served. middlewares.ErrorMiddleware turns them into problem
+// responses, any other error is reported to clients as an internal error.
+// Code is a stable, machine readable identifier, Message is shown to clients
+// as the problem detail.
+
+const (
+	CodeValidationFailed = "validation_failed"
+	CodeInvalid          = "invalid"
+	CodeInternalError    = "internal_error"
+)
+
+// Codes of fields that refer to data that doesn't exist, or conflict with it.
+const (
+	CodeNotFound = "not_found"
+	CodeTaken    = "taken"
+)
+
+// ValidationError rejects a request because of its parameters or payload.
+// Fields lists every rejected field.
+type ValidationError struct {
+	Code	string
+	Message	string
+	Fields	[]FieldError
+}
+
+func (e *ValidationError) Error() string {
+	return e.Message
+}
+
+// FieldError describes why the value of a single field was rejected. Field is
+// the path of the field in the payload, or the name of a path or query
+// parameter.
+type FieldError struct {
+	Field	string	`json:"field"`
+	Code	string	`json:"code"`
+	Message	string	`json:"message"`
+}
+
+// NewValidationError rejects a request because of the given fields.
+func NewValidationError(message string, fields ...FieldError) *ValidationError {
+	return &ValidationError{Code: CodeValidationFailed, Message: message, Fields: fields}
+}
+
+// NewInvalidFieldError rejects a request because of a single field.
+func NewInvalidFieldError(field string, message string) *ValidationError {
+	return NewValidationError(message, FieldError{Field: field, Code: CodeInvalid, Message: message})
+}
+
+// UnauthorizedError is returned when the client isn't signed in or its
+// credentials are wrong.
+type UnauthorizedError struct {
+	Code	string
+	Message	string
+}
+
+func (e *UnauthorizedError) Error() string {
+	return e.Message
+}
+
+func NewUnauthorizedError(code string, message string) *UnauthorizedError {
+	return &UnauthorizedError{Code: code, Message: message}
+}
+
+// ForbiddenError is returned when the user isn't allowed to do something.
+type ForbiddenError struct {
+	Code	string
+	Message	string
+}
+
+func (e *ForbiddenError) Error() string {
+	return e.Message
+}
+
+func NewForbiddenError(code string, message string) *ForbiddenError {
+	return &ForbiddenError{Code: code, Message: message}
 }
 
-func NewApiError(msg string) ApiError{
-	return ApiError{msg}
-}
\ No newline at end of file
+// NotFoundError is returned when an entity doesn't exist, or exists but the
+// user may not see it.
+type NotFoundError struct {
+	Code	string
+	Message	string
+}
+
+func (e *NotFoundError) Error() string {
+	return e.Message
+}
+
+func NewNotFoundError(code string, message string) *NotFoundError {
+	return &NotFoundError{Code: code, Message: message}
+}
+
+// ConflictError is returned when a change conflicts with the current state of
+// the data.
+type ConflictError struct {
+	Code	string
+	Message	string
+}
+
+func (e *ConflictError) Error() string {
+	return e.Message
+}
+
+func NewConflictError(code string, message string) *ConflictError {
+	return &ConflictError{Code: code, Message: message}
+}
+
+// PreconditionFailedError is returned when a conditional request doesn't
+// match the current version of an entity.
+type PreconditionFailedError struct {
+	Code	string
+	Message	string
+}
+
+func (e *PreconditionFailedError) Error() string {
+	return e.Message
+}
+
+func NewPreconditionFailedError(code string, message string) *PreconditionFailedError {
+	return &PreconditionFailedError{Code: code, Message: message}
+}
+
+// UnsupportedMediaTypeError is returned for payloads of a type an endpoint
+// doesn't accept.
+type UnsupportedMediaTypeError struct {
+	Code	string
+	Message	string
+}
+
+func (e *UnsupportedMediaTypeError) Error() string {
+	return e.Message
+}
+
+func NewUnsupportedMediaTypeError(code string, message string) *UnsupportedMediaTypeError {
+	return &UnsupportedMediaTypeError{Code: code, Message: message}
+}
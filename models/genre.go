@@ -3,4 +3,5 @@ package models
 type Genre struct {
 	Id 		int
 	Title 	string
+	Version	int		`json:"-"`	// sent as ETag
 }
\ No newline at end of file
@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+type WatchEvent struct {
+	Id			int
+	MovieId		int
+	MovieTitle	string
+	PosterUrl	string
+	WatchedAt	time.Time
+	IsRewatch	bool
+}
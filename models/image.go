@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// Image is a stored poster. OrphanedAt is set while no movie references it.
+type Image struct {
+	Id			string
+	ContentType	string
+	Size		int64
+	CreatedAt	time.Time
+	OrphanedAt	*time.Time
+}
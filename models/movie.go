@@ -1,21 +1,68 @@
 package models
 
+import "time"
+
 type Movie struct {
-	Id			int
-	Title		string
-	Description	string
-	ReleaseYear	int
-	Director	string
-	Rating		int
-	IsWatched	bool
-	TrailerUrl	string
-	PosterUrl	string
-	Genres		[]Genre	
+	Id						int
+	Kind					string	// movie or series
+	Title					string
+	Description				string
+	ReleaseYear				int
+	Director				string
+	Rating					int	// caller's own rating, 0 when not rated
+	AverageRating			float64
+	RatingsCount			int
+	RatingDistribution		map[int]int
+	IsWatched				bool
+	TrailerUrl				string
+	PosterUrl				string
+	CreatedAt				time.Time
+	Version					int		`json:"-"`	// sent as ETag
+	EpisodesCount			int		// series only
+	WatchedEpisodesCount	int		// series only, episodes the caller watched
+	Genres					[]Genre
+	Credits					[]Credit
 }
 
+const (
+	GenreMatchAny = "any"
+	GenreMatchAll = "all"
+)
+
+// MovieFilters narrows down the movies list. Zero values don't filter.
 type MovieFilters struct {
-	SearchTerm	string
-	GenreId 	string
-	IsWatched 	string
-	Sort		string
-}
\ No newline at end of file
+	SearchTerm	string		`form:"search"`
+	Kind		string		`form:"kind"`				// movie or series
+	GenreIds	[]int		`form:"genreids"`			// comma separated
+	GenreMatch	string		`form:"genrematch"`		// any (default) or all of GenreIds
+	YearFrom	int			`form:"yearfrom"`
+	YearTo		int			`form:"yearto"`
+	Director	string		`form:"director"`
+	PersonId	int			`form:"personid"`		// movies the person is credited in
+	PersonRole	string		`form:"personrole"`		// only credits with this role, needs PersonId
+	MinRating	float64		`form:"minrating"`		// minimum average rating
+	HasTrailer	*bool		`form:"hastrailer"`
+	IsWatched	*bool		`form:"iswatched"`
+	Sort		[]SortField	`swaggerignore:"true"`
+}
+
+// MoviePatch lists the changes of a partial movie update. Nil fields are left
+// unchanged. GenreIds replaces all genres, AddGenreIds and RemoveGenreIds are
+// applied after it. The patch only applies to Version, unless it is 0.
+type MoviePatch struct {
+	Version			int
+	Title			*string
+	Description		*string
+	ReleaseYear		*int
+	Director		*string
+	TrailerUrl		*string
+	GenreIds		[]int
+	AddGenreIds		[]int
+	RemoveGenreIds	[]int
+}
+
+// IsEmpty reports whether the patch changes nothing.
+func (p MoviePatch) IsEmpty() bool {
+	return p.Title == nil && p.Description == nil && p.ReleaseYear == nil && p.Director == nil && p.TrailerUrl == nil &&
+		p.GenreIds == nil && len(p.AddGenreIds) == 0 && len(p.RemoveGenreIds) == 0
+}
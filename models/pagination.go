@@ -0,0 +1,57 @@
+package models
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// PageRequest selects a page either by page number or, when Cursor is set,
+// relative to the row the cursor points at.
+type PageRequest struct {
+	Page	int
+	Limit	int
+	Cursor	*Cursor
+}
+
+func (p PageRequest) Offset() int {
+	return (p.Page - 1) * p.Limit
+}
+
+// PageInfo describes the returned page. Cursors are empty when there is no
+// page in that direction, or when the page itself is empty.
+type PageInfo struct {
+	Total		int
+	NextCursor	string
+	PrevCursor	string
+}
+
+// Cursor points at the row a page starts after or ends before. Clients get it
+// as an opaque string.
+type Cursor struct {
+	After	int	`json:"a,omitempty"`
+	Before	int	`json:"b,omitempty"`
+}
+
+func (c Cursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func ParseCursor(s string) (Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+
+	var c Cursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+	if (c.After == 0) == (c.Before == 0) {
+		return Cursor{}, ErrInvalidCursor
+	}
+	return c, nil
+}
@@ -0,0 +1,69 @@
+package models
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	tests := []Cursor{
+		{After: 42},
+		{Before: 7},
+	}
+
+	for _, cursor := range tests {
+		parsed, err := ParseCursor(cursor.Encode())
+		if err != nil {
+			t.Errorf("ParseCursor(%v.Encode()) returned error %v", cursor, err)
+			continue
+		}
+		if parsed != cursor {
+			t.Errorf("ParseCursor(%v.Encode()) = %v", cursor, parsed)
+		}
+	}
+}
+
+func TestParseCursorRejectsInvalidCursors(t *testing.T) {
+	encode := func(s string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(s))
+	}
+
+	tests := []struct {
+		name   string
+		cursor string
+	}{
+		{"empty", ""},
+		{"not base64", "not a cursor!"},
+		{"padded base64", base64.URLEncoding.EncodeToString([]byte(`{"a":1}`))},
+		{"not json", encode("a=1")},
+		{"neither after nor before", encode(`{}`)},
+		{"both after and before", encode(`{"a":1,"b":2}`)},
+		{"wrong type", encode(`{"a":"1"}`)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseCursor(tt.cursor); !errors.Is(err, ErrInvalidCursor) {
+				t.Errorf("ParseCursor(%q) error = %v, want %v", tt.cursor, err, ErrInvalidCursor)
+			}
+		})
+	}
+}
+
+func TestPageRequestOffset(t *testing.T) {
+	tests := []struct {
+		page   PageRequest
+		offset int
+	}{
+		{PageRequest{Page: 1, Limit: 20}, 0},
+		{PageRequest{Page: 2, Limit: 20}, 20},
+		{PageRequest{Page: 5, Limit: 7}, 28},
+	}
+
+	for _, tt := range tests {
+		if offset := tt.page.Offset(); offset != tt.offset {
+			t.Errorf("%+v.Offset() = %d, want %d", tt.page, offset, tt.offset)
+		}
+	}
+}
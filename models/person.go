@@ -0,0 +1,38 @@
+package models
+
+// Roles of people in the credits of a movie.
+const (
+	CreditDirector	= "director"
+	CreditActor		= "actor"
+	CreditWriter	= "writer"
+	CreditComposer	= "composer"
+)
+
+var CreditRoles = []string{CreditDirector, CreditActor, CreditWriter, CreditComposer}
+
+type Person struct {
+	Id		int
+	Name	string
+	Version	int		`json:"-"`	// sent as ETag
+}
+
+// Credit is the part a person had in a movie. BillingOrder is the order among
+// the credits of the movie with the same role, starting from 1.
+type Credit struct {
+	PersonId		int
+	Name			string
+	Role			string
+	Character		string	// actors only
+	BillingOrder	int
+}
+
+// FilmographyEntry is a credit of a person together with the movie.
+type FilmographyEntry struct {
+	MovieId			int
+	Title			string
+	ReleaseYear		int
+	PosterUrl		string
+	Role			string
+	Character		string
+	BillingOrder	int
+}
@@ -0,0 +1,22 @@
+package models
+
+// ProblemContentType is the media type of error responses (RFC 7807).
+const ProblemContentType = "application/problem+json"
+
+// ProblemTypePrefix is followed by the code of the problem in its type.
+const ProblemTypePrefix = "urn:ozinshe:problem:"
+
+// Problem is the body of every error response, a problem details object as
+// defined by RFC 7807. Code is stable and meant for programs, Detail is meant
+// for humans and may change. RequestId is also sent in the X-Request-Id
+// header and identifies the request in the server logs.
+type Problem struct {
+	Type		string			`json:"type"`
+	Title		string			`json:"title"`
+	Status		int				`json:"status"`
+	Detail		string			`json:"detail,omitempty"`
+	Instance	string			`json:"instance,omitempty"`
+	Code		string			`json:"code"`
+	RequestId	string			`json:"requestId,omitempty"`
+	Errors		[]FieldError	`json:"errors,omitempty"`
+}
@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+const (
+	RevisionCreate	= "create"
+	RevisionUpdate	= "update"
+	RevisionDelete	= "delete"
+	RevisionRestore	= "restore"
+)
+
+// Revision is a recorded change of a movie or genre. Snapshot holds the
+// entity after the change, Changes the fields that differ from the previous
+// revision.
+type Revision struct {
+	Id			int
+	Version		int
+	Action		string
+	UserId		*int	// nil when the change wasn't made through the API or the user was deleted
+	Snapshot	map[string]any
+	Changes		map[string]FieldChange
+	CreatedAt	time.Time
+}
+
+type FieldChange struct {
+	Old	any
+	New	any
+}
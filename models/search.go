@@ -0,0 +1,20 @@
+package models
+
+// SearchResult is a movie matching a search query. TitleHighlight and Snippet
+// are HTML escaped, with the matched words wrapped in <mark> tags. Episodes
+// lists the best matching episodes of a series.
+type SearchResult struct {
+	Movie			Movie
+	Rank			float64
+	TitleHighlight	string
+	Snippet			string
+	Episodes		[]EpisodeMatch
+}
+
+type Suggestion struct {
+	MovieId		int
+	Kind		string
+	Title		string
+	ReleaseYear	int
+	PosterUrl	string
+}
@@ -0,0 +1,39 @@
+package models
+
+// Kinds of movies. Series have seasons of episodes, films don't.
+const (
+	KindMovie	= "movie"
+	KindSeries	= "series"
+)
+
+var MovieKinds = []string{KindMovie, KindSeries}
+
+// Season is a numbered season of a series. WatchedEpisodesCount is the
+// caller's progress through it.
+type Season struct {
+	Id						int
+	Number					int
+	Title					string
+	EpisodesCount			int
+	WatchedEpisodesCount	int
+	Episodes				[]Episode
+}
+
+type Episode struct {
+	Id				int
+	SeasonNumber	int
+	Number			int
+	Title			string
+	Description		string
+	Runtime			int		// minutes
+	AirDate			string	// YYYY-MM-DD, empty when unknown
+	IsWatched		bool	// watched by the caller
+}
+
+// EpisodeMatch is an episode of a series that matched a search query.
+type EpisodeMatch struct {
+	Id				int
+	SeasonNumber	int
+	Number			int
+	Title			string
+}
@@ -0,0 +1,43 @@
+package models
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// MovieSortKeys are the keys movies can be sorted by.
+var MovieSortKeys = []string{"title", "releaseYear", "rating", "averageRating", "createdAt", "addedToWatchlistAt"}
+
+// SortField is one key of a sort, e.g. "-rating" sorts by rating descending.
+type SortField struct {
+	Key		string
+	Desc	bool
+}
+
+// ParseSort parses a comma separated list of sort keys. A key prefixed with
+// "-" sorts descending. Only the allowed keys are accepted, each at most once.
+func ParseSort(value string, allowed []string) ([]SortField, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	fields := make([]SortField, 0)
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		field := SortField{Key: strings.TrimPrefix(part, "-")}
+		field.Desc = field.Key != part
+
+		if !slices.Contains(allowed, field.Key) {
+			return nil, fmt.Errorf("unknown sort key %q, expected one of: %s", field.Key, strings.Join(allowed, ", "))
+		}
+		if seen[field.Key] {
+			return nil, fmt.Errorf("sort key %q is used more than once", field.Key)
+		}
+		seen[field.Key] = true
+		fields = append(fields, field)
+	}
+
+	return fields, nil
+}
@@ -0,0 +1,55 @@
+package models
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseSort(t *testing.T) {
+	allowed := []string{"title", "rating", "createdAt"}
+
+	tests := []struct {
+		name    string
+		value   string
+		want    []SortField
+		wantErr bool
+	}{
+		{name: "empty", value: "", want: nil},
+		{name: "ascending", value: "title", want: []SortField{{Key: "title"}}},
+		{name: "descending", value: "-rating", want: []SortField{{Key: "rating", Desc: true}}},
+		{
+			name:  "several keys in order",
+			value: "-rating,title",
+			want:  []SortField{{Key: "rating", Desc: true}, {Key: "title"}},
+		},
+		{
+			name:  "spaces around keys",
+			value: " title , -createdAt ",
+			want:  []SortField{{Key: "title"}, {Key: "createdAt", Desc: true}},
+		},
+		{name: "unknown key", value: "director", wantErr: true},
+		{name: "keys are case sensitive", value: "Title", wantErr: true},
+		{name: "repeated key", value: "title,title", wantErr: true},
+		{name: "repeated key in both directions", value: "title,-title", wantErr: true},
+		{name: "empty key", value: "title,", wantErr: true},
+		{name: "only a minus", value: "-", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseSort(tt.value, allowed)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("ParseSort(%q) = %v, want an error", tt.value, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseSort(%q) returned error %v", tt.value, err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("ParseSort(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
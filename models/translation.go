@@ -0,0 +1,18 @@
+package models
+
+// Locales content can be translated to.
+const (
+	LocaleKazakh	= "kk"
+	LocaleRussian	= "ru"
+	LocaleEnglish	= "en"
+)
+
+var Locales = []string{LocaleKazakh, LocaleRussian, LocaleEnglish}
+
+// Translation is the title and description of a movie or genre in a locale.
+// Genres only have a title.
+type Translation struct {
+	Locale		string
+	Title		string
+	Description	string	`json:",omitempty"`
+}
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// Entity types. Movies and genres keep revisions, all of them can be trashed.
+const (
+	EntityMovie	= "movie"
+	EntityGenre	= "genre"
+	EntityUser	= "user"
+)
+
+// TrashItem is a deleted movie, genre or user, which can be restored until
+// PurgeAt.
+type TrashItem struct {
+	Type		string
+	Id			int
+	Title		string	// movie or genre title, user name
+	DeletedAt	time.Time
+	PurgeAt		time.Time
+}
@@ -1,8 +1,16 @@
 package models
 
+const (
+	RoleAdmin  = "admin"
+	RoleEditor = "editor"
+	RoleViewer = "viewer"
+)
+
 type User struct {
 	Id				int
 	Name			string
 	Email			string
 	PasswordHash	string
-}
\ No newline at end of file
+	Role			string
+	Version			int		// sent as ETag
+}
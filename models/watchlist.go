@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+const (
+	WatchlistPrivate = "private"
+	WatchlistLink    = "link"
+	WatchlistPublic  = "public"
+)
+
+type Watchlist struct {
+	Id			int
+	UserId		int
+	Title		string
+	Visibility	string
+	ShareToken	string	`json:",omitempty"`
+	IsDefault	bool
+	ItemsCount	int
+	CreatedAt	time.Time
+	Items		[]WatchlistItem	`json:",omitempty"`
+}
+
+type WatchlistItem struct {
+	Movie		Movie
+	Position	int
+	Note		string
+	AddedAt		time.Time
+}
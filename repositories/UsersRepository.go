@@ -2,13 +2,22 @@ package repositories
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"goozinshe/models"
 	"goozinshe/logger"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"go.uber.org/zap"
 )
 
+var ErrUserNotFound = models.NewNotFoundError("user_not_found", "user not found")
+
+// ErrEmailTaken is returned when another user already has the email.
+var ErrEmailTaken = models.NewValidationError("Invalid payload",
+	models.FieldError{Field: "email", Code: models.CodeTaken, Message: "email is already taken"})
+
 type UsersRepository struct {
 	db *pgxpool.Pool
 }
@@ -17,14 +26,35 @@ func NewUsersRepository(conn *pgxpool.Pool) *UsersRepository {
 	return &UsersRepository{db: conn}
 }
 
-func (r *UsersRepository) FindAll(c context.Context) ([]models.User, error) {
+// FindAll returns a page of users ordered by id.
+func (r *UsersRepository) FindAll(c context.Context, page models.PageRequest) ([]models.User, models.PageInfo, error) {
 	logger := logger.GetLogger()
-	logger.Info("Fetching all users")
+	logger.Info("Fetching all users", zap.Int("page", page.Page), zap.Int("limit", page.Limit))
 
-	rows, err := r.db.Query(c, "select id, name, email, password_hash from users order by id")
+	var total int
+	if err := r.db.QueryRow(c, "select count(*) from users where deleted_at is null").Scan(&total); err != nil {
+		logger.Error("Could not count users", zap.Error(err))
+		return nil, models.PageInfo{}, err
+	}
+
+	keys := []sortKey{{expr: "%[1]s.id"}}
+	sql := "select u.id, u.name, u.email, u.role from users u"
+	where := "where u.deleted_at is null"
+	params := pgx.NamedArgs{"limit": page.Limit + 1, "offset": page.Offset()}
+	reverse := false
+	if page.Cursor != nil {
+		reverse = page.Cursor.Before != 0
+		sql = fmt.Sprintf("%s join users cur on cur.id = @cursorId", sql)
+		where = fmt.Sprintf("%s and %s", where, keysetCondition(keys, "u", "cur", reverse))
+		params["cursorId"] = cursorId(page)
+		params["offset"] = 0
+	}
+	sql = fmt.Sprintf("%s %s order by %s limit @limit offset @offset", sql, where, orderBy(keys, "u", reverse))
+
+	rows, err := r.db.Query(c, sql, params)
 	if err != nil {
 		logger.Error("Could not fetch users", zap.Error(err))
-		return nil, err
+		return nil, models.PageInfo{}, err
 	}
 
 defer rows.Close()
@@ -32,20 +62,22 @@ defer rows.Close()
 	users := make([]models.User, 0)
 	for rows.Next() {
 		var user models.User
-		if err := rows.Scan(&user.Id, &user.Name, &user.Email, &user.PasswordHash); err != nil {
+		if err := rows.Scan(&user.Id, &user.Name, &user.Email, &user.Role); err != nil {
 			logger.Error("Could not scan user row", zap.Error(err))
-			return nil, err
+			return nil, models.PageInfo{}, err
 		}
 		users = append(users, user)
 	}
 
-	if rows.Err() != nil {
+	if err = rows.Err(); err != nil {
 		logger.Error("Error occurred during rows iteration", zap.Error(err))
-		return nil, err
+		return nil, models.PageInfo{}, err
 	}
 
-	logger.Info("Successfully fetched users", zap.Int("count", len(users)))
-	return users, nil
+	users, info := pageInfo(users, page, total, func(u models.User) int { return u.Id })
+
+	logger.Info("Successfully fetched users", zap.Int("count", len(users)), zap.Int("total", total))
+	return users, info, nil
 }
 
 func (r *UsersRepository) FindById(c context.Context, id int) (models.User, error) {
@@ -53,8 +85,12 @@ func (r *UsersRepository) FindById(c context.Context, id int) (models.User, erro
 	logger.Info("Fetching user by ID", zap.Int("user_id", id))
 
 	var user models.User
-	row := r.db.QueryRow(c, "select id, name, email from users where id = $1", id)
-	if err := row.Scan(&user.Id, &user.Name, &user.Email); err != nil {
+	row := r.db.QueryRow(c, "select id, name, email, role, version from users where id = $1 and deleted_at is null", id)
+	err := row.Scan(&user.Id, &user.Name, &user.Email, &user.Role, &user.Version)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.User{}, ErrUserNotFound
+	}
+	if err != nil {
 		logger.Error("Could not fetch user", zap.Error(err))
 		return models.User{}, err
 	}
@@ -68,8 +104,12 @@ func (r *UsersRepository) FindByEmail(c context.Context, email string) (models.U
 	logger.Info("Fetching user by email", zap.String("email", email))
 
 	var user models.User
-	row := r.db.QueryRow(c, "select id, name, email, password_hash from users where email = $1", email)
-	if err := row.Scan(&user.Id, &user.Name, &user.Email, &user.PasswordHash); err != nil {
+	row := r.db.QueryRow(c, "select id, name, email, password_hash, role from users where email = $1 and deleted_at is null", email)
+	err := row.Scan(&user.Id, &user.Name, &user.Email, &user.PasswordHash, &user.Role)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.User{}, ErrUserNotFound
+	}
+	if err != nil {
 		logger.Error("Could not fetch user by email", zap.Error(err))
 		return models.User{}, err
 	}
@@ -78,14 +118,33 @@ func (r *UsersRepository) FindByEmail(c context.Context, email string) (models.U
 	return user, nil
 }
 
+// IsEmailTaken reports whether a user other than the one with exceptId has
+// the email. Users in the trash keep their email until they are purged.
+func (r *UsersRepository) IsEmailTaken(c context.Context, email string, exceptId int) (bool, error) {
+	logger := logger.GetLogger()
+	logger.Info("Checking if email is taken", zap.String("email", email))
+
+	var taken bool
+	err := r.db.QueryRow(c, "select exists(select 1 from users where email = $1 and id <> $2)", email, exceptId).Scan(&taken)
+	if err != nil {
+		logger.Error("Could not check if email is taken", zap.Error(err))
+		return false, err
+	}
+
+	return taken, nil
+}
+
 func (r *UsersRepository) Create(c context.Context, user models.User) (int, error) {
 	logger := logger.GetLogger()
 	logger.Info("Creating new user", zap.String("email", user.Email))
 
 	var id int
-	err := r.db.QueryRow(c, "insert into users(name, email, password_hash) values($1, $2, $3) returning id", 
-		user.Name, user.Email, user.PasswordHash).Scan(&id)
+	err := r.db.QueryRow(c, "insert into users(name, email, password_hash, role) values($1, $2, $3, $4) returning id", 
+		user.Name, user.Email, user.PasswordHash, user.Role).Scan(&id)
 
+	if isUniqueViolation(err) {
+		return 0, ErrEmailTaken
+	}
 	if err != nil {
 		logger.Error("Could not create user", zap.Error(err))
 		return 0, err
@@ -95,11 +154,17 @@ func (r *UsersRepository) Create(c context.Context, user models.User) (int, erro
 	return id, nil
 }
 
+// Update, ChangePasswordHash, ChangeRole and Delete only change the user if
+// it still has the expected version. A version of 0 matches any version.
 func (r *UsersRepository) Update(c context.Context, id int, user models.User) error {
 	logger := logger.GetLogger()
 	logger.Info("Updating user", zap.Int("user_id", id))
 
-	_, err := r.db.Exec(c, "update users set name=$1, email=$2 where id=$3", user.Name, user.Email, id)
+	err := r.exec(c, id, user.Version, "update users set name = @name, email = @email, version = version + 1",
+		pgx.NamedArgs{"name": user.Name, "email": user.Email})
+	if isUniqueViolation(err) {
+		return ErrEmailTaken
+	}
 	if err != nil {
 		logger.Error("Could not update user", zap.Error(err))
 		return err
@@ -109,11 +174,12 @@ func (r *UsersRepository) Update(c context.Context, id int, user models.User) er
 	return nil
 }
 
-func (r *UsersRepository) ChangePasswordHash(c context.Context, id int, password string) error {
+func (r *UsersRepository) ChangePasswordHash(c context.Context, id int, password string, version int) error {
 	logger := logger.GetLogger()
 	logger.Info("Updating user password", zap.Int("user_id", id))
 
-	_, err := r.db.Exec(c, "update users set password_hash=$1 where id=$2", password, id)
+	err := r.exec(c, id, version, "update users set password_hash = @password, version = version + 1",
+		pgx.NamedArgs{"password": password})
 	if err != nil {
 		logger.Error("Could not update user password", zap.Error(err))
 		return err
@@ -123,11 +189,28 @@ func (r *UsersRepository) ChangePasswordHash(c context.Context, id int, password
 	return nil
 }
 
-func (r *UsersRepository) Delete(c context.Context, id int) error {
+func (r *UsersRepository) ChangeRole(c context.Context, id int, role string, version int) error {
+	logger := logger.GetLogger()
+	logger.Info("Updating user role", zap.Int("user_id", id), zap.String("role", role))
+
+	err := r.exec(c, id, version, "update users set role = @role, version = version + 1",
+		pgx.NamedArgs{"role": role})
+	if err != nil {
+		logger.Error("Could not update user role", zap.Error(err))
+		return err
+	}
+
+	logger.Info("Successfully updated user role", zap.Int("user_id", id), zap.String("role", role))
+	return nil
+}
+
+// Delete moves the user to the trash. The user can't sign in any more, but
+// keeps their watchlists, ratings and history until they are purged.
+func (r *UsersRepository) Delete(c context.Context, id int, version int) error {
 	logger := logger.GetLogger()
 	logger.Info("Deleting user", zap.Int("user_id", id))
 
-	_, err := r.db.Exec(c, "delete from users where id=$1", id)
+	err := r.exec(c, id, version, "update users set deleted_at = now(), version = version + 1", pgx.NamedArgs{})
 	if err != nil {
 		logger.Error("Could not delete user", zap.Error(err))
 		return err
@@ -137,4 +220,15 @@ func (r *UsersRepository) Delete(c context.Context, id int) error {
 	return nil
 }
 
+// exec runs a statement on the user with the id, if it is not in the trash
+// and still has the version.
+func (r *UsersRepository) exec(c context.Context, id int, version int, statement string, params pgx.NamedArgs) error {
+	params["id"] = id
+	params["version"] = version
 
+	tag, err := r.db.Exec(c, fmt.Sprintf("%s where id = @id and deleted_at is null and %s", statement, versionCondition), params)
+	if err != nil {
+		return err
+	}
+	return checkVersion(tag, version)
+}
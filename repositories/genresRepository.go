@@ -2,14 +2,23 @@ package repositories
 
 import (
 	"context"
+	"errors"
+	"fmt"
 
 	"goozinshe/models"
 	"goozinshe/logger"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"go.uber.org/zap"
 )
 
+var ErrGenreNotFound = models.NewNotFoundError("genre_not_found", "genre not found")
+
+// ErrGenreInUse is returned when deleting a genre would leave a movie without
+// genres.
+var ErrGenreInUse = models.NewConflictError("genre_in_use", "genre is the only genre of a movie")
+
 type GenresRepository struct {
 	db *pgxpool.Pool
 }
@@ -22,7 +31,7 @@ func (r *GenresRepository) FindAllByIds(c context.Context, ids []int) ([]models.
 	logger := logger.GetLogger()
 	logger.Info("Fetching genres by IDs", zap.Ints("genre_ids", ids))
 
-	rows, err := r.db.Query(c, "select id, title from genres where id = any($1)", ids)
+	rows, err := r.db.Query(c, "select id, title from genres where id = any($1) and deleted_at is null", ids)
 	if err != nil {
 		logger.Error("Could not fetch genres", zap.Error(err))
 		return nil, err
@@ -44,13 +53,21 @@ func (r *GenresRepository) FindAllByIds(c context.Context, ids []int) ([]models.
 	return genres, nil
 }
 
-func (r *GenresRepository) FindById(c context.Context, id int) (models.Genre, error) {
+// FindById returns the genre with its title in the first of locales it is
+// translated to.
+func (r *GenresRepository) FindById(c context.Context, id int, locales []string) (models.Genre, error) {
 	logger := logger.GetLogger()
 	logger.Info("Fetching genre by ID", zap.Int("genre_id", id))
 
 	var genre models.Genre
-	row := r.db.QueryRow(c, "select id, title from genres where id = $1", id)
-	err := row.Scan(&genre.Id, &genre.Title)
+	row := r.db.QueryRow(
+		c,
+		fmt.Sprintf("select g.id, %s, g.version from genres g where g.id = @id and g.deleted_at is null", translated(models.EntityGenre, "g", "title")),
+		pgx.NamedArgs{"id": id, "locales": locales})
+	err := row.Scan(&genre.Id, &genre.Title, &genre.Version)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.Genre{}, ErrGenreNotFound
+	}
 	if err != nil {
 		logger.Error("Could not fetch genre", zap.Error(err))
 		return models.Genre{}, err
@@ -60,14 +77,37 @@ func (r *GenresRepository) FindById(c context.Context, id int) (models.Genre, er
 	return genre, nil
 }
 
-func (r *GenresRepository) FindAll(c context.Context) ([]models.Genre, error) {
+// FindAll returns a page of genres ordered by id, with their titles in the
+// first of locales they are translated to.
+func (r *GenresRepository) FindAll(c context.Context, locales []string, page models.PageRequest) ([]models.Genre, models.PageInfo, error) {
 	logger := logger.GetLogger()
-	logger.Info("Fetching all genres")
+	logger.Info("Fetching all genres", zap.Int("page", page.Page), zap.Int("limit", page.Limit))
 
-	rows, err := r.db.Query(c, "select id, title from genres")
+	var total int
+	err := r.db.QueryRow(c, "select count(*) from genres where deleted_at is null").Scan(&total)
+	if err != nil {
+		logger.Error("Could not count genres", zap.Error(err))
+		return nil, models.PageInfo{}, err
+	}
+
+	keys := []sortKey{{expr: "%[1]s.id"}}
+	sql := fmt.Sprintf("select g.id, %s from genres g", translated(models.EntityGenre, "g", "title"))
+	where := "where g.deleted_at is null"
+	params := pgx.NamedArgs{"locales": locales, "limit": page.Limit + 1, "offset": page.Offset()}
+	reverse := false
+	if page.Cursor != nil {
+		reverse = page.Cursor.Before != 0
+		sql = fmt.Sprintf("%s join genres cur on cur.id = @cursorId", sql)
+		where = fmt.Sprintf("%s and %s", where, keysetCondition(keys, "g", "cur", reverse))
+		params["cursorId"] = cursorId(page)
+		params["offset"] = 0
+	}
+	sql = fmt.Sprintf("%s %s order by %s limit @limit offset @offset", sql, where, orderBy(keys, "g", reverse))
+
+	rows, err := r.db.Query(c, sql, params)
 	if err != nil {
 		logger.Error("Could not fetch genres", zap.Error(err))
-		return nil, err
+		return nil, models.PageInfo{}, err
 	}
 	defer rows.Close()
 
@@ -77,38 +117,68 @@ func (r *GenresRepository) FindAll(c context.Context) ([]models.Genre, error) {
 		err := rows.Scan(&genre.Id, &genre.Title)
 		if err != nil {
 			logger.Error("Could not scan genre", zap.Error(err))
-			return nil, err
+			return nil, models.PageInfo{}, err
 		}
 		genres = append(genres, genre)
 	}
 
-	logger.Info("Successfully fetched all genres", zap.Int("count", len(genres)))
-	return genres, nil
+	if err = rows.Err(); err != nil {
+		logger.Error("Error iterating over genres", zap.Error(err))
+		return nil, models.PageInfo{}, err
+	}
+
+	genres, info := pageInfo(genres, page, total, func(g models.Genre) int { return g.Id })
+
+	logger.Info("Successfully fetched all genres", zap.Int("count", len(genres)), zap.Int("total", total))
+	return genres, info, nil
 }
 
-func (r *GenresRepository) Create(c context.Context, genre models.Genre) (int, error) {
+// Create, Update, RestoreRevision and Delete record a revision of the genre,
+// made by the user with userId.
+func (r *GenresRepository) Create(c context.Context, genre models.Genre, userId int) (int, error) {
 	logger := logger.GetLogger()
 	logger.Info("Creating new genre", zap.String("title", genre.Title))
 
+	tx, err := r.db.Begin(c)
+	if err != nil {
+		logger.Error("Could not begin transaction", zap.Error(err))
+		return 0, err
+	}
+	defer tx.Rollback(c)
+
 	var id int
-	row := r.db.QueryRow(c, "insert into genres (title) values ($1) returning id", genre.Title)
-	err := row.Scan(&id)
+	row := tx.QueryRow(c, "insert into genres (title) values ($1) returning id", genre.Title)
+	err = row.Scan(&id)
 	if err != nil {
 		logger.Error("Could not create genre", zap.Error(err))
 		return 0, err
 	}
 
+	err = recordRevision(c, tx, models.EntityGenre, id, userId, models.RevisionCreate)
+	if err != nil {
+		return 0, err
+	}
+
+	err = tx.Commit(c)
+	if err != nil {
+		logger.Error("Could not commit transaction", zap.Error(err))
+		return 0, err
+	}
+
 	logger.Info("Successfully created genre", zap.Int("genre_id", id))
 	return id, nil
 }
 
-func (r *GenresRepository) Update(c context.Context, id int, genre models.Genre) error {
+// Update changes the genre if it still has genre.Version, or regardless of
+// its version when genre.Version is 0.
+func (r *GenresRepository) Update(c context.Context, id int, genre models.Genre, userId int) error {
 	logger := logger.GetLogger()
 	logger.Info("Updating genre", zap.Int("genre_id", id), zap.String("title", genre.Title))
 
-	_, err := r.db.Exec(c, "update genres set title=$1 where id=$2", genre.Title, id)
+	err := r.change(c, id, genre.Version, userId, models.RevisionUpdate,
+		"update genres set title = @title, version = version + 1",
+		pgx.NamedArgs{"title": genre.Title}, nil)
 	if err != nil {
-		logger.Error("Could not update genre", zap.Error(err))
 		return err
 	}
 
@@ -116,16 +186,124 @@ func (r *GenresRepository) Update(c context.Context, id int, genre models.Genre)
 	return nil
 }
 
-func (r *GenresRepository) Delete(c context.Context, id int) error {
+// genreSnapshot is the content of a genre revision snapshot.
+type genreSnapshot struct {
+	Title        string
+	Translations map[string]models.Translation
+}
+
+// RestoreRevision sets the title and translations of the genre back to the
+// ones of a revision, if the genre still has the version, or regardless of its
+// version when version is 0. Revisions from before translations existed keep
+// the current translations.
+func (r *GenresRepository) RestoreRevision(c context.Context, id int, revisionId int, version int, userId int) error {
+	logger := logger.GetLogger()
+	logger.Info("Restoring genre revision", zap.Int("genre_id", id), zap.Int("revision_id", revisionId))
+
+	var snapshot genreSnapshot
+	err := findRevisionSnapshot(c, r.db, models.EntityGenre, id, revisionId, &snapshot)
+	if err != nil {
+		logger.Error("Could not fetch revision", zap.Error(err))
+		return err
+	}
+
+	err = r.change(c, id, version, userId, models.RevisionRestore,
+		"update genres set title = @title, version = version + 1",
+		pgx.NamedArgs{"title": snapshot.Title},
+		func(tx pgx.Tx) error {
+			return restoreTranslations(c, tx, models.EntityGenre, id, snapshot.Translations)
+		})
+	if err != nil {
+		return err
+	}
+
+	logger.Info("Successfully restored genre revision", zap.Int("genre_id", id), zap.Int("revision_id", revisionId))
+	return nil
+}
+
+// Delete moves the genre to the trash if it still has the version, or
+// regardless of its version when version is 0. Movies keep the genre and get
+// it back when it is restored. The only genre of a movie can't be deleted.
+func (r *GenresRepository) Delete(c context.Context, id int, version int, userId int) error {
 	logger := logger.GetLogger()
 	logger.Info("Deleting genre", zap.Int("genre_id", id))
 
-	_, err := r.db.Exec(c, "delete from genres where id=$1", id)
+	var inUse bool
+	err := r.db.QueryRow(
+		c,
+		`
+select exists(
+	select 1
+	from movies_genres mg
+	join movies m on m.id = mg.movie_id and m.deleted_at is null
+	where mg.genre_id = $1
+	and not exists(
+		select 1
+		from movies_genres other
+		join genres g on g.id = other.genre_id and g.deleted_at is null
+		where other.movie_id = mg.movie_id and other.genre_id <> mg.genre_id
+	)
+)
+		`,
+		id).Scan(&inUse)
+	if err != nil {
+		logger.Error("Could not check genre movies", zap.Error(err))
+		return err
+	}
+	if inUse {
+		logger.Info("Genre is the only genre of a movie", zap.Int("genre_id", id))
+		return ErrGenreInUse
+	}
+
+	err = r.change(c, id, version, userId, models.RevisionDelete, "update genres set deleted_at = now(), version = version + 1", pgx.NamedArgs{}, nil)
 	if err != nil {
-		logger.Error("Could not delete genre", zap.Error(err))
 		return err
 	}
 
 	logger.Info("Successfully deleted genre", zap.Int("genre_id", id))
 	return nil
 }
+
+// change runs a statement on the genre with the id, if it is not in the
+// trash and still has the version, then runs apply unless it is nil, and
+// records the revision.
+func (r *GenresRepository) change(c context.Context, id int, version int, userId int, action string, statement string, params pgx.NamedArgs, apply func(tx pgx.Tx) error) error {
+	logger := logger.GetLogger()
+
+	tx, err := r.db.Begin(c)
+	if err != nil {
+		logger.Error("Could not begin transaction", zap.Error(err))
+		return err
+	}
+	defer tx.Rollback(c)
+
+	params["id"] = id
+	params["version"] = version
+	tag, err := tx.Exec(c, fmt.Sprintf("%s where id = @id and deleted_at is null and %s", statement, versionCondition), params)
+	if err != nil {
+		logger.Error("Could not change genre", zap.String("action", action), zap.Error(err))
+		return err
+	}
+	if err = checkVersion(tag, version); err != nil {
+		logger.Info("Genre version changed", zap.Int("genre_id", id), zap.Int("version", version))
+		return err
+	}
+
+	if apply != nil {
+		if err = apply(tx); err != nil {
+			return err
+		}
+	}
+
+	err = recordRevision(c, tx, models.EntityGenre, id, userId, action)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit(c)
+	if err != nil {
+		logger.Error("Could not commit transaction", zap.Error(err))
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"goozinshe/logger"
+	"goozinshe/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+type HistoryRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewHistoryRepository(conn *pgxpool.Pool) *HistoryRepository {
+	return &HistoryRepository{db: conn}
+}
+
+// FindAll returns a page of the user's watch events, newest first, with movie
+// titles in the first of locales they are translated to.
+func (r *HistoryRepository) FindAll(c context.Context, userId int, locales []string, page int, limit int) ([]models.WatchEvent, error) {
+	logger := logger.GetLogger()
+	logger.Info("Fetching watch history", zap.Int("user_id", userId), zap.Int("page", page), zap.Int("limit", limit))
+
+	sql := fmt.Sprintf(`
+select
+e.id,
+e.movie_id,
+%s,
+m.poster_url,
+e.watched_at,
+e.is_rewatch
+from (
+	select
+	we.id,
+	we.movie_id,
+	we.watched_at,
+	row_number() over (partition by we.movie_id order by we.watched_at, we.id) > 1 as is_rewatch
+	from watch_events we
+	where we.user_id = @userId
+) e
+join movies m on m.id = e.movie_id and m.deleted_at is null
+order by e.watched_at desc, e.id desc
+limit @limit offset @offset
+	`, translated(models.EntityMovie, "m", "title"))
+
+	rows, err := r.db.Query(c, sql, pgx.NamedArgs{
+		"userId":  userId,
+		"locales": locales,
+		"limit":   limit,
+		"offset":  (page - 1) * limit,
+	})
+	if err != nil {
+		logger.Error("Could not fetch watch history", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := make([]models.WatchEvent, 0)
+	for rows.Next() {
+		var e models.WatchEvent
+		err := rows.Scan(&e.Id, &e.MovieId, &e.MovieTitle, &e.PosterUrl, &e.WatchedAt, &e.IsRewatch)
+		if err != nil {
+			logger.Error("Could not scan watch event", zap.Error(err))
+			return nil, err
+		}
+		events = append(events, e)
+	}
+
+	if err = rows.Err(); err != nil {
+		logger.Error("Error iterating over watch history", zap.Error(err))
+		return nil, err
+	}
+
+	logger.Info("Successfully fetched watch history", zap.Int("user_id", userId), zap.Int("count", len(events)))
+	return events, nil
+}
@@ -0,0 +1,173 @@
+package repositories
+
+import (
+	"context"
+	"goozinshe/logger"
+	"goozinshe/models"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+type ImagesRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewImagesRepository(conn *pgxpool.Pool) *ImagesRepository {
+	return &ImagesRepository{db: conn}
+}
+
+// Register tracks an image and reports whether it is new. Registering an
+// image again keeps it from being swept.
+func (r *ImagesRepository) Register(c context.Context, image models.Image) (bool, error) {
+	logger := logger.GetLogger()
+	logger.Info("Registering image", zap.String("image_id", image.Id))
+
+	var created bool
+	err := r.db.QueryRow(
+		c,
+		`
+insert into images(id, content_type, size)
+values($1, $2, $3)
+on conflict (id) do update set orphaned_at = null
+returning xmax = 0
+		`,
+		image.Id,
+		image.ContentType,
+		image.Size).Scan(&created)
+	if err != nil {
+		logger.Error("Could not register image", zap.Error(err))
+		return false, err
+	}
+
+	logger.Info("Successfully registered image", zap.String("image_id", image.Id), zap.Bool("created", created))
+	return created, nil
+}
+
+// FindExpired registers stored files that aren't tracked yet, updates which
+// images are orphaned and returns the images that have been orphaned for
+// longer than grace. With dryRun none of the changes are kept.
+func (r *ImagesRepository) FindExpired(c context.Context, stored []models.Image, grace time.Duration, dryRun bool) ([]models.Image, error) {
+	logger := logger.GetLogger()
+	logger.Info("Looking for expired images", zap.Int("stored", len(stored)), zap.Duration("grace", grace), zap.Bool("dry_run", dryRun))
+
+	tx, err := r.db.Begin(c)
+	if err != nil {
+		logger.Error("Could not begin transaction", zap.Error(err))
+		return nil, err
+	}
+	defer tx.Rollback(c)
+
+	ids := make([]string, 0, len(stored))
+	createdAt := make([]time.Time, 0, len(stored))
+	sizes := make([]int64, 0, len(stored))
+	for _, image := range stored {
+		ids = append(ids, image.Id)
+		createdAt = append(createdAt, image.CreatedAt)
+		sizes = append(sizes, image.Size)
+	}
+
+	_, err = tx.Exec(
+		c,
+		`
+insert into images(id, created_at, size)
+select * from unnest($1::text[], $2::timestamp[], $3::bigint[])
+on conflict (id) do nothing
+		`,
+		ids,
+		createdAt,
+		sizes)
+	if err != nil {
+		logger.Error("Could not register untracked images", zap.Error(err))
+		return nil, err
+	}
+
+	_, err = tx.Exec(c, `
+update images i
+set orphaned_at = case when exists(select 1 from movies m where m.poster_url = i.id) then null else now() end
+where (i.orphaned_at is null) = not exists(select 1 from movies m where m.poster_url = i.id)
+	`)
+	if err != nil {
+		logger.Error("Could not mark orphaned images", zap.Error(err))
+		return nil, err
+	}
+
+	rows, err := tx.Query(
+		c,
+		"select id, content_type, size, created_at, orphaned_at from images where orphaned_at < now() - $1::interval order by orphaned_at, id",
+		grace)
+	if err != nil {
+		logger.Error("Could not fetch expired images", zap.Error(err))
+		return nil, err
+	}
+
+	expired, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.Image, error) {
+		var image models.Image
+		err := row.Scan(&image.Id, &image.ContentType, &image.Size, &image.CreatedAt, &image.OrphanedAt)
+		return image, err
+	})
+	if err != nil {
+		logger.Error("Could not scan expired images", zap.Error(err))
+		return nil, err
+	}
+
+	if !dryRun {
+		if err := tx.Commit(c); err != nil {
+			logger.Error("Could not commit transaction", zap.Error(err))
+			return nil, err
+		}
+	}
+
+	logger.Info("Found expired images", zap.Int("count", len(expired)))
+	return expired, nil
+}
+
+// DeleteOrphaned deletes the rows of the images that are still orphaned and
+// returns their ids. Images that got referenced again are kept. deleteFiles is
+// called for every deleted row before the deletion commits, so the files are
+// gone while the rows are still locked: an upload of the same poster meanwhile
+// waits and then stores it again, rather than reusing files that are about to
+// be deleted.
+func (r *ImagesRepository) DeleteOrphaned(c context.Context, ids []string, deleteFiles func(id string)) ([]string, error) {
+	logger := logger.GetLogger()
+	logger.Info("Deleting orphaned images", zap.Int("count", len(ids)))
+
+	tx, err := r.db.Begin(c)
+	if err != nil {
+		logger.Error("Could not begin transaction", zap.Error(err))
+		return nil, err
+	}
+	defer tx.Rollback(c)
+
+	rows, err := tx.Query(c, `
+delete from images i
+where i.id = any($1)
+  and i.orphaned_at is not null
+  and not exists(select 1 from movies m where m.poster_url = i.id)
+returning i.id
+	`, ids)
+	if err != nil {
+		logger.Error("Could not delete orphaned images", zap.Error(err))
+		return nil, err
+	}
+
+	deleted, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		logger.Error("Could not delete orphaned images", zap.Error(err))
+		return nil, err
+	}
+
+	for _, id := range deleted {
+		deleteFiles(id)
+	}
+
+	if err := tx.Commit(c); err != nil {
+		logger.Error("Could not commit transaction", zap.Error(err))
+		return nil, err
+	}
+
+	logger.Info("Successfully deleted orphaned images", zap.Int("count", len(deleted)))
+	return deleted, nil
+}
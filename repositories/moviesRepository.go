@@ -2,12 +2,14 @@ package repositories
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"goozinshe/logger"
 	"goozinshe/models"
-	"strconv"
+	"strings"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"go.uber.org/zap"
 )
@@ -20,147 +22,336 @@ func NewMoviesRepository(conn *pgxpool.Pool) *MoviesRepository {
 	return &MoviesRepository{db: conn}
 }
 
-func (r *MoviesRepository) FindById(c context.Context, id int) (models.Movie, error) {
-	sql :=
-		`
-select 
+// creditOrder orders the credits of a movie by role and then by billing order.
+// It must be used with the movie_credits alias mc.
+const creditOrder = "array_position(array['director', 'writer', 'composer', 'actor'], mc.role), mc.billing_order"
+
+// movieDirectors is the director string of the movie m, the names of its
+// directors in billing order.
+const movieDirectors = `coalesce((
+	select string_agg(p.name, ', ' order by mc.billing_order)
+	from movie_credits mc
+	join people p on p.id = mc.person_id
+	where mc.movie_id = m.id and mc.role = 'director'
+), '')`
+
+// movieColumns selects a movie together with the caller's own rating, watched
+// state, the aggregated rating of all users, the caller's progress through the
+// episodes of a series, its genres and its credits. Titles and descriptions
+// are translated to the first of @locales they are translated to.
+// Genres and credits are aggregated into JSON arrays, so every movie is a
+// single row, also when it has no genres. It must be used with movieUserJoins
+// and scanned with scanMovie.
+var movieColumns = `
 m.id,
-m.title,
-m.description,
+m.kind,
+` + translated(models.EntityMovie, "m", "title") + `,
+` + translated(models.EntityMovie, "m", "description") + `,
 m.release_year,
 m.director,
-m.rating,
-m.is_watched,
+coalesce(ur.rating, 0),
+coalesce(rs.average, 0),
+rs.total,
+rs.r1,
+rs.r2,
+rs.r3,
+rs.r4,
+rs.r5,
+uw.movie_id is not null,
 m.trailer_url,
 m.poster_url,
-g.id,
-g.title
+m.created_at,
+m.version,
+(
+	select count(*)
+	from seasons s
+	join episodes e on e.season_id = s.id
+	where s.series_id = m.id
+),
+(
+	select count(*)
+	from seasons s
+	join episodes e on e.season_id = s.id
+	join user_watched_episodes we on we.episode_id = e.id and we.user_id = @userId
+	where s.series_id = m.id
+),
+coalesce((
+	select json_agg(json_build_object('Id', g.id, 'Title', ` + translated(models.EntityGenre, "g", "title") + `) order by g.id)
+	from movies_genres mg
+	join genres g on g.id = mg.genre_id and g.deleted_at is null
+	where mg.movie_id = m.id
+), '[]'),
+coalesce((
+	select json_agg(json_build_object(
+		'PersonId', p.id,
+		'Name', p.name,
+		'Role', mc.role,
+		'Character', mc.character,
+		'BillingOrder', mc.billing_order
+	) order by ` + creditOrder + `)
+	from movie_credits mc
+	join people p on p.id = mc.person_id
+	where mc.movie_id = m.id
+), '[]')`
+
+const movieWatchedJoin = `
+left join user_watched_movies uw on uw.movie_id = m.id and uw.user_id = @userId`
+
+const movieUserJoins = movieWatchedJoin + `
+left join user_movie_ratings ur on ur.movie_id = m.id and ur.user_id = @userId
+left join lateral (
+	select
+	avg(r.rating)::float8 as average,
+	count(*) as total,
+	count(*) filter (where r.rating = 1) as r1,
+	count(*) filter (where r.rating = 2) as r2,
+	count(*) filter (where r.rating = 3) as r3,
+	count(*) filter (where r.rating = 4) as r4,
+	count(*) filter (where r.rating = 5) as r5
+	from user_movie_ratings r
+	where r.movie_id = m.id
+) rs on true`
+
+func scanMovie(row pgx.Row, m *models.Movie, extra ...any) error {
+	var r1, r2, r3, r4, r5 int
+
+	dest := []any{
+		&m.Id,
+		&m.Kind,
+		&m.Title,
+		&m.Description,
+		&m.ReleaseYear,
+		&m.Director,
+		&m.Rating,
+		&m.AverageRating,
+		&m.RatingsCount,
+		&r1,
+		&r2,
+		&r3,
+		&r4,
+		&r5,
+		&m.IsWatched,
+		&m.TrailerUrl,
+		&m.PosterUrl,
+		&m.CreatedAt,
+		&m.Version,
+		&m.EpisodesCount,
+		&m.WatchedEpisodesCount,
+		&m.Genres,
+		&m.Credits,
+	}
+
+	err := row.Scan(append(dest, extra...)...)
+	if err != nil {
+		return err
+	}
+
+	m.RatingDistribution = map[int]int{1: r1, 2: r2, 3: r3, 4: r4, 5: r5}
+	return nil
+}
+
+// FindById returns the movie with the id, or ErrMovieNotFound when there is
+// none or it is in the trash. Its texts are in the first of locales they are
+// translated to.
+func (r *MoviesRepository) FindById(c context.Context, id int, userId int, locales []string) (models.Movie, error) {
+	sql := fmt.Sprintf(`
+select %s
 from movies m
-join movies_genres mg on mg.movie_id = m.id
-join genres g on mg.genre_id  = g.id
-where m.id = $1
-	`
+%s
+where m.id = @id and m.deleted_at is null
+	`, movieColumns, movieUserJoins)
 
 	logger := logger.GetLogger()
 
-	rows, err := r.db.Query(c, sql, id)
-	defer rows.Close()
+	var movie models.Movie
+	err := scanMovie(r.db.QueryRow(c, sql, pgx.NamedArgs{"id": id, "userId": userId, "locales": locales}), &movie)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.Movie{}, ErrMovieNotFound
+	}
 	if err != nil {
 		logger.Error("Could not query database", zap.String("db_msg", err.Error()))
 		return models.Movie{}, err
 	}
 
-	var movie *models.Movie
+	return movie, nil
+}
 
-	for rows.Next() {
-		var m models.Movie
-		var g models.Genre
-
-		err := rows.Scan(
-			&m.Id,
-			&m.Title,
-			&m.Description,
-			&m.ReleaseYear,
-			&m.Director,
-			&m.Rating,
-			&m.IsWatched,
-			&m.TrailerUrl,
-			&m.PosterUrl,
-			&g.Id,
-			&g.Title,
-		)
-		if err != nil {
-			logger.Error("Could not scan query row", zap.String("db_msg", err.Error()))
-			return models.Movie{}, err
-		}
+// movieSortExprs maps models.MovieSortKeys to the expressions movies are
+// sorted by. Titles are sorted as they are shown, in the first of @locales
+// they are translated to. Rating, averageRating and addedToWatchlistAt are null
+// for movies that aren't rated or in the default watchlist, and sort last.
+var movieSortExprs = map[string]string{
+	"title":         translated(models.EntityMovie, "%[1]s", "title"),
+	"releaseYear":   "%[1]s.release_year",
+	"rating":        "(select r.rating from user_movie_ratings r where r.movie_id = %[1]s.id and r.user_id = @userId)",
+	"averageRating": "(select avg(r.rating) from user_movie_ratings r where r.movie_id = %[1]s.id)",
+	"createdAt":     "%[1]s.created_at",
+	"addedToWatchlistAt": `(
+		select wi.added_at
+		from watchlist_items wi
+		join watchlists w on w.id = wi.watchlist_id
+		where w.user_id = @userId and w.is_default and wi.movie_id = %[1]s.id
+	)`,
+}
 
-		if movie != nil {
-			m = *movie
+// FindAll returns a page of movies matching the filters. Movies are ordered by
+// the sort keys and then by id, so that pages are stable. Their texts are in
+// the first of locales they are translated to, the search term matches them in
+// any locale.
+func (r *MoviesRepository) FindAll(c context.Context, userId int, locales []string, filters models.MovieFilters, page models.PageRequest) ([]models.Movie, models.PageInfo, error) {
+	logger := logger.GetLogger()
+
+	where := "where m.deleted_at is null"
+	params := pgx.NamedArgs{"userId": userId, "locales": locales}
+
+	if filters.SearchTerm != "" {
+		where = fmt.Sprintf(`%s and (
+	m.title ilike @s
+	or m.search_vector @@ websearch_to_tsquery('simple', @searchTerm)
+	or exists(
+		select 1
+		from movie_translations mt
+		where mt.movie_id = m.id and (mt.title ilike @s or mt.search_vector @@ websearch_to_tsquery('simple', @searchTerm))
+	)
+)`, where)
+		params["s"] = fmt.Sprintf("%%%s%%", escapeLike(filters.SearchTerm))
+		params["searchTerm"] = filters.SearchTerm
+	}
+
+	if len(filters.GenreIds) > 0 {
+		if filters.GenreMatch == models.GenreMatchAll {
+			where = fmt.Sprintf("%s and (select count(*) from movies_genres fg where fg.movie_id = m.id and fg.genre_id = any(@genreIds)) = @genreCount", where)
+			params["genreCount"] = len(filters.GenreIds)
+		} else {
+			where = fmt.Sprintf("%s and exists(select 1 from movies_genres fg where fg.movie_id = m.id and fg.genre_id = any(@genreIds))", where)
 		}
+		params["genreIds"] = filters.GenreIds
+	}
 
-		m.Genres = append(m.Genres, g)
-		movie = &m
+	if filters.Kind != "" {
+		where = fmt.Sprintf("%s and m.kind = @kind", where)
+		params["kind"] = filters.Kind
 	}
 
-	err = rows.Err()
-	if err != nil {
-		logger.Error(err.Error())
-		return models.Movie{}, err
+	if filters.YearFrom != 0 {
+		where = fmt.Sprintf("%s and m.release_year >= @yearFrom", where)
+		params["yearFrom"] = filters.YearFrom
 	}
 
-	return *movie, nil
-}
+	if filters.YearTo != 0 {
+		where = fmt.Sprintf("%s and m.release_year <= @yearTo", where)
+		params["yearTo"] = filters.YearTo
+	}
 
-func (r *MoviesRepository) FindAll(c context.Context, filters models.MovieFilters) ([]models.Movie, error) {
-	logger := logger.GetLogger()
+	if filters.Director != "" {
+		where = fmt.Sprintf("%s and m.director ilike @director", where)
+		params["director"] = fmt.Sprintf("%%%s%%", escapeLike(filters.Director))
+	}
 
-	sql := `select m.id, m.title, m.description, m.release_year, m.director, m.rating, m.is_watched, m.trailer_url, m.poster_url, g.id, g.title from movies m join movies_genres mg on mg.movie_id = m.id join genres g on mg.genre_id  = g.id where 1=1`
-	params := pgx.NamedArgs{}
+	if filters.PersonId != 0 {
+		credit := "mc.person_id = @personId"
+		if filters.PersonRole != "" {
+			credit = fmt.Sprintf("%s and mc.role = @personRole", credit)
+			params["personRole"] = filters.PersonRole
+		}
+		where = fmt.Sprintf("%s and exists(select 1 from movie_credits mc where mc.movie_id = m.id and %s)", where, credit)
+		params["personId"] = filters.PersonId
+	}
 
-	if filters.SearchTerm != "" {
-		sql = fmt.Sprintf("%s and m.title ilike @s", sql)
-		params["s"] = fmt.Sprintf("%%%s%%", filters.SearchTerm)
+	if filters.MinRating > 0 {
+		where = fmt.Sprintf("%s and %s >= @minRating", where, sortKey{expr: movieSortExprs["averageRating"]}.on("m"))
+		params["minRating"] = filters.MinRating
+	}
+
+	if filters.HasTrailer != nil {
+		where = fmt.Sprintf("%s and (coalesce(m.trailer_url, '') <> '') = @hasTrailer", where)
+		params["hasTrailer"] = *filters.HasTrailer
+	}
+
+	if filters.IsWatched != nil {
+		where = fmt.Sprintf("%s and (uw.movie_id is not null) = @isWatched", where)
+		params["isWatched"] = *filters.IsWatched
 	}
 
-	if filters.GenreId != "" {
-		sql = fmt.Sprintf("%s and g.id = @genreId", sql)
-		params["genreId"] = filters.GenreId
+	var total int
+	countSql := fmt.Sprintf("select count(*) from movies m %s %s", movieWatchedJoin, where)
+	err := r.db.QueryRow(c, countSql, params).Scan(&total)
+	if err != nil {
+		logger.Error("Could not count movies", zap.String("db_msg", err.Error()))
+		return nil, models.PageInfo{}, err
 	}
 
-	if filters.IsWatched != "" {
-		isWatched, _ := strconv.ParseBool(filters.IsWatched)
-		sql = fmt.Sprintf("%s and m.is_watched = @isWatched", sql)
-		params["isWatched"] = isWatched
+	keys := make([]sortKey, 0, len(filters.Sort)+1)
+	for _, field := range filters.Sort {
+		expr, ok := movieSortExprs[field.Key]
+		if !ok {
+			return nil, models.PageInfo{}, models.NewInvalidFieldError("sort", fmt.Sprintf("unsupported sort key %q", field.Key))
+		}
+		keys = append(keys, sortKey{expr: expr, desc: field.Desc})
 	}
+	keys = append(keys, sortKey{expr: "%[1]s.id"})
 
-	if filters.Sort != "" {
-		identifier := pgx.Identifier{filters.Sort}
-		sql = fmt.Sprintf("%s order by m.%s", sql, identifier.Sanitize())
+	cursorJoin := ""
+	reverse := false
+	params["limit"] = page.Limit + 1
+	params["offset"] = page.Offset()
+	if page.Cursor != nil {
+		reverse = page.Cursor.Before != 0
+		cursorJoin = "join movies cur on cur.id = @cursorId"
+		where = fmt.Sprintf("%s and %s", where, keysetCondition(keys, "m", "cur", reverse))
+		params["cursorId"] = cursorId(page)
+		params["offset"] = 0
 	}
 
+	sql := fmt.Sprintf(`
+select %s
+from (
+	select m.*
+	from movies m
+	%s
+	%s
+	%s
+	order by %s
+	limit @limit offset @offset
+) m
+%s
+order by %s
+	`, movieColumns, movieWatchedJoin, cursorJoin, where, orderBy(keys, "m", reverse), movieUserJoins, orderBy(keys, "m", reverse))
+
 	rows, err := r.db.Query(c, sql, params)
 	if err != nil {
 		logger.Error("Could not query database", zap.String("db_msg", err.Error()))
-		return nil, err
+		return nil, models.PageInfo{}, err
 	}
 	defer rows.Close()
 
-	movies := make([]*models.Movie, 0)
-	moviesMap := make(map[int]*models.Movie)
-
+	movies := make([]models.Movie, 0)
 	for rows.Next() {
 		var m models.Movie
-		var g models.Genre
 
-		err := rows.Scan(&m.Id, &m.Title, &m.Description, &m.ReleaseYear, &m.Director, &m.Rating, &m.IsWatched, &m.TrailerUrl, &m.PosterUrl, &g.Id, &g.Title)
+		err := scanMovie(rows, &m)
 		if err != nil {
 			logger.Error("Could not scan row", zap.String("db_msg", err.Error()))
-			return nil, err
-		}
-
-		if _, exists := moviesMap[m.Id]; !exists {
-			moviesMap[m.Id] = &m
-			movies = append(movies, &m)
+			return nil, models.PageInfo{}, err
 		}
-		moviesMap[m.Id].Genres = append(moviesMap[m.Id].Genres, g)
+		movies = append(movies, m)
 	}
 
 	err = rows.Err()
 	if err != nil {
 		logger.Error("Error iterating rows", zap.String("db_msg", err.Error()))
-		return nil, err
+		return nil, models.PageInfo{}, err
 	}
 
-	concreteMovies := make([]models.Movie, 0, len(movies))
-	for _, v := range movies {
-		concreteMovies = append(concreteMovies, *v)
-	}
+	movies, info := pageInfo(movies, page, total, func(m models.Movie) int { return m.Id })
 
-	logger.Info("Successfully retrieved movies", zap.Int("count", len(concreteMovies)))
-	return concreteMovies, nil
+	logger.Info("Successfully retrieved movies", zap.Int("count", len(movies)), zap.Int("total", total))
+	return movies, info, nil
 }
 
-func (r *MoviesRepository) Create(c context.Context, movie models.Movie) (int, error) {
+// Create, Update, Patch, SetPoster, RestoreRevision and Delete record a
+// revision of the movie, made by the user with userId.
+func (r *MoviesRepository) Create(c context.Context, movie models.Movie, userId int) (int, error) {
 	logger := logger.GetLogger()
 
 	tx, err := r.db.Begin(c)
@@ -168,9 +359,10 @@ func (r *MoviesRepository) Create(c context.Context, movie models.Movie) (int, e
 		logger.Error("Could not begin transaction", zap.String("db_msg", err.Error()))
 		return 0, err
 	}
+	defer tx.Rollback(c)
 
 	var id int
-	row := tx.QueryRow(c, "insert into movies(title, description, release_year, director, trailer_url, poster_url) values($1, $2, $3, $4, $5, $6) returning id", movie.Title, movie.Description, movie.ReleaseYear, movie.Director, movie.TrailerUrl, movie.PosterUrl)
+	row := tx.QueryRow(c, "insert into movies(kind, title, description, release_year, director, trailer_url, poster_url) values($1, $2, $3, $4, $5, $6, $7) returning id", movie.Kind, movie.Title, movie.Description, movie.ReleaseYear, movie.Director, movie.TrailerUrl, movie.PosterUrl)
 	err = row.Scan(&id)
 	if err != nil {
 		logger.Error("Could not insert movie", zap.String("db_msg", err.Error()))
@@ -185,6 +377,16 @@ func (r *MoviesRepository) Create(c context.Context, movie models.Movie) (int, e
 		}
 	}
 
+	err = linkDirector(c, tx, id, movie.Director)
+	if err != nil {
+		return 0, err
+	}
+
+	err = recordRevision(c, tx, models.EntityMovie, id, userId, models.RevisionCreate)
+	if err != nil {
+		return 0, err
+	}
+
 	err = tx.Commit(c)
 	if err != nil {
 		logger.Error("Could not commit transaction", zap.String("db_msg", err.Error()))
@@ -195,7 +397,22 @@ func (r *MoviesRepository) Create(c context.Context, movie models.Movie) (int, e
 	return id, nil
 }
 
-func (r *MoviesRepository) Update(c context.Context, id int, updatedMovie models.Movie) error {
+// checkMovieVersion is checkVersion for statements that only change movies
+// outside the trash. Without a version to check, a movie that wasn't changed
+// was deleted meanwhile.
+func checkMovieVersion(tag pgconn.CommandTag, version int) error {
+	if err := checkVersion(tag, version); err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrMovieNotFound
+	}
+	return nil
+}
+
+// Update replaces the movie if it still has updatedMovie.Version, or
+// regardless of its version when updatedMovie.Version is 0.
+func (r *MoviesRepository) Update(c context.Context, id int, updatedMovie models.Movie, userId int) error {
 	logger := logger.GetLogger()
 	logger.Info("Starting transaction for updating movie", zap.Int("movie_id", id))
 
@@ -205,75 +422,520 @@ func (r *MoviesRepository) Update(c context.Context, id int, updatedMovie models
 		return err
 	}
 
-	_, err = tx.Exec(
+	defer tx.Rollback(c)
+
+	tag, err := tx.Exec(
 		c,
 		`
 update movies
 set 
-title = $1,
-description = $2,
-release_year = $3,
-director = $4,
-trailer_url = $5,
-poster_url = $6
-where id = $7
-		`,
-		updatedMovie.Title,
-		updatedMovie.Description,
-		updatedMovie.ReleaseYear,
-		updatedMovie.Director,
-		updatedMovie.TrailerUrl,
-		updatedMovie.PosterUrl,
-		id)
+title = @title,
+description = @description,
+release_year = @releaseYear,
+director = @director,
+trailer_url = @trailerUrl,
+poster_url = @posterUrl,
+version = version + 1
+where id = @id and deleted_at is null and `+versionCondition,
+		pgx.NamedArgs{
+			"id":          id,
+			"title":       updatedMovie.Title,
+			"description": updatedMovie.Description,
+			"releaseYear": updatedMovie.ReleaseYear,
+			"director":    updatedMovie.Director,
+			"trailerUrl":  updatedMovie.TrailerUrl,
+			"posterUrl":   updatedMovie.PosterUrl,
+			"version":     updatedMovie.Version,
+		})
 	if err != nil {
 		logger.Error("Could not update movie", zap.Error(err))
 		return err
 	}
+	if err = checkMovieVersion(tag, updatedMovie.Version); err != nil {
+		logger.Info("Movie version changed", zap.Int("movie_id", id), zap.Int("version", updatedMovie.Version))
+		return err
+	}
 
-	_, err = tx.Exec(c, "delete from movies_genres where movie_id = $1", id)
+	genreIds := make([]int, 0, len(updatedMovie.Genres))
+	for _, genre := range updatedMovie.Genres {
+		genreIds = append(genreIds, genre.Id)
+	}
+	err = setMovieGenres(c, tx, id, genreIds)
 	if err != nil {
-		logger.Error("Could not delete movie genres", zap.Error(err))
 		return err
 	}
-	for _, genre := range updatedMovie.Genres {
-		_, err = tx.Exec(c, "insert into movies_genres(movie_id, genre_id) values($1, $2)", id, genre.Id)
+
+	err = linkDirector(c, tx, id, updatedMovie.Director)
+	if err != nil {
+		return err
+	}
+
+	err = recordRevision(c, tx, models.EntityMovie, id, userId, models.RevisionUpdate)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit(c)
+	if err != nil {
+		logger.Error("Could not commit transaction", zap.Error(err))
+		return err
+	}
+
+	logger.Info("Successfully updated movie", zap.Int("movie_id", id))
+	return nil
+}
+
+// Patch applies a partial update. Only the columns set in the patch are
+// written, and only the genres that change are inserted or deleted. Like
+// Update, it checks patch.Version unless it is 0.
+func (r *MoviesRepository) Patch(c context.Context, id int, patch models.MoviePatch, userId int) error {
+	logger := logger.GetLogger()
+	logger.Info("Starting transaction for patching movie", zap.Int("movie_id", id))
+
+	tx, err := r.db.Begin(c)
+	if err != nil {
+		logger.Error("Could not begin transaction", zap.Error(err))
+		return err
+	}
+	defer tx.Rollback(c)
+
+	columns := make([]string, 0, 6)
+	params := pgx.NamedArgs{"id": id, "version": patch.Version}
+	set := func(column string, param string, value any) {
+		columns = append(columns, fmt.Sprintf("%s = @%s", column, param))
+		params[param] = value
+	}
+	if patch.Title != nil {
+		set("title", "title", *patch.Title)
+	}
+	if patch.Description != nil {
+		set("description", "description", *patch.Description)
+	}
+	if patch.ReleaseYear != nil {
+		set("release_year", "releaseYear", *patch.ReleaseYear)
+	}
+	if patch.Director != nil {
+		set("director", "director", *patch.Director)
+	}
+	if patch.TrailerUrl != nil {
+		set("trailer_url", "trailerUrl", *patch.TrailerUrl)
+	}
+
+	// Changing only the genres is a change of the movie too.
+	columns = append(columns, "version = version + 1")
+	sql := fmt.Sprintf("update movies set %s where id = @id and deleted_at is null and %s", strings.Join(columns, ", "), versionCondition)
+	tag, err := tx.Exec(c, sql, params)
+	if err != nil {
+		logger.Error("Could not update movie", zap.Error(err))
+		return err
+	}
+	if err = checkMovieVersion(tag, patch.Version); err != nil {
+		logger.Info("Movie version changed", zap.Int("movie_id", id), zap.Int("version", patch.Version))
+		return err
+	}
+
+	if patch.GenreIds != nil {
+		err = setMovieGenres(c, tx, id, patch.GenreIds)
 		if err != nil {
-			logger.Error("Could not insert movie genre", zap.Error(err))
 			return err
 		}
 	}
 
+	if len(patch.AddGenreIds) > 0 {
+		err = addMovieGenres(c, tx, id, patch.AddGenreIds)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(patch.RemoveGenreIds) > 0 {
+		_, err = tx.Exec(c, "delete from movies_genres where movie_id = $1 and genre_id = any($2)", id, patch.RemoveGenreIds)
+		if err != nil {
+			logger.Error("Could not delete movie genres", zap.Error(err))
+			return err
+		}
+	}
+
+	if patch.Director != nil {
+		err = linkDirector(c, tx, id, *patch.Director)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = recordRevision(c, tx, models.EntityMovie, id, userId, models.RevisionUpdate)
+	if err != nil {
+		return err
+	}
+
 	err = tx.Commit(c)
 	if err != nil {
 		logger.Error("Could not commit transaction", zap.Error(err))
 		return err
 	}
 
-	logger.Info("Successfully updated movie", zap.Int("movie_id", id))
+	logger.Info("Successfully patched movie", zap.Int("movie_id", id), zap.Strings("columns", columns))
 	return nil
 }
 
-func (r *MoviesRepository) Delete(c context.Context, id int) error {
+// SetPoster replaces the poster of a movie if it still has the version, or
+// regardless of its version when version is 0.
+func (r *MoviesRepository) SetPoster(c context.Context, id int, posterUrl string, version int, userId int) error {
 	logger := logger.GetLogger()
-	logger.Info("Starting transaction for deleting movie", zap.Int("movie_id", id))
+	logger.Info("Updating movie poster", zap.Int("movie_id", id), zap.String("poster_url", posterUrl))
+
+	tx, err := r.db.Begin(c)
+	if err != nil {
+		logger.Error("Could not begin transaction", zap.Error(err))
+		return err
+	}
+	defer tx.Rollback(c)
+
+	tag, err := tx.Exec(
+		c,
+		"update movies set poster_url = @posterUrl, version = version + 1 where id = @id and deleted_at is null and "+versionCondition,
+		pgx.NamedArgs{"id": id, "posterUrl": posterUrl, "version": version})
+	if err != nil {
+		logger.Error("Could not update movie poster", zap.Error(err))
+		return err
+	}
+	if err = checkMovieVersion(tag, version); err != nil {
+		logger.Info("Movie version changed", zap.Int("movie_id", id), zap.Int("version", version))
+		return err
+	}
+
+	err = recordRevision(c, tx, models.EntityMovie, id, userId, models.RevisionUpdate)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit(c)
+	if err != nil {
+		logger.Error("Could not commit transaction", zap.Error(err))
+		return err
+	}
+
+	logger.Info("Successfully updated movie poster", zap.Int("movie_id", id))
+	return nil
+}
+
+// movieSnapshot is the content of a movie revision snapshot.
+type movieSnapshot struct {
+	Title        string
+	Description  string
+	ReleaseYear  int
+	Director     string
+	TrailerUrl   string
+	PosterUrl    string
+	GenreIds     []int
+	Credits      []models.Credit
+	Translations map[string]models.Translation
+}
+
+// RestoreRevision rolls the movie back to a revision, if it still has the
+// version, or regardless of its version when version is 0. Posters, genres and
+// people that were deleted since are not restored: the movie keeps its current
+// poster, and loses the deleted genres and credits. Revisions from before
+// credits existed only restore the director, revisions from before
+// translations existed keep the current translations.
+func (r *MoviesRepository) RestoreRevision(c context.Context, id int, revisionId int, version int, userId int) error {
+	logger := logger.GetLogger()
+	logger.Info("Restoring movie revision", zap.Int("movie_id", id), zap.Int("revision_id", revisionId))
 
 	tx, err := r.db.Begin(c)
 	if err != nil {
 		logger.Error("Could not begin transaction", zap.Error(err))
 		return err
 	}
+	defer tx.Rollback(c)
+
+	var snapshot movieSnapshot
+	err = findRevisionSnapshot(c, tx, models.EntityMovie, id, revisionId, &snapshot)
+	if err != nil {
+		logger.Error("Could not fetch revision", zap.Error(err))
+		return err
+	}
 
-	_, err = tx.Exec(c, "delete from movies_genres where movie_id = $1", id)
+	tag, err := tx.Exec(
+		c,
+		`
+update movies
+set
+title = @title,
+description = @description,
+release_year = @releaseYear,
+director = @director,
+trailer_url = @trailerUrl,
+poster_url = coalesce((select i.id from images i where i.id = @posterUrl), poster_url),
+version = version + 1
+where id = @id and deleted_at is null and `+versionCondition,
+		pgx.NamedArgs{
+			"id":          id,
+			"title":       snapshot.Title,
+			"description": snapshot.Description,
+			"releaseYear": snapshot.ReleaseYear,
+			"director":    snapshot.Director,
+			"trailerUrl":  snapshot.TrailerUrl,
+			"posterUrl":   snapshot.PosterUrl,
+			"version":     version,
+		})
+	if err != nil {
+		logger.Error("Could not restore movie", zap.Error(err))
+		return err
+	}
+	if err = checkMovieVersion(tag, version); err != nil {
+		logger.Info("Movie version changed", zap.Int("movie_id", id), zap.Int("version", version))
+		return err
+	}
+
+	var genreIds []int
+	err = tx.QueryRow(c, "select coalesce(array_agg(id), '{}') from genres where id = any($1) and deleted_at is null", snapshot.GenreIds).Scan(&genreIds)
+	if err != nil {
+		logger.Error("Could not fetch revision genres", zap.Error(err))
+		return err
+	}
+	err = setMovieGenres(c, tx, id, genreIds)
+	if err != nil {
+		return err
+	}
+
+	if snapshot.Credits != nil {
+		err = setMovieCredits(c, tx, id, snapshot.Credits)
+	} else {
+		err = linkDirector(c, tx, id, snapshot.Director)
+	}
+	if err != nil {
+		return err
+	}
+
+	err = restoreTranslations(c, tx, models.EntityMovie, id, snapshot.Translations)
+	if err != nil {
+		return err
+	}
+
+	err = recordRevision(c, tx, models.EntityMovie, id, userId, models.RevisionRestore)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit(c)
+	if err != nil {
+		logger.Error("Could not commit transaction", zap.Error(err))
+		return err
+	}
+
+	logger.Info("Successfully restored movie revision", zap.Int("movie_id", id), zap.Int("revision_id", revisionId))
+	return nil
+}
+
+// setMovieGenres makes genreIds the genres of a movie, keeping the rows of
+// genres the movie already has.
+func setMovieGenres(c context.Context, tx pgx.Tx, id int, genreIds []int) error {
+	logger := logger.GetLogger()
+
+	_, err := tx.Exec(c, "delete from movies_genres where movie_id = $1 and genre_id <> all($2)", id, genreIds)
 	if err != nil {
 		logger.Error("Could not delete movie genres", zap.Error(err))
 		return err
 	}
 
-	_, err = tx.Exec(c, "delete from movies where id = $1", id)
+	return addMovieGenres(c, tx, id, genreIds)
+}
+
+func addMovieGenres(c context.Context, tx pgx.Tx, id int, genreIds []int) error {
+	_, err := tx.Exec(
+		c,
+		`
+insert into movies_genres(movie_id, genre_id)
+select $1, unnest($2::int[])
+on conflict do nothing
+		`,
+		id,
+		genreIds)
+	if err != nil {
+		logger.GetLogger().Error("Could not insert movie genres", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// SetCredits replaces the credits of the movie if it still has the version, or
+// regardless of its version when version is 0. Credits are billed in the order
+// they are given within their role.
+func (r *MoviesRepository) SetCredits(c context.Context, id int, credits []models.Credit, version int, userId int) error {
+	logger := logger.GetLogger()
+	logger.Info("Updating movie credits", zap.Int("movie_id", id), zap.Int("count", len(credits)))
+
+	tx, err := r.db.Begin(c)
+	if err != nil {
+		logger.Error("Could not begin transaction", zap.Error(err))
+		return err
+	}
+	defer tx.Rollback(c)
+
+	tag, err := tx.Exec(
+		c,
+		"update movies set version = version + 1 where id = @id and deleted_at is null and "+versionCondition,
+		pgx.NamedArgs{"id": id, "version": version})
+	if err != nil {
+		logger.Error("Could not update movie", zap.Error(err))
+		return err
+	}
+	if err = checkMovieVersion(tag, version); err != nil {
+		logger.Info("Movie version changed", zap.Int("movie_id", id), zap.Int("version", version))
+		return err
+	}
+
+	err = setMovieCredits(c, tx, id, credits)
+	if err != nil {
+		return err
+	}
+
+	err = recordRevision(c, tx, models.EntityMovie, id, userId, models.RevisionUpdate)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit(c)
+	if err != nil {
+		logger.Error("Could not commit transaction", zap.Error(err))
+		return err
+	}
+
+	logger.Info("Successfully updated movie credits", zap.Int("movie_id", id))
+	return nil
+}
+
+// setMovieCredits replaces the credits of a movie, numbering the billing
+// order within each role in the order of credits. Credits of people that
+// don't exist are skipped.
+func setMovieCredits(c context.Context, tx pgx.Tx, id int, credits []models.Credit) error {
+	logger := logger.GetLogger()
+
+	personIds := make([]int, 0, len(credits))
+	roles := make([]string, 0, len(credits))
+	characters := make([]string, 0, len(credits))
+	for _, credit := range credits {
+		personIds = append(personIds, credit.PersonId)
+		roles = append(roles, credit.Role)
+		characters = append(characters, credit.Character)
+	}
+
+	_, err := tx.Exec(c, "delete from movie_credits where movie_id = $1", id)
+	if err != nil {
+		logger.Error("Could not delete movie credits", zap.Error(err))
+		return err
+	}
+
+	_, err = tx.Exec(
+		c,
+		`
+insert into movie_credits(movie_id, person_id, role, character, billing_order)
+select $1, t.person_id, t.role, t.character, row_number() over (partition by t.role order by t.n)
+from unnest($2::int[], $3::text[], $4::text[]) with ordinality as t(person_id, role, character, n)
+join people p on p.id = t.person_id
+on conflict do nothing
+		`,
+		id,
+		personIds,
+		roles,
+		characters)
+	if err != nil {
+		logger.Error("Could not insert movie credits", zap.Error(err))
+		return err
+	}
+
+	return syncDirector(c, tx, id)
+}
+
+// linkDirector credits the person named director as the director of the
+// movie, unless its directors already have that name. People are matched by
+// name regardless of case, and created when nobody has the name yet. An empty
+// director removes the director credits.
+func linkDirector(c context.Context, tx pgx.Tx, id int, director string) error {
+	logger := logger.GetLogger()
+	director = strings.TrimSpace(director)
+
+	var current string
+	err := tx.QueryRow(c, "select "+movieDirectors+" from movies m where m.id = $1", id).Scan(&current)
+	if err != nil {
+		logger.Error("Could not fetch movie directors", zap.Error(err))
+		return err
+	}
+	if strings.EqualFold(current, director) {
+		return syncDirector(c, tx, id)
+	}
+
+	_, err = tx.Exec(c, "delete from movie_credits where movie_id = $1 and role = 'director'", id)
+	if err != nil {
+		logger.Error("Could not delete director credits", zap.Error(err))
+		return err
+	}
+
+	if director != "" {
+		var personId int
+		err = tx.QueryRow(c, "select id from people where lower(name) = lower($1) order by id limit 1", director).Scan(&personId)
+		if errors.Is(err, pgx.ErrNoRows) {
+			err = tx.QueryRow(c, "insert into people (name) values ($1) returning id", director).Scan(&personId)
+		}
+		if err != nil {
+			logger.Error("Could not find or create director", zap.String("director", director), zap.Error(err))
+			return err
+		}
+
+		_, err = tx.Exec(c, "insert into movie_credits(movie_id, person_id, role, billing_order) values ($1, $2, 'director', 1)", id, personId)
+		if err != nil {
+			logger.Error("Could not insert director credit", zap.Error(err))
+			return err
+		}
+	}
+
+	return syncDirector(c, tx, id)
+}
+
+// syncDirector sets the director string of a movie to the names of its
+// directors.
+func syncDirector(c context.Context, tx pgx.Tx, id int) error {
+	_, err := tx.Exec(c, "update movies m set director = "+movieDirectors+" where m.id = $1", id)
+	if err != nil {
+		logger.GetLogger().Error("Could not update movie director", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// Delete moves the movie to the trash, if it still has the version, or
+// regardless of its version when version is 0. Ratings, watchlist items and
+// watch history are kept until the movie is purged, so restoring it brings
+// them back.
+func (r *MoviesRepository) Delete(c context.Context, id int, version int, userId int) error {
+	logger := logger.GetLogger()
+	logger.Info("Starting transaction for deleting movie", zap.Int("movie_id", id))
+
+	tx, err := r.db.Begin(c)
+	if err != nil {
+		logger.Error("Could not begin transaction", zap.Error(err))
+		return err
+	}
+	defer tx.Rollback(c)
+
+	tag, err := tx.Exec(
+		c,
+		"update movies set deleted_at = now(), version = version + 1 where id = @id and deleted_at is null and "+versionCondition,
+		pgx.NamedArgs{"id": id, "version": version})
 	if err != nil {
 		logger.Error("Could not delete movie", zap.Error(err))
 		return err
 	}
+	if err = checkMovieVersion(tag, version); err != nil {
+		logger.Info("Movie version changed", zap.Int("movie_id", id), zap.Int("version", version))
+		return err
+	}
+
+	err = recordRevision(c, tx, models.EntityMovie, id, userId, models.RevisionDelete)
+	if err != nil {
+		return err
+	}
 
 	err = tx.Commit(c)
 	if err != nil {
@@ -285,30 +947,160 @@ func (r *MoviesRepository) Delete(c context.Context, id int) error {
 	return nil
 }
 
-func (r *MoviesRepository) SetRating(c context.Context, id int, rating int) error {
+// checkMovie returns ErrMovieNotFound unless id is a movie that isn't in the
+// trash.
+func checkMovie(c context.Context, db rowQuerier, id int) error {
+	var exists bool
+	err := db.QueryRow(c, "select exists(select 1 from movies where id = $1 and deleted_at is null)", id).Scan(&exists)
+	if err != nil {
+		logger.GetLogger().Error("Could not check movie", zap.Int("movie_id", id), zap.Error(err))
+		return err
+	}
+	if !exists {
+		return ErrMovieNotFound
+	}
+	return nil
+}
+
+func (r *MoviesRepository) SetRating(c context.Context, id int, userId int, rating int) error {
 	logger := logger.GetLogger()
-	logger.Info("Updating movie rating", zap.Int("movie_id", id), zap.Int("rating", rating))
+	logger.Info("Updating movie rating", zap.Int("movie_id", id), zap.Int("user_id", userId), zap.Int("rating", rating))
 
-	_, err := r.db.Exec(c, "update movies set rating = $1 where id = $2", rating, id)
+	if err := checkMovie(c, r.db, id); err != nil {
+		return err
+	}
+
+	_, err := r.db.Exec(
+		c,
+		`
+insert into user_movie_ratings(user_id, movie_id, rating, rated_at)
+values($1, $2, $3, now())
+on conflict (user_id, movie_id) do update
+set
+rating = excluded.rating,
+rated_at = excluded.rated_at
+		`,
+		userId,
+		id,
+		rating)
 	if err != nil {
 		logger.Error("Could not update movie rating", zap.Error(err))
 		return err
 	}
 
-	logger.Info("Successfully updated movie rating", zap.Int("movie_id", id), zap.Int("rating", rating))
+	logger.Info("Successfully updated movie rating", zap.Int("movie_id", id), zap.Int("user_id", userId), zap.Int("rating", rating))
 	return nil
 }
 
-func (r *MoviesRepository) SetWatched(c context.Context, id int, isWatched bool) error {
+func (r *MoviesRepository) DeleteRating(c context.Context, id int, userId int) error {
 	logger := logger.GetLogger()
-	logger.Info("Updating movie watch status", zap.Int("movie_id", id), zap.Bool("is_watched", isWatched))
+	logger.Info("Removing movie rating", zap.Int("movie_id", id), zap.Int("user_id", userId))
+
+	if err := checkMovie(c, r.db, id); err != nil {
+		return err
+	}
 
-	_, err := r.db.Exec(c, "update movies set is_watched = $1 where id = $2", isWatched, id)
+	_, err := r.db.Exec(c, "delete from user_movie_ratings where movie_id = $1 and user_id = $2", id, userId)
 	if err != nil {
-		logger.Error("Could not update movie watch status", zap.Error(err))
+		logger.Error("Could not remove movie rating", zap.Error(err))
 		return err
 	}
 
-	logger.Info("Successfully updated movie watch status", zap.Int("movie_id", id), zap.Bool("is_watched", isWatched))
+	logger.Info("Successfully removed movie rating", zap.Int("movie_id", id), zap.Int("user_id", userId))
+	return nil
+}
+
+// checkWatchable reports why the watched state of the movie id was not
+// changed: ErrMovieNotFound when it doesn't exist or is in the trash, and
+// ErrSeriesWatched when it is a series.
+func checkWatchable(c context.Context, db rowQuerier, id int) error {
+	var kind string
+	err := db.QueryRow(c, "select kind from movies where id = $1 and deleted_at is null", id).Scan(&kind)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrMovieNotFound
+	}
+	if err != nil {
+		logger.GetLogger().Error("Could not check movie", zap.Int("movie_id", id), zap.Error(err))
+		return err
+	}
+	if kind == models.KindSeries {
+		return ErrSeriesWatched
+	}
 	return nil
-}
\ No newline at end of file
+}
+
+// SetWatched updates the watched state of a movie for a single user. Marking
+// a movie as watched always records a watch event, so marking an already
+// watched movie again is stored as a rewatch.
+func (r *MoviesRepository) SetWatched(c context.Context, id int, userId int, isWatched bool) error {
+	logger := logger.GetLogger()
+	logger.Info("Updating movie watch status", zap.Int("movie_id", id), zap.Int("user_id", userId), zap.Bool("is_watched", isWatched))
+
+	tx, err := r.db.Begin(c)
+	if err != nil {
+		logger.Error("Could not begin transaction", zap.Error(err))
+		return err
+	}
+	defer tx.Rollback(c)
+
+	var tag pgconn.CommandTag
+	if isWatched {
+		tag, err = tx.Exec(
+			c,
+			`
+insert into user_watched_movies(user_id, movie_id, watched_at)
+select $1, id, now() from movies where id = $2 and kind = 'movie' and deleted_at is null
+on conflict (user_id, movie_id) do update
+set watched_at = excluded.watched_at
+			`,
+			userId,
+			id)
+		if err != nil {
+			logger.Error("Could not update movie watch status", zap.Error(err))
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return checkWatchable(c, tx, id)
+		}
+
+		_, err = tx.Exec(c, "insert into watch_events(user_id, movie_id, watched_at) values($1, $2, now())", userId, id)
+		if err != nil {
+			logger.Error("Could not insert watch event", zap.Error(err))
+			return err
+		}
+	} else {
+		tag, err = tx.Exec(
+			c,
+			`
+delete from user_watched_movies w
+using movies m
+where w.user_id = $1 and w.movie_id = $2 and m.id = w.movie_id and m.kind = 'movie' and m.deleted_at is null
+			`,
+			userId,
+			id)
+		if err != nil {
+			logger.Error("Could not update movie watch status", zap.Error(err))
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			if err = checkWatchable(c, tx, id); err != nil {
+				return err
+			}
+		}
+	}
+
+	err = tx.Commit(c)
+	if err != nil {
+		logger.Error("Could not commit transaction", zap.Error(err))
+		return err
+	}
+
+	logger.Info("Successfully updated movie watch status", zap.Int("movie_id", id), zap.Int("user_id", userId), zap.Bool("is_watched", isWatched))
+	return nil
+}
+
+// escapeLike escapes the wildcards of a like pattern, so user input only
+// matches literally.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
@@ -0,0 +1,23 @@
+package repositories
+
+import "testing"
+
+func TestEscapeLike(t *testing.T) {
+	tests := []struct {
+		s    string
+		want string
+	}{
+		{"matrix", "matrix"},
+		{"100%", `100\%`},
+		{"snake_case", `snake\_case`},
+		{`back\slash`, `back\\slash`},
+		{`\%_`, `\\\%\_`},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := escapeLike(tt.s); got != tt.want {
+			t.Errorf("escapeLike(%q) = %q, want %q", tt.s, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package repositories
+
+import (
+	"fmt"
+	"goozinshe/models"
+	"strings"
+)
+
+// sortKey is one column of a keyset ordering. expr is a format string whose
+// %[1]s verb is replaced by a table alias, so the same expression can be
+// evaluated both for the listed rows and for the row a cursor points at.
+// Null values are always sorted last.
+type sortKey struct {
+	expr string
+	desc bool
+}
+
+func (k sortKey) on(alias string) string {
+	return fmt.Sprintf(k.expr, alias)
+}
+
+// orderBy renders the order by list of keys for rows of alias. reverse flips
+// the order, which is used to walk backwards from a "before" cursor.
+func orderBy(keys []sortKey, alias string, reverse bool) string {
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		desc := k.desc != reverse
+		direction := "asc"
+		if desc {
+			direction = "desc"
+		}
+		nulls := "last"
+		if reverse {
+			nulls = "first"
+		}
+		parts = append(parts, fmt.Sprintf("%s %s nulls %s", k.on(alias), direction, nulls))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// keysetCondition renders a condition that keeps the rows of alias ordered
+// strictly after (or, with before, strictly before) the row of cursorAlias.
+func keysetCondition(keys []sortKey, alias string, cursorAlias string, before bool) string {
+	alternatives := make([]string, 0, len(keys))
+	for i, k := range keys {
+		parts := make([]string, 0, i+1)
+		for _, prev := range keys[:i] {
+			parts = append(parts, fmt.Sprintf("%s is not distinct from %s", prev.on(alias), prev.on(cursorAlias)))
+		}
+
+		row, cur := k.on(alias), k.on(cursorAlias)
+		if before {
+			row, cur = cur, row
+		}
+		operator := ">"
+		if k.desc {
+			operator = "<"
+		}
+		// row comes after cur when it is greater (or less, for descending
+		// keys), or when it is null and cur isn't, since nulls sort last.
+		parts = append(parts, fmt.Sprintf("(%[2]s is not null and (%[1]s %[3]s %[2]s or %[1]s is null))", row, cur, operator))
+
+		alternatives = append(alternatives, "("+strings.Join(parts, " and ")+")")
+	}
+	return "(" + strings.Join(alternatives, " or ") + ")"
+}
+
+// cursorId returns the id of the row the cursor points at.
+func cursorId(page models.PageRequest) int {
+	if page.Cursor.Before != 0 {
+		return page.Cursor.Before
+	}
+	return page.Cursor.After
+}
+
+// pageInfo trims the extra row fetched to detect further pages, restores the
+// order of pages fetched backwards and fills in the cursors around the page.
+// Pages requested by number get cursors too, so clients can switch to them.
+func pageInfo[T any](items []T, page models.PageRequest, total int, id func(T) int) ([]T, models.PageInfo) {
+	info := models.PageInfo{Total: total}
+	if page.Cursor == nil {
+		hasMore := len(items) > page.Limit
+		if hasMore {
+			items = items[:page.Limit]
+		}
+		if len(items) == 0 {
+			return items, info
+		}
+		if page.Page > 1 {
+			info.PrevCursor = models.Cursor{Before: id(items[0])}.Encode()
+		}
+		if hasMore {
+			info.NextCursor = models.Cursor{After: id(items[len(items)-1])}.Encode()
+		}
+		return items, info
+	}
+
+	before := page.Cursor.Before != 0
+	hasMore := len(items) > page.Limit
+	if hasMore {
+		items = items[:page.Limit]
+	}
+	if before {
+		for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+			items[i], items[j] = items[j], items[i]
+		}
+	}
+
+	if len(items) == 0 {
+		return items, info
+	}
+
+	first, last := id(items[0]), id(items[len(items)-1])
+	if !before || hasMore {
+		info.PrevCursor = models.Cursor{Before: first}.Encode()
+	}
+	if before || hasMore {
+		info.NextCursor = models.Cursor{After: last}.Encode()
+	}
+	return items, info
+}
@@ -0,0 +1,152 @@
+package repositories
+
+import (
+	"goozinshe/models"
+	"reflect"
+	"testing"
+)
+
+var testSortKeys = []sortKey{{expr: "%[1]s.rating", desc: true}, {expr: "%[1]s.id"}}
+
+func TestOrderBy(t *testing.T) {
+	tests := []struct {
+		reverse bool
+		want    string
+	}{
+		{false, "m.rating desc nulls last, m.id asc nulls last"},
+		{true, "m.rating asc nulls first, m.id desc nulls first"},
+	}
+
+	for _, tt := range tests {
+		if got := orderBy(testSortKeys, "m", tt.reverse); got != tt.want {
+			t.Errorf("orderBy(reverse=%v) = %q, want %q", tt.reverse, got, tt.want)
+		}
+	}
+}
+
+func TestKeysetCondition(t *testing.T) {
+	tests := []struct {
+		name   string
+		keys   []sortKey
+		before bool
+		want   string
+	}{
+		{
+			name: "single key after",
+			keys: testSortKeys[1:],
+			want: "(((cur.id is not null and (m.id > cur.id or m.id is null))))",
+		},
+		{
+			name: "descending key after",
+			keys: testSortKeys,
+			want: "(((cur.rating is not null and (m.rating < cur.rating or m.rating is null)))" +
+				" or (m.rating is not distinct from cur.rating and (cur.id is not null and (m.id > cur.id or m.id is null))))",
+		},
+		{
+			name:   "descending key before",
+			keys:   testSortKeys,
+			before: true,
+			want: "(((m.rating is not null and (cur.rating < m.rating or cur.rating is null)))" +
+				" or (m.rating is not distinct from cur.rating and (m.id is not null and (cur.id > m.id or cur.id is null))))",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := keysetCondition(tt.keys, "m", "cur", tt.before); got != tt.want {
+				t.Errorf("keysetCondition() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPageInfo(t *testing.T) {
+	id := func(i int) int { return i }
+	cursor := func(c models.Cursor) string { return c.Encode() }
+
+	tests := []struct {
+		name      string
+		items     []int
+		page      models.PageRequest
+		wantItems []int
+		wantNext  string
+		wantPrev  string
+	}{
+		{
+			name:      "first page with more",
+			items:     []int{1, 2, 3},
+			page:      models.PageRequest{Page: 1, Limit: 2},
+			wantItems: []int{1, 2},
+			wantNext:  cursor(models.Cursor{After: 2}),
+		},
+		{
+			name:      "middle page",
+			items:     []int{3, 4, 5},
+			page:      models.PageRequest{Page: 2, Limit: 2},
+			wantItems: []int{3, 4},
+			wantNext:  cursor(models.Cursor{After: 4}),
+			wantPrev:  cursor(models.Cursor{Before: 3}),
+		},
+		{
+			name:      "last page",
+			items:     []int{5},
+			page:      models.PageRequest{Page: 3, Limit: 2},
+			wantItems: []int{5},
+			wantPrev:  cursor(models.Cursor{Before: 5}),
+		},
+		{
+			name:      "page past the end",
+			items:     []int{},
+			page:      models.PageRequest{Page: 9, Limit: 2},
+			wantItems: []int{},
+		},
+		{
+			name:      "after cursor with more",
+			items:     []int{3, 4, 5},
+			page:      models.PageRequest{Page: 1, Limit: 2, Cursor: &models.Cursor{After: 2}},
+			wantItems: []int{3, 4},
+			wantNext:  cursor(models.Cursor{After: 4}),
+			wantPrev:  cursor(models.Cursor{Before: 3}),
+		},
+		{
+			name:      "after cursor at the end",
+			items:     []int{5},
+			page:      models.PageRequest{Page: 1, Limit: 2, Cursor: &models.Cursor{After: 4}},
+			wantItems: []int{5},
+			wantPrev:  cursor(models.Cursor{Before: 5}),
+		},
+		{
+			name:      "before cursor is reversed",
+			items:     []int{4, 3, 2},
+			page:      models.PageRequest{Page: 1, Limit: 2, Cursor: &models.Cursor{Before: 5}},
+			wantItems: []int{3, 4},
+			wantNext:  cursor(models.Cursor{After: 4}),
+			wantPrev:  cursor(models.Cursor{Before: 3}),
+		},
+		{
+			name:      "before cursor at the start",
+			items:     []int{2, 1},
+			page:      models.PageRequest{Page: 1, Limit: 2, Cursor: &models.Cursor{Before: 3}},
+			wantItems: []int{1, 2},
+			wantNext:  cursor(models.Cursor{After: 2}),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items, info := pageInfo(tt.items, tt.page, 5, id)
+			if !reflect.DeepEqual(items, tt.wantItems) {
+				t.Errorf("items = %v, want %v", items, tt.wantItems)
+			}
+			if info.Total != 5 {
+				t.Errorf("Total = %d, want 5", info.Total)
+			}
+			if info.NextCursor != tt.wantNext {
+				t.Errorf("NextCursor = %q, want %q", info.NextCursor, tt.wantNext)
+			}
+			if info.PrevCursor != tt.wantPrev {
+				t.Errorf("PrevCursor = %q, want %q", info.PrevCursor, tt.wantPrev)
+			}
+		})
+	}
+}
@@ -0,0 +1,276 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"goozinshe/logger"
+	"goozinshe/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+var ErrPersonNotFound = models.NewNotFoundError("person_not_found", "person not found")
+
+// ErrPersonInUse is returned when deleting a person who is credited in a
+// movie.
+var ErrPersonInUse = models.NewConflictError("person_in_use", "person is credited in a movie")
+
+type PeopleRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewPeopleRepository(conn *pgxpool.Pool) *PeopleRepository {
+	return &PeopleRepository{db: conn}
+}
+
+// FindAll returns a page of people ordered by name. A non-empty search only
+// returns people whose name contains it.
+func (r *PeopleRepository) FindAll(c context.Context, search string, page models.PageRequest) ([]models.Person, models.PageInfo, error) {
+	logger := logger.GetLogger()
+	logger.Info("Fetching people", zap.String("search", search), zap.Int("page", page.Page), zap.Int("limit", page.Limit))
+
+	where := "where true"
+	params := pgx.NamedArgs{"limit": page.Limit + 1, "offset": page.Offset()}
+	if search != "" {
+		where = "where p.name ilike @search"
+		params["search"] = "%" + escapeLike(search) + "%"
+	}
+
+	var total int
+	err := r.db.QueryRow(c, fmt.Sprintf("select count(*) from people p %s", where), params).Scan(&total)
+	if err != nil {
+		logger.Error("Could not count people", zap.Error(err))
+		return nil, models.PageInfo{}, err
+	}
+
+	keys := []sortKey{{expr: "lower(%[1]s.name)"}, {expr: "%[1]s.id"}}
+	sql := "select p.id, p.name from people p"
+	reverse := false
+	if page.Cursor != nil {
+		reverse = page.Cursor.Before != 0
+		sql = fmt.Sprintf("%s join people cur on cur.id = @cursorId", sql)
+		where = fmt.Sprintf("%s and %s", where, keysetCondition(keys, "p", "cur", reverse))
+		params["cursorId"] = cursorId(page)
+		params["offset"] = 0
+	}
+	sql = fmt.Sprintf("%s %s order by %s limit @limit offset @offset", sql, where, orderBy(keys, "p", reverse))
+
+	rows, err := r.db.Query(c, sql, params)
+	if err != nil {
+		logger.Error("Could not fetch people", zap.Error(err))
+		return nil, models.PageInfo{}, err
+	}
+	defer rows.Close()
+
+	people := make([]models.Person, 0)
+	for rows.Next() {
+		var person models.Person
+		err := rows.Scan(&person.Id, &person.Name)
+		if err != nil {
+			logger.Error("Could not scan person", zap.Error(err))
+			return nil, models.PageInfo{}, err
+		}
+		people = append(people, person)
+	}
+
+	if err = rows.Err(); err != nil {
+		logger.Error("Error iterating over people", zap.Error(err))
+		return nil, models.PageInfo{}, err
+	}
+
+	people, info := pageInfo(people, page, total, func(p models.Person) int { return p.Id })
+
+	logger.Info("Successfully fetched people", zap.Int("count", len(people)), zap.Int("total", total))
+	return people, info, nil
+}
+
+func (r *PeopleRepository) FindById(c context.Context, id int) (models.Person, error) {
+	logger := logger.GetLogger()
+	logger.Info("Fetching person by ID", zap.Int("person_id", id))
+
+	var person models.Person
+	err := r.db.QueryRow(c, "select id, name, version from people where id = $1", id).Scan(&person.Id, &person.Name, &person.Version)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.Person{}, ErrPersonNotFound
+	}
+	if err != nil {
+		logger.Error("Could not fetch person", zap.Error(err))
+		return models.Person{}, err
+	}
+
+	logger.Info("Successfully fetched person", zap.Int("person_id", person.Id))
+	return person, nil
+}
+
+// FindAllByIds returns the people with the ids. People that don't exist are
+// left out.
+func (r *PeopleRepository) FindAllByIds(c context.Context, ids []int) ([]models.Person, error) {
+	logger := logger.GetLogger()
+	logger.Info("Fetching people by IDs", zap.Ints("person_ids", ids))
+
+	rows, err := r.db.Query(c, "select id, name from people where id = any($1)", ids)
+	if err != nil {
+		logger.Error("Could not fetch people", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	people := make([]models.Person, 0)
+	for rows.Next() {
+		var person models.Person
+		err := rows.Scan(&person.Id, &person.Name)
+		if err != nil {
+			logger.Error("Could not scan person", zap.Error(err))
+			return nil, err
+		}
+		people = append(people, person)
+	}
+
+	if err = rows.Err(); err != nil {
+		logger.Error("Error iterating over people", zap.Error(err))
+		return nil, err
+	}
+
+	logger.Info("Successfully fetched people", zap.Int("count", len(people)))
+	return people, nil
+}
+
+func (r *PeopleRepository) Create(c context.Context, person models.Person) (int, error) {
+	logger := logger.GetLogger()
+	logger.Info("Creating new person", zap.String("name", person.Name))
+
+	var id int
+	err := r.db.QueryRow(c, "insert into people (name) values ($1) returning id", person.Name).Scan(&id)
+	if err != nil {
+		logger.Error("Could not create person", zap.Error(err))
+		return 0, err
+	}
+
+	logger.Info("Successfully created person", zap.Int("person_id", id))
+	return id, nil
+}
+
+// Update renames the person if it still has person.Version, or regardless of
+// its version when person.Version is 0. The director of the movies the person
+// directed is renamed too.
+func (r *PeopleRepository) Update(c context.Context, id int, person models.Person) error {
+	logger := logger.GetLogger()
+	logger.Info("Updating person", zap.Int("person_id", id), zap.String("name", person.Name))
+
+	tx, err := r.db.Begin(c)
+	if err != nil {
+		logger.Error("Could not begin transaction", zap.Error(err))
+		return err
+	}
+	defer tx.Rollback(c)
+
+	tag, err := tx.Exec(
+		c,
+		"update people set name = @name, version = version + 1 where id = @id and "+versionCondition,
+		pgx.NamedArgs{"id": id, "name": person.Name, "version": person.Version})
+	if err != nil {
+		logger.Error("Could not update person", zap.Error(err))
+		return err
+	}
+	if err = checkVersion(tag, person.Version); err != nil {
+		logger.Info("Person version changed", zap.Int("person_id", id), zap.Int("version", person.Version))
+		return err
+	}
+
+	_, err = tx.Exec(c, fmt.Sprintf(`
+update movies m
+set director = %s
+where m.id in (select mc.movie_id from movie_credits mc where mc.person_id = $1 and mc.role = 'director')
+	`, movieDirectors), id)
+	if err != nil {
+		logger.Error("Could not update directors", zap.Error(err))
+		return err
+	}
+
+	err = tx.Commit(c)
+	if err != nil {
+		logger.Error("Could not commit transaction", zap.Error(err))
+		return err
+	}
+
+	logger.Info("Successfully updated person", zap.Int("person_id", id))
+	return nil
+}
+
+// Delete deletes the person if it still has the version, or regardless of its
+// version when version is 0. People who are credited in a movie, including
+// movies in the trash, can't be deleted.
+func (r *PeopleRepository) Delete(c context.Context, id int, version int) error {
+	logger := logger.GetLogger()
+	logger.Info("Deleting person", zap.Int("person_id", id))
+
+	var inUse bool
+	err := r.db.QueryRow(c, "select exists(select 1 from movie_credits where person_id = $1)", id).Scan(&inUse)
+	if err != nil {
+		logger.Error("Could not check person credits", zap.Error(err))
+		return err
+	}
+	if inUse {
+		logger.Info("Person is credited in a movie", zap.Int("person_id", id))
+		return ErrPersonInUse
+	}
+
+	tag, err := r.db.Exec(c, "delete from people where id = @id and "+versionCondition, pgx.NamedArgs{"id": id, "version": version})
+	if isForeignKeyViolation(err) {
+		return ErrPersonInUse
+	}
+	if err != nil {
+		logger.Error("Could not delete person", zap.Error(err))
+		return err
+	}
+	if err = checkVersion(tag, version); err != nil {
+		logger.Info("Person version changed", zap.Int("person_id", id), zap.Int("version", version))
+		return err
+	}
+
+	logger.Info("Successfully deleted person", zap.Int("person_id", id))
+	return nil
+}
+
+// Filmography returns the credits of the person in movies that aren't in the
+// trash, newest movies first, with titles in the first of locales they are
+// translated to.
+func (r *PeopleRepository) Filmography(c context.Context, id int, locales []string) ([]models.FilmographyEntry, error) {
+	logger := logger.GetLogger()
+	logger.Info("Fetching filmography", zap.Int("person_id", id))
+
+	rows, err := r.db.Query(c, fmt.Sprintf(`
+select m.id, coalesce(%[1]s, ''), coalesce(m.release_year, 0), coalesce(m.poster_url, ''), mc.role, mc.character, mc.billing_order
+from movie_credits mc
+join movies m on m.id = mc.movie_id and m.deleted_at is null
+where mc.person_id = @id
+order by m.release_year desc nulls last, %[1]s, m.id, %[2]s
+	`, translated(models.EntityMovie, "m", "title"), creditOrder), pgx.NamedArgs{"id": id, "locales": locales})
+	if err != nil {
+		logger.Error("Could not fetch filmography", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := make([]models.FilmographyEntry, 0)
+	for rows.Next() {
+		var e models.FilmographyEntry
+		err := rows.Scan(&e.MovieId, &e.Title, &e.ReleaseYear, &e.PosterUrl, &e.Role, &e.Character, &e.BillingOrder)
+		if err != nil {
+			logger.Error("Could not scan filmography entry", zap.Error(err))
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+
+	if err = rows.Err(); err != nil {
+		logger.Error("Error iterating over filmography", zap.Error(err))
+		return nil, err
+	}
+
+	logger.Info("Successfully fetched filmography", zap.Int("person_id", id), zap.Int("count", len(entries)))
+	return entries, nil
+}
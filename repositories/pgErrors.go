@@ -0,0 +1,26 @@
+package repositories
+
+import (
+	"errors"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Postgres error codes of broken constraints.
+const (
+	foreignKeyViolation = "23503"
+	uniqueViolation     = "23505"
+)
+
+func isUniqueViolation(err error) bool {
+	return hasErrorCode(err, uniqueViolation)
+}
+
+func isForeignKeyViolation(err error) bool {
+	return hasErrorCode(err, foreignKeyViolation)
+}
+
+func hasErrorCode(err error, code string) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == code
+}
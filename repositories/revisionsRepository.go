@@ -0,0 +1,159 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"goozinshe/logger"
+	"goozinshe/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+var ErrRevisionNotFound = models.NewNotFoundError("revision_not_found", "revision not found")
+
+// revisionSnapshots select the version and a JSON snapshot of the entity with
+// the id bound as @id. Snapshot keys are the JSON field names of the models.
+var revisionSnapshots = map[string]string{
+	models.EntityMovie: `
+select m.version, jsonb_build_object(
+	'Title', m.title,
+	'Description', m.description,
+	'ReleaseYear', m.release_year,
+	'Director', m.director,
+	'TrailerUrl', m.trailer_url,
+	'PosterUrl', m.poster_url,
+	'GenreIds', coalesce((select jsonb_agg(mg.genre_id order by mg.genre_id) from movies_genres mg where mg.movie_id = m.id), '[]'),
+	'Credits', coalesce((
+		select jsonb_agg(jsonb_build_object('PersonId', mc.person_id, 'Role', mc.role, 'Character', mc.character) order by mc.role, mc.billing_order)
+		from movie_credits mc
+		where mc.movie_id = m.id
+	), '[]'),
+	'Translations', ` + translationsSnapshot(models.EntityMovie, "m") + `
+) as snapshot
+from movies m
+where m.id = @id`,
+	models.EntityGenre: `
+select g.version, jsonb_build_object(
+	'Title', g.title,
+	'Translations', ` + translationsSnapshot(models.EntityGenre, "g") + `
+) as snapshot
+from genres g
+where g.id = @id`,
+}
+
+// recordRevision snapshots the entity as it is in tx and stores it as a
+// revision, together with the fields that differ from the previous revision.
+// Deletes are recorded before the entity is deleted.
+func recordRevision(c context.Context, tx pgx.Tx, entity string, id int, userId int, action string) error {
+	sql := fmt.Sprintf(`
+with snap as (%s),
+previous as (
+	select r.snapshot
+	from revisions r
+	where r.entity_type = @entity and r.entity_id = @id
+	order by r.id desc
+	limit 1
+)
+insert into revisions(entity_type, entity_id, version, action, user_id, snapshot, changes)
+select
+@entity,
+@id,
+cur.version,
+@action,
+nullif(@userId, 0),
+cur.snapshot,
+coalesce((
+	select jsonb_object_agg(field.key, jsonb_build_object('Old', prev.snapshot -> field.key, 'New', field.value))
+	from jsonb_each(cur.snapshot) field
+	where prev.snapshot -> field.key is distinct from field.value
+), '{}')
+from snap cur
+left join previous prev on true
+	`, revisionSnapshots[entity])
+
+	_, err := tx.Exec(c, sql, pgx.NamedArgs{"entity": entity, "id": id, "userId": userId, "action": action})
+	if err != nil {
+		logger.GetLogger().Error("Could not record revision", zap.String("entity", entity), zap.Int("id", id), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// rowQuerier is implemented by both pgxpool.Pool and pgx.Tx.
+type rowQuerier interface {
+	QueryRow(c context.Context, sql string, args ...any) pgx.Row
+}
+
+// findRevisionSnapshot reads the snapshot of a revision of the entity into
+// snapshot.
+func findRevisionSnapshot(c context.Context, db rowQuerier, entity string, id int, revisionId int, snapshot any) error {
+	err := db.QueryRow(
+		c,
+		"select snapshot from revisions where id = $1 and entity_type = $2 and entity_id = $3",
+		revisionId,
+		entity,
+		id).Scan(snapshot)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrRevisionNotFound
+	}
+	return err
+}
+
+type RevisionsRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewRevisionsRepository(conn *pgxpool.Pool) *RevisionsRepository {
+	return &RevisionsRepository{db: conn}
+}
+
+// FindAll returns a page of the revisions of an entity, newest first.
+func (r *RevisionsRepository) FindAll(c context.Context, entity string, id int, page models.PageRequest) ([]models.Revision, models.PageInfo, error) {
+	logger := logger.GetLogger()
+	logger.Info("Fetching revisions", zap.String("entity", entity), zap.Int("id", id), zap.Int("page", page.Page), zap.Int("limit", page.Limit))
+
+	var total int
+	err := r.db.QueryRow(c, "select count(*) from revisions where entity_type = $1 and entity_id = $2", entity, id).Scan(&total)
+	if err != nil {
+		logger.Error("Could not count revisions", zap.Error(err))
+		return nil, models.PageInfo{}, err
+	}
+
+	rows, err := r.db.Query(
+		c,
+		`
+select id, version, action, user_id, snapshot, changes, created_at
+from revisions
+where entity_type = @entity and entity_id = @id
+order by id desc
+limit @limit offset @offset
+		`,
+		pgx.NamedArgs{"entity": entity, "id": id, "limit": page.Limit, "offset": page.Offset()})
+	if err != nil {
+		logger.Error("Could not fetch revisions", zap.Error(err))
+		return nil, models.PageInfo{}, err
+	}
+	defer rows.Close()
+
+	revisions := make([]models.Revision, 0)
+	for rows.Next() {
+		var revision models.Revision
+		err := rows.Scan(&revision.Id, &revision.Version, &revision.Action, &revision.UserId, &revision.Snapshot, &revision.Changes, &revision.CreatedAt)
+		if err != nil {
+			logger.Error("Could not scan revision", zap.Error(err))
+			return nil, models.PageInfo{}, err
+		}
+		revisions = append(revisions, revision)
+	}
+
+	if err = rows.Err(); err != nil {
+		logger.Error("Error iterating over revisions", zap.Error(err))
+		return nil, models.PageInfo{}, err
+	}
+
+	logger.Info("Successfully fetched revisions", zap.Int("count", len(revisions)), zap.Int("total", total))
+	return revisions, models.PageInfo{Total: total}, nil
+}
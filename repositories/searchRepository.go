@@ -0,0 +1,243 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"goozinshe/logger"
+	"goozinshe/models"
+	"html"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+// Postgres wraps matched words in these control characters, which don't occur
+// in movie texts, so the text can be HTML escaped before they become <mark>
+// tags.
+const (
+	highlightStart = "\x02"
+	highlightStop  = "\x03"
+)
+
+// searchQuery binds the search term as a full text query and as a lowercase
+// term for trigram matching. A movie matches when its title, director or
+// description contain the words, when its title or director is similar
+// enough to the term, which tolerates typos, or when it is a series and the
+// title or description of one of its episodes contain the words. Titles and
+// descriptions match in every locale they are translated to.
+const searchQuery = `
+with q as (
+	select websearch_to_tsquery('simple', @q) as query, lower(@q) as term
+)`
+
+const searchCondition = `m.deleted_at is null and (
+	m.search_vector @@ q.query
+	or q.term <% lower(m.title)
+	or q.term <% lower(m.director)
+	or exists(
+		select 1
+		from movie_translations mt
+		where mt.movie_id = m.id and (mt.search_vector @@ q.query or q.term <% lower(mt.title))
+	)
+	or exists(
+		select 1
+		from seasons s
+		join episodes e on e.season_id = s.id
+		where s.series_id = m.id and e.search_vector @@ q.query
+	)
+)`
+
+// episodeMatches aggregates the three best matching episodes of the series m
+// into a JSON array. It must be used with searchQuery.
+const episodeMatches = `coalesce((
+	select json_agg(json_build_object(
+		'Id', em.id,
+		'SeasonNumber', em.season_number,
+		'Number', em.number,
+		'Title', em.title
+	) order by em.rank desc, em.season_number, em.number)
+	from (
+		select e.id, s.number as season_number, e.number, e.title, ts_rank_cd(e.search_vector, q.query) as rank
+		from seasons s
+		join episodes e on e.season_id = s.id
+		where s.series_id = m.id and e.search_vector @@ q.query
+		order by rank desc, s.number, e.number
+		limit 3
+	) em
+), '[]')`
+
+type SearchRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewSearchRepository(conn *pgxpool.Pool) *SearchRepository {
+	return &SearchRepository{db: conn}
+}
+
+// Search returns a page of movies matching the query, best matches first. A
+// non-empty kind only returns movies or only series. Series that only match
+// through their episodes rank below those matching themselves. A movie ranks
+// by the locale it matches best in, and is shown in the first of locales it is
+// translated to.
+func (r *SearchRepository) Search(c context.Context, userId int, locales []string, query string, kind string, page models.PageRequest) ([]models.SearchResult, models.PageInfo, error) {
+	logger := logger.GetLogger()
+	logger.Info("Searching movies", zap.String("query", query), zap.String("kind", kind), zap.Int("page", page.Page), zap.Int("limit", page.Limit))
+
+	headlineOptions := fmt.Sprintf("StartSel=%s, StopSel=%s", highlightStart, highlightStop)
+	params := pgx.NamedArgs{
+		"q":              query,
+		"userId":         userId,
+		"locales":        locales,
+		"limit":          page.Limit,
+		"offset":         page.Offset(),
+		"titleOptions":   headlineOptions + ", HighlightAll=true",
+		"snippetOptions": headlineOptions + ", MaxWords=30, MinWords=10, MaxFragments=2",
+	}
+
+	where := searchCondition
+	if kind != "" {
+		where = fmt.Sprintf("%s and m.kind = @kind", where)
+		params["kind"] = kind
+	}
+
+	var total int
+	countSql := fmt.Sprintf("%s select count(*) from movies m, q where %s", searchQuery, where)
+	err := r.db.QueryRow(c, countSql, params).Scan(&total)
+	if err != nil {
+		logger.Error("Could not count search results", zap.Error(err))
+		return nil, models.PageInfo{}, err
+	}
+
+	sql := fmt.Sprintf(`
+%s
+select %s, m.rank, m.title_highlight, m.snippet, m.episode_matches
+from (
+	select
+	m.*,
+	greatest(ts_rank_cd(m.search_vector, q.query) + word_similarity(q.term, lower(m.title)), (
+		select max(ts_rank_cd(mt.search_vector, q.query) + word_similarity(q.term, lower(mt.title)))
+		from movie_translations mt
+		where mt.movie_id = m.id
+	)) + coalesce((
+		select max(ts_rank_cd(e.search_vector, q.query)) / 2
+		from seasons s
+		join episodes e on e.season_id = s.id
+		where s.series_id = m.id and e.search_vector @@ q.query
+	), 0) as rank,
+	ts_headline('simple', coalesce(%s, ''), q.query, @titleOptions) as title_highlight,
+	ts_headline('simple', coalesce(%s, ''), q.query, @snippetOptions) as snippet,
+	%s as episode_matches
+	from movies m, q
+	where %s
+	order by rank desc, m.id
+	limit @limit offset @offset
+) m
+%s
+order by m.rank desc, m.id
+	`, searchQuery, movieColumns, translated(models.EntityMovie, "m", "title"), translated(models.EntityMovie, "m", "description"), episodeMatches, where, movieUserJoins)
+
+	rows, err := r.db.Query(c, sql, params)
+	if err != nil {
+		logger.Error("Could not search movies", zap.Error(err))
+		return nil, models.PageInfo{}, err
+	}
+	defer rows.Close()
+
+	searchResults := make([]models.SearchResult, 0)
+	for rows.Next() {
+		var result models.SearchResult
+
+		err := scanMovie(rows, &result.Movie, &result.Rank, &result.TitleHighlight, &result.Snippet, &result.Episodes)
+		if err != nil {
+			logger.Error("Could not scan search result", zap.Error(err))
+			return nil, models.PageInfo{}, err
+		}
+
+		result.TitleHighlight = highlight(result.TitleHighlight)
+		result.Snippet = highlight(result.Snippet)
+		searchResults = append(searchResults, result)
+	}
+
+	if err = rows.Err(); err != nil {
+		logger.Error("Error iterating over search results", zap.Error(err))
+		return nil, models.PageInfo{}, err
+	}
+
+	logger.Info("Successfully searched movies", zap.Int("count", len(searchResults)), zap.Int("total", total))
+	return searchResults, models.PageInfo{Total: total}, nil
+}
+
+// Suggest returns movie titles for autocomplete. Titles starting with the
+// prefix come first, followed by titles that contain a similar word. Every
+// movie is suggested once, under its title that matches best in any locale,
+// preferring the first of locales on a tie.
+func (r *SearchRepository) Suggest(c context.Context, locales []string, prefix string, limit int) ([]models.Suggestion, error) {
+	logger := logger.GetLogger()
+	logger.Info("Fetching search suggestions", zap.String("prefix", prefix))
+
+	sql := `
+with titles as (
+	select m.id, m.title, null::int as preference
+	from movies m
+	where m.deleted_at is null
+	union all
+	select mt.movie_id, mt.title, array_position(@locales::text[], mt.locale)
+	from movie_translations mt
+	join movies m on m.id = mt.movie_id and m.deleted_at is null
+),
+best as (
+	select distinct on (t.id)
+	t.id,
+	t.title,
+	lower(t.title) like @pattern as is_prefix,
+	word_similarity(@term, lower(t.title)) as similarity
+	from titles t
+	where lower(t.title) like @pattern or @term <% lower(t.title)
+	order by t.id, lower(t.title) like @pattern desc, word_similarity(@term, lower(t.title)) desc, t.preference nulls last
+)
+select m.id, m.kind, b.title, m.release_year, coalesce(m.poster_url, '')
+from best b
+join movies m on m.id = b.id
+order by b.is_prefix desc, b.similarity desc, b.title, m.id
+limit @limit
+	`
+
+	term := strings.ToLower(prefix)
+	rows, err := r.db.Query(c, sql, pgx.NamedArgs{
+		"locales": locales,
+		"term":    term,
+		"pattern": escapeLike(term) + "%",
+		"limit":   limit,
+	})
+	if err != nil {
+		logger.Error("Could not fetch search suggestions", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	suggestions := make([]models.Suggestion, 0)
+	for rows.Next() {
+		var s models.Suggestion
+		if err := rows.Scan(&s.MovieId, &s.Kind, &s.Title, &s.ReleaseYear, &s.PosterUrl); err != nil {
+			logger.Error("Could not scan search suggestion", zap.Error(err))
+			return nil, err
+		}
+		suggestions = append(suggestions, s)
+	}
+
+	if err = rows.Err(); err != nil {
+		logger.Error("Error iterating over search suggestions", zap.Error(err))
+		return nil, err
+	}
+
+	logger.Info("Successfully fetched search suggestions", zap.Int("count", len(suggestions)))
+	return suggestions, nil
+}
+
+func highlight(s string) string {
+	s = html.EscapeString(s)
+	s = strings.ReplaceAll(s, highlightStart, "<mark>")
+	return strings.ReplaceAll(s, highlightStop, "</mark>")
+}
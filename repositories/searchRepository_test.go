@@ -0,0 +1,34 @@
+package repositories
+
+import "testing"
+
+func TestHighlight(t *testing.T) {
+	tests := []struct {
+		name string
+		s    string
+		want string
+	}{
+		{"plain text", "Inception", "Inception"},
+		{"match", "The " + highlightStart + "Matrix" + highlightStop, "The <mark>Matrix</mark>"},
+		{
+			"several matches",
+			highlightStart + "Star" + highlightStop + " " + highlightStart + "Wars" + highlightStop,
+			"<mark>Star</mark> <mark>Wars</mark>",
+		},
+		{
+			"html is escaped",
+			`<b>"Tom & Jerry"</b> ` + highlightStart + "cartoon" + highlightStop,
+			"&lt;b&gt;&#34;Tom &amp; Jerry&#34;&lt;/b&gt; <mark>cartoon</mark>",
+		},
+		{"mark tags in the text are escaped", "<mark>", "&lt;mark&gt;"},
+		{"empty", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := highlight(tt.s); got != tt.want {
+				t.Errorf("highlight(%q) = %q, want %q", tt.s, got, tt.want)
+			}
+		})
+	}
+}
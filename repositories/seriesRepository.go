@@ -0,0 +1,544 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"goozinshe/logger"
+	"goozinshe/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrSeriesNotFound  = models.NewNotFoundError("series_not_found", "series not found")
+	ErrSeasonNotFound  = models.NewNotFoundError("season_not_found", "season not found")
+	ErrEpisodeNotFound = models.NewNotFoundError("episode_not_found", "episode not found")
+)
+
+// ErrSeasonNumberTaken and ErrEpisodeNumberTaken are returned when the series
+// or season already has a season or episode with the number.
+var (
+	ErrSeasonNumberTaken = models.NewValidationError("Invalid payload",
+		models.FieldError{Field: "number", Code: models.CodeTaken, Message: "the series already has a season with this number"})
+	ErrEpisodeNumberTaken = models.NewValidationError("Invalid payload",
+		models.FieldError{Field: "number", Code: models.CodeTaken, Message: "the season already has an episode with this number"})
+)
+
+// ErrSeriesWatched is returned when a series is marked as watched as a whole.
+// A series is watched once all of its episodes are.
+var ErrSeriesWatched = models.NewInvalidFieldError("id",
+	"a series is marked as watched through PATCH /movies/:id/seasons/:season/setWatched or /movies/:id/seasons/:season/episodes/:episode/setWatched")
+
+type SeriesRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewSeriesRepository(conn *pgxpool.Pool) *SeriesRepository {
+	return &SeriesRepository{db: conn}
+}
+
+// seasonColumns selects a season of the series s together with its episodes
+// and whether the caller watched them, aggregated into a JSON array in episode
+// order. It must be scanned with scanSeason.
+const seasonColumns = `
+s.id,
+s.number,
+s.title,
+coalesce((
+	select json_agg(json_build_object(
+		'Id', e.id,
+		'SeasonNumber', s.number,
+		'Number', e.number,
+		'Title', e.title,
+		'Description', e.description,
+		'Runtime', e.runtime,
+		'AirDate', coalesce(to_char(e.air_date, 'YYYY-MM-DD'), ''),
+		'IsWatched', we.episode_id is not null
+	) order by e.number)
+	from episodes e
+	left join user_watched_episodes we on we.episode_id = e.id and we.user_id = @userId
+	where e.season_id = s.id
+), '[]')`
+
+func scanSeason(row pgx.Row, s *models.Season) error {
+	err := row.Scan(&s.Id, &s.Number, &s.Title, &s.Episodes)
+	if err != nil {
+		return err
+	}
+
+	s.EpisodesCount = len(s.Episodes)
+	for _, e := range s.Episodes {
+		if e.IsWatched {
+			s.WatchedEpisodesCount++
+		}
+	}
+	return nil
+}
+
+// checkSeries returns ErrSeriesNotFound unless id is a series that isn't in
+// the trash.
+func checkSeries(c context.Context, db rowQuerier, id int) error {
+	var exists bool
+	err := db.QueryRow(c, "select exists(select 1 from movies where id = $1 and kind = 'series' and deleted_at is null)", id).Scan(&exists)
+	if err != nil {
+		logger.GetLogger().Error("Could not check series", zap.Int("series_id", id), zap.Error(err))
+		return err
+	}
+	if !exists {
+		return ErrSeriesNotFound
+	}
+	return nil
+}
+
+// findSeasonId returns the id of the season of the series with the number.
+func findSeasonId(c context.Context, db rowQuerier, seriesId int, number int) (int, error) {
+	if err := checkSeries(c, db, seriesId); err != nil {
+		return 0, err
+	}
+
+	var id int
+	err := db.QueryRow(c, "select id from seasons where series_id = $1 and number = $2", seriesId, number).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, ErrSeasonNotFound
+	}
+	if err != nil {
+		logger.GetLogger().Error("Could not fetch season", zap.Int("series_id", seriesId), zap.Int("season", number), zap.Error(err))
+		return 0, err
+	}
+	return id, nil
+}
+
+// findEpisodeId returns the id of the episode with the number in the season of
+// the series.
+func findEpisodeId(c context.Context, db rowQuerier, seriesId int, seasonNumber int, number int) (int, error) {
+	seasonId, err := findSeasonId(c, db, seriesId, seasonNumber)
+	if err != nil {
+		return 0, err
+	}
+
+	var id int
+	err = db.QueryRow(c, "select id from episodes where season_id = $1 and number = $2", seasonId, number).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, ErrEpisodeNotFound
+	}
+	if err != nil {
+		logger.GetLogger().Error("Could not fetch episode", zap.Int("season_id", seasonId), zap.Int("episode", number), zap.Error(err))
+		return 0, err
+	}
+	return id, nil
+}
+
+// FindSeasons returns the seasons of the series in order, with their episodes
+// and the progress of the user through them.
+func (r *SeriesRepository) FindSeasons(c context.Context, seriesId int, userId int) ([]models.Season, error) {
+	logger := logger.GetLogger()
+	logger.Info("Fetching seasons", zap.Int("series_id", seriesId), zap.Int("user_id", userId))
+
+	if err := checkSeries(c, r.db, seriesId); err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.Query(
+		c,
+		"select "+seasonColumns+" from seasons s where s.series_id = @seriesId order by s.number",
+		pgx.NamedArgs{"seriesId": seriesId, "userId": userId})
+	if err != nil {
+		logger.Error("Could not fetch seasons", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	seasons := make([]models.Season, 0)
+	for rows.Next() {
+		var season models.Season
+		if err := scanSeason(rows, &season); err != nil {
+			logger.Error("Could not scan season", zap.Error(err))
+			return nil, err
+		}
+		seasons = append(seasons, season)
+	}
+
+	if err = rows.Err(); err != nil {
+		logger.Error("Error iterating over seasons", zap.Error(err))
+		return nil, err
+	}
+
+	logger.Info("Successfully fetched seasons", zap.Int("series_id", seriesId), zap.Int("count", len(seasons)))
+	return seasons, nil
+}
+
+// FindSeason returns the season of the series with the number, with its
+// episodes and the progress of the user through them.
+func (r *SeriesRepository) FindSeason(c context.Context, seriesId int, number int, userId int) (models.Season, error) {
+	logger := logger.GetLogger()
+	logger.Info("Fetching season", zap.Int("series_id", seriesId), zap.Int("season", number), zap.Int("user_id", userId))
+
+	if err := checkSeries(c, r.db, seriesId); err != nil {
+		return models.Season{}, err
+	}
+
+	var season models.Season
+	err := scanSeason(r.db.QueryRow(
+		c,
+		"select "+seasonColumns+" from seasons s where s.series_id = @seriesId and s.number = @number",
+		pgx.NamedArgs{"seriesId": seriesId, "number": number, "userId": userId}), &season)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.Season{}, ErrSeasonNotFound
+	}
+	if err != nil {
+		logger.Error("Could not fetch season", zap.Error(err))
+		return models.Season{}, err
+	}
+
+	return season, nil
+}
+
+// NextEpisode returns the episode the user should watch next, or nil when the
+// user watched every episode that has aired. It is the first unwatched episode
+// after the furthest one the user watched, or the first one the user skipped
+// when there is none after it.
+func (r *SeriesRepository) NextEpisode(c context.Context, seriesId int, userId int) (*models.Episode, error) {
+	logger := logger.GetLogger()
+	logger.Info("Fetching next episode", zap.Int("series_id", seriesId), zap.Int("user_id", userId))
+
+	if err := checkSeries(c, r.db, seriesId); err != nil {
+		return nil, err
+	}
+
+	sql := `
+with eps as (
+	select e.*, s.number as season_number, we.episode_id is not null as watched
+	from seasons s
+	join episodes e on e.season_id = s.id
+	left join user_watched_episodes we on we.episode_id = e.id and we.user_id = @userId
+	where s.series_id = @seriesId
+),
+furthest as (
+	select season_number, number
+	from eps
+	where watched
+	order by season_number desc, number desc
+	limit 1
+)
+select e.id, e.season_number, e.number, e.title, e.description, e.runtime, coalesce(to_char(e.air_date, 'YYYY-MM-DD'), '')
+from eps e
+left join furthest f on true
+where not e.watched and (e.air_date is null or e.air_date <= current_date)
+order by coalesce((e.season_number, e.number) < (f.season_number, f.number), false), e.season_number, e.number
+limit 1
+	`
+
+	var episode models.Episode
+	err := r.db.QueryRow(c, sql, pgx.NamedArgs{"seriesId": seriesId, "userId": userId}).Scan(
+		&episode.Id,
+		&episode.SeasonNumber,
+		&episode.Number,
+		&episode.Title,
+		&episode.Description,
+		&episode.Runtime,
+		&episode.AirDate)
+	if errors.Is(err, pgx.ErrNoRows) {
+		logger.Info("No episode left to watch", zap.Int("series_id", seriesId), zap.Int("user_id", userId))
+		return nil, nil
+	}
+	if err != nil {
+		logger.Error("Could not fetch next episode", zap.Error(err))
+		return nil, err
+	}
+
+	logger.Info("Successfully fetched next episode", zap.Int("series_id", seriesId), zap.Int("episode_id", episode.Id))
+	return &episode, nil
+}
+
+func (r *SeriesRepository) CreateSeason(c context.Context, seriesId int, season models.Season) (int, error) {
+	logger := logger.GetLogger()
+	logger.Info("Creating season", zap.Int("series_id", seriesId), zap.Int("season", season.Number))
+
+	if err := checkSeries(c, r.db, seriesId); err != nil {
+		return 0, err
+	}
+
+	var id int
+	err := r.db.QueryRow(
+		c,
+		"insert into seasons(series_id, number, title) values($1, $2, $3) returning id",
+		seriesId,
+		season.Number,
+		season.Title).Scan(&id)
+	if isUniqueViolation(err) {
+		return 0, ErrSeasonNumberTaken
+	}
+	if err != nil {
+		logger.Error("Could not create season", zap.Error(err))
+		return 0, err
+	}
+
+	logger.Info("Successfully created season", zap.Int("season_id", id))
+	return id, nil
+}
+
+// UpdateSeason changes the number and title of the season of the series with
+// the number.
+func (r *SeriesRepository) UpdateSeason(c context.Context, seriesId int, number int, season models.Season) error {
+	logger := logger.GetLogger()
+	logger.Info("Updating season", zap.Int("series_id", seriesId), zap.Int("season", number))
+
+	id, err := findSeasonId(c, r.db, seriesId, number)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.Exec(c, "update seasons set number = $1, title = $2 where id = $3", season.Number, season.Title, id)
+	if isUniqueViolation(err) {
+		return ErrSeasonNumberTaken
+	}
+	if err != nil {
+		logger.Error("Could not update season", zap.Error(err))
+		return err
+	}
+
+	logger.Info("Successfully updated season", zap.Int("season_id", id))
+	return nil
+}
+
+// DeleteSeason deletes the season of the series with the number, together with
+// its episodes and their watched state.
+func (r *SeriesRepository) DeleteSeason(c context.Context, seriesId int, number int) error {
+	logger := logger.GetLogger()
+	logger.Info("Deleting season", zap.Int("series_id", seriesId), zap.Int("season", number))
+
+	id, err := findSeasonId(c, r.db, seriesId, number)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.Exec(c, "delete from seasons where id = $1", id)
+	if err != nil {
+		logger.Error("Could not delete season", zap.Error(err))
+		return err
+	}
+
+	logger.Info("Successfully deleted season", zap.Int("season_id", id))
+	return nil
+}
+
+func (r *SeriesRepository) CreateEpisode(c context.Context, seriesId int, seasonNumber int, episode models.Episode) (int, error) {
+	logger := logger.GetLogger()
+	logger.Info("Creating episode", zap.Int("series_id", seriesId), zap.Int("season", seasonNumber), zap.Int("episode", episode.Number))
+
+	seasonId, err := findSeasonId(c, r.db, seriesId, seasonNumber)
+	if err != nil {
+		return 0, err
+	}
+
+	var id int
+	err = r.db.QueryRow(
+		c,
+		`
+insert into episodes(season_id, number, title, description, runtime, air_date)
+values(@seasonId, @number, @title, @description, @runtime, nullif(@airDate, '')::date)
+returning id
+		`,
+		pgx.NamedArgs{
+			"seasonId":    seasonId,
+			"number":      episode.Number,
+			"title":       episode.Title,
+			"description": episode.Description,
+			"runtime":     episode.Runtime,
+			"airDate":     episode.AirDate,
+		}).Scan(&id)
+	if isUniqueViolation(err) {
+		return 0, ErrEpisodeNumberTaken
+	}
+	if err != nil {
+		logger.Error("Could not create episode", zap.Error(err))
+		return 0, err
+	}
+
+	logger.Info("Successfully created episode", zap.Int("episode_id", id))
+	return id, nil
+}
+
+// UpdateEpisode replaces the episode with the number in the season of the
+// series.
+func (r *SeriesRepository) UpdateEpisode(c context.Context, seriesId int, seasonNumber int, number int, episode models.Episode) error {
+	logger := logger.GetLogger()
+	logger.Info("Updating episode", zap.Int("series_id", seriesId), zap.Int("season", seasonNumber), zap.Int("episode", number))
+
+	id, err := findEpisodeId(c, r.db, seriesId, seasonNumber, number)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.Exec(
+		c,
+		`
+update episodes
+set
+number = @number,
+title = @title,
+description = @description,
+runtime = @runtime,
+air_date = nullif(@airDate, '')::date
+where id = @id
+		`,
+		pgx.NamedArgs{
+			"id":          id,
+			"number":      episode.Number,
+			"title":       episode.Title,
+			"description": episode.Description,
+			"runtime":     episode.Runtime,
+			"airDate":     episode.AirDate,
+		})
+	if isUniqueViolation(err) {
+		return ErrEpisodeNumberTaken
+	}
+	if err != nil {
+		logger.Error("Could not update episode", zap.Error(err))
+		return err
+	}
+
+	logger.Info("Successfully updated episode", zap.Int("episode_id", id))
+	return nil
+}
+
+func (r *SeriesRepository) DeleteEpisode(c context.Context, seriesId int, seasonNumber int, number int) error {
+	logger := logger.GetLogger()
+	logger.Info("Deleting episode", zap.Int("series_id", seriesId), zap.Int("season", seasonNumber), zap.Int("episode", number))
+
+	id, err := findEpisodeId(c, r.db, seriesId, seasonNumber, number)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.Exec(c, "delete from episodes where id = $1", id)
+	if err != nil {
+		logger.Error("Could not delete episode", zap.Error(err))
+		return err
+	}
+
+	logger.Info("Successfully deleted episode", zap.Int("episode_id", id))
+	return nil
+}
+
+// SetEpisodeWatched marks the episode with the number in the season of the
+// series as watched or unwatched by the user.
+func (r *SeriesRepository) SetEpisodeWatched(c context.Context, seriesId int, seasonNumber int, number int, userId int, isWatched bool) error {
+	logger := logger.GetLogger()
+	logger.Info("Updating episode watch status", zap.Int("series_id", seriesId), zap.Int("season", seasonNumber), zap.Int("episode", number), zap.Int("user_id", userId), zap.Bool("is_watched", isWatched))
+
+	id, err := findEpisodeId(c, r.db, seriesId, seasonNumber, number)
+	if err != nil {
+		return err
+	}
+
+	return r.setWatched(c, seriesId, []int{id}, userId, isWatched)
+}
+
+// SetSeasonWatched marks every episode of the season of the series with the
+// number as watched or unwatched by the user.
+func (r *SeriesRepository) SetSeasonWatched(c context.Context, seriesId int, number int, userId int, isWatched bool) error {
+	logger := logger.GetLogger()
+	logger.Info("Updating season watch status", zap.Int("series_id", seriesId), zap.Int("season", number), zap.Int("user_id", userId), zap.Bool("is_watched", isWatched))
+
+	seasonId, err := findSeasonId(c, r.db, seriesId, number)
+	if err != nil {
+		return err
+	}
+
+	rows, err := r.db.Query(c, "select id from episodes where season_id = $1", seasonId)
+	if err != nil {
+		logger.Error("Could not fetch episodes", zap.Error(err))
+		return err
+	}
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[int])
+	if err != nil {
+		logger.Error("Could not scan episodes", zap.Error(err))
+		return err
+	}
+
+	return r.setWatched(c, seriesId, ids, userId, isWatched)
+}
+
+// setWatched marks the episodes as watched or unwatched by the user. The series
+// itself is watched once the user watched all of its episodes, which adds it to
+// the user's watch history like a movie.
+func (r *SeriesRepository) setWatched(c context.Context, seriesId int, episodeIds []int, userId int, isWatched bool) error {
+	logger := logger.GetLogger()
+
+	tx, err := r.db.Begin(c)
+	if err != nil {
+		logger.Error("Could not begin transaction", zap.Error(err))
+		return err
+	}
+	defer tx.Rollback(c)
+
+	if isWatched {
+		_, err = tx.Exec(
+			c,
+			`
+insert into user_watched_episodes(user_id, episode_id, watched_at)
+select $1, e.id, now()
+from unnest($2::int[]) as e(id)
+on conflict (user_id, episode_id) do update
+set watched_at = excluded.watched_at
+			`,
+			userId,
+			episodeIds)
+	} else {
+		_, err = tx.Exec(c, "delete from user_watched_episodes where user_id = $1 and episode_id = any($2)", userId, episodeIds)
+	}
+	if err != nil {
+		logger.Error("Could not update episode watch status", zap.Error(err))
+		return err
+	}
+
+	var finished bool
+	err = tx.QueryRow(
+		c,
+		`
+select count(*) > 0 and count(*) = count(we.episode_id)
+from seasons s
+join episodes e on e.season_id = s.id
+left join user_watched_episodes we on we.episode_id = e.id and we.user_id = $2
+where s.series_id = $1
+		`,
+		seriesId,
+		userId).Scan(&finished)
+	if err != nil {
+		logger.Error("Could not check series progress", zap.Error(err))
+		return err
+	}
+
+	if finished {
+		tag, err := tx.Exec(c, "insert into user_watched_movies(user_id, movie_id, watched_at) values($1, $2, now()) on conflict (user_id, movie_id) do nothing", userId, seriesId)
+		if err != nil {
+			logger.Error("Could not update series watch status", zap.Error(err))
+			return err
+		}
+		if tag.RowsAffected() > 0 {
+			_, err = tx.Exec(c, "insert into watch_events(user_id, movie_id, watched_at) values($1, $2, now())", userId, seriesId)
+			if err != nil {
+				logger.Error("Could not insert watch event", zap.Error(err))
+				return err
+			}
+		}
+	} else {
+		_, err = tx.Exec(c, "delete from user_watched_movies where user_id = $1 and movie_id = $2", userId, seriesId)
+		if err != nil {
+			logger.Error("Could not update series watch status", zap.Error(err))
+			return err
+		}
+	}
+
+	err = tx.Commit(c)
+	if err != nil {
+		logger.Error("Could not commit transaction", zap.Error(err))
+		return err
+	}
+
+	logger.Info("Successfully updated episode watch status", zap.Int("series_id", seriesId), zap.Int("user_id", userId), zap.Int("count", len(episodeIds)))
+	return nil
+}
@@ -0,0 +1,224 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"goozinshe/logger"
+	"goozinshe/models"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrRefreshTokenInvalid = models.NewUnauthorizedError("refresh_token_invalid", "refresh token is invalid or expired")
+	ErrRefreshTokenReused  = models.NewUnauthorizedError("refresh_token_reused", "refresh token was already used")
+)
+
+// SessionsRepository stores sign-in sessions. Every session is a family of
+// rotating refresh tokens: each refresh token can be used exactly once and
+// presenting a used one revokes the whole session.
+type SessionsRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewSessionsRepository(conn *pgxpool.Pool) *SessionsRepository {
+	return &SessionsRepository{db: conn}
+}
+
+// Create starts a new session for the user with its first refresh token.
+func (r *SessionsRepository) Create(c context.Context, userId int, tokenHash string, expiresAt time.Time) (string, error) {
+	logger := logger.GetLogger()
+	logger.Info("Creating session", zap.Int("user_id", userId))
+
+	tx, err := r.db.Begin(c)
+	if err != nil {
+		logger.Error("Could not begin transaction", zap.Error(err))
+		return "", err
+	}
+	defer tx.Rollback(c)
+
+	sessionId := uuid.NewString()
+	_, err = tx.Exec(c, "insert into auth_sessions(id, user_id) values($1, $2)", sessionId, userId)
+	if err != nil {
+		logger.Error("Could not create session", zap.Error(err))
+		return "", err
+	}
+
+	_, err = tx.Exec(c, "insert into refresh_tokens(token_hash, session_id, expires_at) values($1, $2, $3)", tokenHash, sessionId, expiresAt)
+	if err != nil {
+		logger.Error("Could not create refresh token", zap.Error(err))
+		return "", err
+	}
+
+	err = tx.Commit(c)
+	if err != nil {
+		logger.Error("Could not commit transaction", zap.Error(err))
+		return "", err
+	}
+
+	logger.Info("Successfully created session", zap.Int("user_id", userId), zap.String("session_id", sessionId))
+	return sessionId, nil
+}
+
+// Rotate exchanges a refresh token for a new one within the same session and
+// returns the session and user id. Reusing an already rotated token revokes
+// the session, because either the user or an attacker holds a stolen copy.
+func (r *SessionsRepository) Rotate(c context.Context, tokenHash string, newTokenHash string, expiresAt time.Time) (string, int, error) {
+	logger := logger.GetLogger()
+	logger.Info("Rotating refresh token")
+
+	tx, err := r.db.Begin(c)
+	if err != nil {
+		logger.Error("Could not begin transaction", zap.Error(err))
+		return "", 0, err
+	}
+	defer tx.Rollback(c)
+
+	var sessionId string
+	var userId int
+	var used, revoked, expired bool
+	err = tx.QueryRow(
+		c,
+		`
+select
+s.id::text,
+s.user_id,
+t.used_at is not null,
+s.revoked_at is not null,
+t.expires_at <= now()
+from refresh_tokens t
+join auth_sessions s on s.id = t.session_id
+where t.token_hash = $1
+for update
+		`,
+		tokenHash).Scan(&sessionId, &userId, &used, &revoked, &expired)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", 0, ErrRefreshTokenInvalid
+	}
+	if err != nil {
+		logger.Error("Could not fetch refresh token", zap.Error(err))
+		return "", 0, err
+	}
+
+	if revoked || expired {
+		return "", 0, ErrRefreshTokenInvalid
+	}
+
+	if used {
+		logger.Warn("Refresh token reuse detected, revoking session", zap.String("session_id", sessionId), zap.Int("user_id", userId))
+		_, err = tx.Exec(c, "update auth_sessions set revoked_at = now() where id = $1", sessionId)
+		if err != nil {
+			logger.Error("Could not revoke session", zap.Error(err))
+			return "", 0, err
+		}
+		if err = tx.Commit(c); err != nil {
+			logger.Error("Could not commit transaction", zap.Error(err))
+			return "", 0, err
+		}
+		return "", 0, ErrRefreshTokenReused
+	}
+
+	_, err = tx.Exec(c, "update refresh_tokens set used_at = now() where token_hash = $1", tokenHash)
+	if err != nil {
+		logger.Error("Could not mark refresh token as used", zap.Error(err))
+		return "", 0, err
+	}
+
+	_, err = tx.Exec(c, "insert into refresh_tokens(token_hash, session_id, expires_at) values($1, $2, $3)", newTokenHash, sessionId, expiresAt)
+	if err != nil {
+		logger.Error("Could not create refresh token", zap.Error(err))
+		return "", 0, err
+	}
+
+	err = tx.Commit(c)
+	if err != nil {
+		logger.Error("Could not commit transaction", zap.Error(err))
+		return "", 0, err
+	}
+
+	logger.Info("Successfully rotated refresh token", zap.String("session_id", sessionId), zap.Int("user_id", userId))
+	return sessionId, userId, nil
+}
+
+// Revoke ends a session and puts the access token it was called with on the
+// denylist until the token expires.
+func (r *SessionsRepository) Revoke(c context.Context, sessionId string, jti string, jtiExpiresAt time.Time) error {
+	logger := logger.GetLogger()
+	logger.Info("Revoking session", zap.String("session_id", sessionId))
+
+	tx, err := r.db.Begin(c)
+	if err != nil {
+		logger.Error("Could not begin transaction", zap.Error(err))
+		return err
+	}
+	defer tx.Rollback(c)
+
+	_, err = tx.Exec(c, "update auth_sessions set revoked_at = now() where id = $1 and revoked_at is null", sessionId)
+	if err != nil {
+		logger.Error("Could not revoke session", zap.Error(err))
+		return err
+	}
+
+	_, err = tx.Exec(c, "insert into revoked_access_tokens(jti, expires_at) values($1, $2) on conflict (jti) do nothing", jti, jtiExpiresAt)
+	if err != nil {
+		logger.Error("Could not revoke access token", zap.Error(err))
+		return err
+	}
+
+	// Expired tokens are rejected anyway, so there is no need to keep them.
+	_, err = tx.Exec(c, "delete from revoked_access_tokens where expires_at < now()")
+	if err != nil {
+		logger.Error("Could not clean up revoked access tokens", zap.Error(err))
+		return err
+	}
+
+	err = tx.Commit(c)
+	if err != nil {
+		logger.Error("Could not commit transaction", zap.Error(err))
+		return err
+	}
+
+	logger.Info("Successfully revoked session", zap.String("session_id", sessionId))
+	return nil
+}
+
+// RevokeAllForUser ends every session of the user except keepSessionId, e.g.
+// after a password change. Pass an empty keepSessionId to end all of them.
+func (r *SessionsRepository) RevokeAllForUser(c context.Context, userId int, keepSessionId string) error {
+	logger := logger.GetLogger()
+	logger.Info("Revoking all sessions of user", zap.Int("user_id", userId))
+
+	_, err := r.db.Exec(c, "update auth_sessions set revoked_at = now() where user_id = $1 and id::text <> $2 and revoked_at is null", userId, keepSessionId)
+	if err != nil {
+		logger.Error("Could not revoke sessions", zap.Error(err))
+		return err
+	}
+
+	logger.Info("Successfully revoked all sessions of user", zap.Int("user_id", userId))
+	return nil
+}
+
+// IsRevoked reports whether an access token can no longer be used, either
+// because it was denylisted or because its session is gone or revoked.
+func (r *SessionsRepository) IsRevoked(c context.Context, sessionId string, jti string) (bool, error) {
+	var revoked bool
+	err := r.db.QueryRow(
+		c,
+		`
+select
+exists(select 1 from revoked_access_tokens where jti = $1)
+or not exists(select 1 from auth_sessions where id = $2 and revoked_at is null)
+		`,
+		jti,
+		sessionId).Scan(&revoked)
+	if err != nil {
+		logger.GetLogger().Error("Could not check token revocation", zap.Error(err))
+		return false, err
+	}
+
+	return revoked, nil
+}
@@ -0,0 +1,253 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"goozinshe/logger"
+	"goozinshe/models"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+var ErrTranslationNotFound = models.NewNotFoundError("translation_not_found", "translation not found")
+
+// translationTable describes where the translations of an entity are stored.
+type translationTable struct {
+	entities string // table of the translated entities
+	table    string
+	key      string   // column referencing the entity
+	fields   []string // title and, if translated, description, in this order
+}
+
+var translationTables = map[string]translationTable{
+	models.EntityMovie: {entities: "movies", table: "movie_translations", key: "movie_id", fields: []string{"title", "description"}},
+	models.EntityGenre: {entities: "genres", table: "genre_translations", key: "genre_id", fields: []string{"title"}},
+}
+
+// translated selects the field of the entity with the alias in the first of
+// @locales it is translated to, or its original value. Empty translations,
+// such as a missing description, fall through to the next locale.
+func translated(entity string, alias string, field string) string {
+	t := translationTables[entity]
+	return fmt.Sprintf(`coalesce((
+	select t.%[4]s
+	from %[1]s t
+	where t.%[2]s = %[3]s.id and t.locale = any(@locales::text[]) and t.%[4]s <> ''
+	order by array_position(@locales::text[], t.locale)
+	limit 1
+), %[3]s.%[4]s)`, t.table, t.key, alias, field)
+}
+
+// translationsSnapshot is the JSON object of the translations of the entity
+// with the alias, keyed by locale, that revision snapshots keep.
+func translationsSnapshot(entity string, alias string) string {
+	t := translationTables[entity]
+	values := make([]string, 0, len(t.fields))
+	for _, field := range t.fields {
+		values = append(values, fmt.Sprintf("'%s', t.%s", snapshotKey(field), field))
+	}
+	return fmt.Sprintf(
+		"coalesce((select jsonb_object_agg(t.locale, jsonb_build_object(%s)) from %s t where t.%s = %s.id), '{}')",
+		strings.Join(values, ", "), t.table, t.key, alias)
+}
+
+func snapshotKey(field string) string {
+	return strings.ToUpper(field[:1]) + field[1:]
+}
+
+type TranslationsRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewTranslationsRepository(conn *pgxpool.Pool) *TranslationsRepository {
+	return &TranslationsRepository{db: conn}
+}
+
+// FindAll returns the translations of the entity with the id in the order of
+// models.Locales.
+func (r *TranslationsRepository) FindAll(c context.Context, entity string, id int) ([]models.Translation, error) {
+	logger := logger.GetLogger()
+	logger.Info("Fetching translations", zap.String("entity", entity), zap.Int("id", id))
+
+	t := translationTables[entity]
+	rows, err := r.db.Query(
+		c,
+		fmt.Sprintf("select locale, %s from %s where %s = $1 order by array_position($2::text[], locale)", strings.Join(t.fields, ", "), t.table, t.key),
+		id,
+		models.Locales)
+	if err != nil {
+		logger.Error("Could not fetch translations", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	translations := make([]models.Translation, 0)
+	for rows.Next() {
+		var translation models.Translation
+		dest := []any{&translation.Locale, &translation.Title, &translation.Description}
+		if err := rows.Scan(dest[:len(t.fields)+1]...); err != nil {
+			logger.Error("Could not scan translation", zap.Error(err))
+			return nil, err
+		}
+		translations = append(translations, translation)
+	}
+
+	if err = rows.Err(); err != nil {
+		logger.Error("Error iterating over translations", zap.Error(err))
+		return nil, err
+	}
+
+	logger.Info("Successfully fetched translations", zap.String("entity", entity), zap.Int("id", id), zap.Int("count", len(translations)))
+	return translations, nil
+}
+
+// Set adds or replaces the translation of the entity with the id to
+// translation.Locale, if the entity still has the version, or regardless of its
+// version when version is 0. It records a revision of the entity, made by the
+// user with userId.
+func (r *TranslationsRepository) Set(c context.Context, entity string, id int, translation models.Translation, version int, userId int) error {
+	logger := logger.GetLogger()
+	logger.Info("Setting translation", zap.String("entity", entity), zap.Int("id", id), zap.String("locale", translation.Locale))
+
+	err := r.change(c, entity, id, version, userId, func(tx pgx.Tx) error {
+		return setTranslation(c, tx, entity, id, translation)
+	})
+	if err != nil {
+		return err
+	}
+
+	logger.Info("Successfully set translation", zap.String("entity", entity), zap.Int("id", id), zap.String("locale", translation.Locale))
+	return nil
+}
+
+// Delete removes the translation of the entity with the id to the locale, if
+// the entity still has the version, or regardless of its version when version
+// is 0. It records a revision of the entity, made by the user with userId.
+func (r *TranslationsRepository) Delete(c context.Context, entity string, id int, locale string, version int, userId int) error {
+	logger := logger.GetLogger()
+	logger.Info("Deleting translation", zap.String("entity", entity), zap.Int("id", id), zap.String("locale", locale))
+
+	t := translationTables[entity]
+	var exists bool
+	err := r.db.QueryRow(c, fmt.Sprintf("select exists(select 1 from %s where %s = $1 and locale = $2)", t.table, t.key), id, locale).Scan(&exists)
+	if err != nil {
+		logger.Error("Could not check translation", zap.Error(err))
+		return err
+	}
+	if !exists {
+		return ErrTranslationNotFound
+	}
+
+	err = r.change(c, entity, id, version, userId, func(tx pgx.Tx) error {
+		_, err := tx.Exec(c, fmt.Sprintf("delete from %s where %s = $1 and locale = $2", t.table, t.key), id, locale)
+		if err != nil {
+			logger.Error("Could not delete translation", zap.Error(err))
+		}
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	logger.Info("Successfully deleted translation", zap.String("entity", entity), zap.Int("id", id), zap.String("locale", locale))
+	return nil
+}
+
+// change bumps the version of the entity, if it is not in the trash and still
+// has the version, runs apply and records the revision.
+func (r *TranslationsRepository) change(c context.Context, entity string, id int, version int, userId int, apply func(tx pgx.Tx) error) error {
+	logger := logger.GetLogger()
+
+	tx, err := r.db.Begin(c)
+	if err != nil {
+		logger.Error("Could not begin transaction", zap.Error(err))
+		return err
+	}
+	defer tx.Rollback(c)
+
+	tag, err := tx.Exec(
+		c,
+		fmt.Sprintf("update %s set version = version + 1 where id = @id and deleted_at is null and %s", translationTables[entity].entities, versionCondition),
+		pgx.NamedArgs{"id": id, "version": version})
+	if err != nil {
+		logger.Error("Could not update version", zap.String("entity", entity), zap.Error(err))
+		return err
+	}
+	if err = checkVersion(tag, version); err != nil {
+		logger.Info("Version changed", zap.String("entity", entity), zap.Int("id", id), zap.Int("version", version))
+		return err
+	}
+
+	if err = apply(tx); err != nil {
+		return err
+	}
+
+	err = recordRevision(c, tx, entity, id, userId, models.RevisionUpdate)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit(c)
+	if err != nil {
+		logger.Error("Could not commit transaction", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+func setTranslation(c context.Context, tx pgx.Tx, entity string, id int, translation models.Translation) error {
+	t := translationTables[entity]
+	values := []any{id, translation.Locale, translation.Title, translation.Description}[:len(t.fields)+2]
+
+	placeholders := make([]string, len(values))
+	updates := make([]string, len(t.fields))
+	for i := range values {
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+	}
+	for i, field := range t.fields {
+		updates[i] = fmt.Sprintf("%[1]s = excluded.%[1]s", field)
+	}
+
+	_, err := tx.Exec(
+		c,
+		fmt.Sprintf(
+			"insert into %s(%s, locale, %s) values(%s) on conflict (%s, locale) do update set %s",
+			t.table, t.key, strings.Join(t.fields, ", "), strings.Join(placeholders, ", "), t.key, strings.Join(updates, ", ")),
+		values...)
+	if err != nil {
+		logger.GetLogger().Error("Could not set translation", zap.String("entity", entity), zap.Int("id", id), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// restoreTranslations replaces the translations of the entity with the ones of
+// a revision snapshot, keyed by locale. Snapshots from before translations
+// existed have none, and leave the translations unchanged.
+func restoreTranslations(c context.Context, tx pgx.Tx, entity string, id int, translations map[string]models.Translation) error {
+	if translations == nil {
+		return nil
+	}
+
+	t := translationTables[entity]
+	_, err := tx.Exec(c, fmt.Sprintf("delete from %s where %s = $1", t.table, t.key), id)
+	if err != nil {
+		logger.GetLogger().Error("Could not delete translations", zap.String("entity", entity), zap.Int("id", id), zap.Error(err))
+		return err
+	}
+
+	for _, locale := range models.Locales {
+		translation, ok := translations[locale]
+		if !ok {
+			continue
+		}
+		translation.Locale = locale
+		if err := setTranslation(c, tx, entity, id, translation); err != nil {
+			return err
+		}
+	}
+	return nil
+}
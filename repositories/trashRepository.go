@@ -0,0 +1,196 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"goozinshe/logger"
+	"goozinshe/models"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+var ErrNotInTrash = models.NewNotFoundError("not_in_trash", "item is not in the trash")
+
+// trashTables maps entity types to their tables.
+var trashTables = map[string]string{
+	models.EntityMovie: "movies",
+	models.EntityGenre: "genres",
+	models.EntityUser:  "users",
+}
+
+type TrashRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewTrashRepository(conn *pgxpool.Pool) *TrashRepository {
+	return &TrashRepository{db: conn}
+}
+
+// FindAll returns a page of the trashed movies, genres and users, most
+// recently deleted first.
+func (r *TrashRepository) FindAll(c context.Context, page models.PageRequest) ([]models.TrashItem, models.PageInfo, error) {
+	logger := logger.GetLogger()
+	logger.Info("Fetching trash", zap.Int("page", page.Page), zap.Int("limit", page.Limit))
+
+	sql := `
+with trash as (
+	select 'movie' as type, id, title, deleted_at from movies where deleted_at is not null
+	union all
+	select 'genre', id, title, deleted_at from genres where deleted_at is not null
+	union all
+	select 'user', id, name, deleted_at from users where deleted_at is not null
+)
+select type, id, coalesce(title, ''), deleted_at, count(*) over ()
+from trash
+order by deleted_at desc, type, id
+limit @limit offset @offset
+	`
+
+	rows, err := r.db.Query(c, sql, pgx.NamedArgs{"limit": page.Limit, "offset": page.Offset()})
+	if err != nil {
+		logger.Error("Could not fetch trash", zap.Error(err))
+		return nil, models.PageInfo{}, err
+	}
+	defer rows.Close()
+
+	var total int
+	items := make([]models.TrashItem, 0)
+	for rows.Next() {
+		var item models.TrashItem
+		if err := rows.Scan(&item.Type, &item.Id, &item.Title, &item.DeletedAt, &total); err != nil {
+			logger.Error("Could not scan trash item", zap.Error(err))
+			return nil, models.PageInfo{}, err
+		}
+		items = append(items, item)
+	}
+
+	if err = rows.Err(); err != nil {
+		logger.Error("Error iterating over trash", zap.Error(err))
+		return nil, models.PageInfo{}, err
+	}
+
+	logger.Info("Successfully fetched trash", zap.Int("count", len(items)))
+	return items, models.PageInfo{Total: total}, nil
+}
+
+// Restore takes a movie, genre or user out of the trash. Restoring a movie or
+// genre is recorded as a revision made by the user with userId.
+func (r *TrashRepository) Restore(c context.Context, entity string, id int, userId int) error {
+	logger := logger.GetLogger()
+	logger.Info("Restoring from trash", zap.String("entity", entity), zap.Int("id", id))
+
+	tx, err := r.db.Begin(c)
+	if err != nil {
+		logger.Error("Could not begin transaction", zap.Error(err))
+		return err
+	}
+	defer tx.Rollback(c)
+
+	sql := fmt.Sprintf("update %s set deleted_at = null, version = version + 1 where id = $1 and deleted_at is not null", trashTables[entity])
+	tag, err := tx.Exec(c, sql, id)
+	if err != nil {
+		logger.Error("Could not restore from trash", zap.Error(err))
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotInTrash
+	}
+
+	if _, ok := revisionSnapshots[entity]; ok {
+		err = recordRevision(c, tx, entity, id, userId, models.RevisionRestore)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = tx.Commit(c)
+	if err != nil {
+		logger.Error("Could not commit transaction", zap.Error(err))
+		return err
+	}
+
+	logger.Info("Successfully restored from trash", zap.String("entity", entity), zap.Int("id", id))
+	return nil
+}
+
+// PurgeResult counts the items that were purged, by entity type.
+type PurgeResult map[string]int
+
+// Purge permanently deletes the movies, genres and users that have been in
+// the trash for longer than retention, together with everything that refers
+// to them. Revisions are kept. Posters of purged movies are deleted by the
+// image sweeper.
+func (r *TrashRepository) Purge(c context.Context, retention time.Duration) (PurgeResult, error) {
+	logger := logger.GetLogger()
+	logger.Info("Purging trash", zap.Duration("retention", retention))
+
+	tx, err := r.db.Begin(c)
+	if err != nil {
+		logger.Error("Could not begin transaction", zap.Error(err))
+		return nil, err
+	}
+	defer tx.Rollback(c)
+
+	expired := make(map[string][]int, len(trashTables))
+	for entity, table := range trashTables {
+		sql := fmt.Sprintf("select id from %s where deleted_at < now() - $1::interval for update", table)
+		rows, err := tx.Query(c, sql, retention)
+		if err != nil {
+			logger.Error("Could not fetch expired trash", zap.String("entity", entity), zap.Error(err))
+			return nil, err
+		}
+		expired[entity], err = pgx.CollectRows(rows, pgx.RowTo[int])
+		if err != nil {
+			logger.Error("Could not scan expired trash", zap.String("entity", entity), zap.Error(err))
+			return nil, err
+		}
+	}
+
+	// Ratings, watchlists, history and sessions of users are deleted by
+	// cascading foreign keys, movie references have to be deleted first.
+	statements := []struct {
+		sql    string
+		entity string
+	}{
+		{"delete from movies_genres where movie_id = any($1)", models.EntityMovie},
+		{"delete from movie_credits where movie_id = any($1)", models.EntityMovie},
+		{"delete from seasons where series_id = any($1)", models.EntityMovie},
+		{"delete from movie_translations where movie_id = any($1)", models.EntityMovie},
+		{"delete from user_movie_ratings where movie_id = any($1)", models.EntityMovie},
+		{"delete from watchlist_items where movie_id = any($1)", models.EntityMovie},
+		{"delete from user_watched_movies where movie_id = any($1)", models.EntityMovie},
+		{"delete from watch_events where movie_id = any($1)", models.EntityMovie},
+		{"delete from movies where id = any($1)", models.EntityMovie},
+		{"delete from movies_genres where genre_id = any($1)", models.EntityGenre},
+		{"delete from genre_translations where genre_id = any($1)", models.EntityGenre},
+		{"delete from genres where id = any($1)", models.EntityGenre},
+		{"delete from users where id = any($1)", models.EntityUser},
+	}
+	for _, statement := range statements {
+		if len(expired[statement.entity]) == 0 {
+			continue
+		}
+		_, err = tx.Exec(c, statement.sql, expired[statement.entity])
+		if err != nil {
+			logger.Error("Could not purge trash", zap.String("statement", statement.sql), zap.Error(err))
+			return nil, err
+		}
+	}
+
+	err = tx.Commit(c)
+	if err != nil {
+		logger.Error("Could not commit transaction", zap.Error(err))
+		return nil, err
+	}
+
+	result := make(PurgeResult, len(expired))
+	for entity, ids := range expired {
+		result[entity] = len(ids)
+	}
+
+	logger.Info("Successfully purged trash", zap.Any("purged", result))
+	return result, nil
+}
@@ -0,0 +1,25 @@
+package repositories
+
+import (
+	"goozinshe/models"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// ErrVersionMismatch is returned by updates and deletes that expect a version
+// which is no longer current, because someone else changed the row meanwhile.
+var ErrVersionMismatch = models.NewPreconditionFailedError("version_mismatch", "the resource was changed by someone else")
+
+// versionCondition restricts an update to the expected version, bound as
+// @version. A version of 0 matches any version.
+const versionCondition = "(@version = 0 or version = @version)"
+
+// checkVersion reports ErrVersionMismatch when a conditional statement didn't
+// change any row. Rows are looked up before they are changed, so a missing row
+// means its version changed.
+func checkVersion(tag pgconn.CommandTag, version int) error {
+	if version != 0 && tag.RowsAffected() == 0 {
+		return ErrVersionMismatch
+	}
+	return nil
+}
@@ -2,14 +2,26 @@ package repositories
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"goozinshe/logger"
 	"goozinshe/models"
 
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"go.uber.org/zap"
 )
 
+const defaultWatchlistTitle = "Watchlist"
+
+var (
+	ErrMovieNotFound          = models.NewNotFoundError("movie_not_found", "movie not found")
+	ErrWatchlistNotFound      = models.NewNotFoundError("watchlist_not_found", "watchlist not found")
+	ErrWatchlistItemNotFound  = models.NewNotFoundError("watchlist_item_not_found", "movie is not in the watchlist")
+	ErrWatchlistOrderMismatch = models.NewInvalidFieldError("movieIds", "movie ids must list every movie of the watchlist exactly once")
+)
+
 type WatchlistRepository struct {
 	db *pgxpool.Pool
 }
@@ -18,68 +30,298 @@ func NewWatchlistRepository(conn *pgxpool.Pool) *WatchlistRepository {
 	return &WatchlistRepository{db: conn}
 }
 
-func (r *WatchlistRepository) FindAll(c context.Context) ([]models.Movie, error) {
+const watchlistColumns = `
+w.id,
+w.user_id,
+w.title,
+w.visibility,
+coalesce(w.share_token, ''),
+w.is_default,
+(select count(*) from watchlist_items wi join movies m on m.id = wi.movie_id and m.deleted_at is null where wi.watchlist_id = w.id),
+w.created_at`
+
+func scanWatchlist(row pgx.Row, w *models.Watchlist) error {
+	return row.Scan(&w.Id, &w.UserId, &w.Title, &w.Visibility, &w.ShareToken, &w.IsDefault, &w.ItemsCount, &w.CreatedAt)
+}
+
+// FindAll returns a page of the movies of the user's default watchlist in list order.
+func (r *WatchlistRepository) FindAll(c context.Context, userId int, locales []string, page models.PageRequest) ([]models.Movie, models.PageInfo, error) {
 	logger := logger.GetLogger()
-	logger.Info("Fetching all movies from watchlist")
-
-	sql := `
-    select 
-        m.id,
-        m.title,
-        m.description,
-        m.release_year,
-        m.director,
-        m.rating,
-        m.is_watched,
-        m.trailer_url,
-        m.poster_url,
-        g.id,
-        g.title
-    from movies m
-    join watchlist w on w.movie_id = m.id
-    join movies_genres mg on mg.movie_id = m.id
-    join genres g on mg.genre_id = g.id
-    `
-
-	rows, err := r.db.Query(c, sql)
+	logger.Info("Fetching all movies from watchlist", zap.Int("user_id", userId), zap.Int("page", page.Page), zap.Int("limit", page.Limit))
+
+	watchlist, err := r.FindDefault(c, userId)
 	if err != nil {
-		logger.Error("Error querying watchlist movies", zap.Error(err))
-		return nil, err
+		return nil, models.PageInfo{}, err
+	}
+
+	items, err := r.queryItems(c, watchlist.Id, userId, locales, &page)
+	if err != nil {
+		return nil, models.PageInfo{}, err
+	}
+
+	items, info := pageInfo(items, page, watchlist.ItemsCount, func(item models.WatchlistItem) int { return item.Movie.Id })
+
+	watchlistMovies := make([]models.Movie, 0, len(items))
+	for _, item := range items {
+		watchlistMovies = append(watchlistMovies, item.Movie)
+	}
+
+	logger.Info("Successfully fetched watchlist movies", zap.Int("count", len(watchlistMovies)), zap.Int("total", info.Total))
+	return watchlistMovies, info, nil
+}
+
+func (r *WatchlistRepository) AddToWatchlist(c context.Context, userId int, movieId int) error {
+	watchlist, err := r.FindDefault(c, userId)
+	if err != nil {
+		return err
+	}
+
+	return r.AddItem(c, watchlist.Id, movieId, "")
+}
+
+func (r *WatchlistRepository) Delete(c context.Context, userId int, movieId int) error {
+	watchlist, err := r.FindDefault(c, userId)
+	if err != nil {
+		return err
 	}
 
-defer rows.Close()
+	return r.RemoveItem(c, watchlist.Id, movieId)
+}
 
-	movies := make([]*models.Movie, 0)
-	moviesMap := make(map[int]*models.Movie)
+// FindDefault returns the user's default watchlist, creating it on first use.
+func (r *WatchlistRepository) FindDefault(c context.Context, userId int) (models.Watchlist, error) {
+	logger := logger.GetLogger()
+	logger.Info("Fetching default watchlist", zap.Int("user_id", userId))
+
+	_, err := r.db.Exec(
+		c,
+		`
+insert into watchlists(user_id, title, visibility, is_default)
+values($1, $2, $3, true)
+on conflict (user_id) where is_default do nothing
+		`,
+		userId,
+		defaultWatchlistTitle,
+		models.WatchlistPrivate)
+	if err != nil {
+		logger.Error("Could not create default watchlist", zap.Error(err))
+		return models.Watchlist{}, err
+	}
 
+	var watchlist models.Watchlist
+	sql := fmt.Sprintf("select %s from watchlists w where w.user_id = $1 and w.is_default", watchlistColumns)
+	err = scanWatchlist(r.db.QueryRow(c, sql, userId), &watchlist)
+	if err != nil {
+		logger.Error("Could not fetch default watchlist", zap.Error(err))
+		return models.Watchlist{}, err
+	}
+
+	return watchlist, nil
+}
+
+func (r *WatchlistRepository) FindAllByUser(c context.Context, userId int) ([]models.Watchlist, error) {
+	logger := logger.GetLogger()
+	logger.Info("Fetching watchlists", zap.Int("user_id", userId))
+
+	// Make sure the default list always shows up, even before anything was added to it.
+	if _, err := r.FindDefault(c, userId); err != nil {
+		return nil, err
+	}
+
+	sql := fmt.Sprintf("select %s from watchlists w where w.user_id = $1 order by w.is_default desc, w.created_at, w.id", watchlistColumns)
+	rows, err := r.db.Query(c, sql, userId)
+	if err != nil {
+		logger.Error("Could not fetch watchlists", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	watchlists := make([]models.Watchlist, 0)
 	for rows.Next() {
-		var m models.Movie
-		var g models.Genre
-
-		err := rows.Scan(
-			&m.Id,
-			&m.Title,
-			&m.Description,
-			&m.ReleaseYear,
-			&m.Director,
-			&m.Rating,
-			&m.IsWatched,
-			&m.TrailerUrl,
-			&m.PosterUrl,
-			&g.Id,
-			&g.Title,
-		)
-		if err != nil {
-			logger.Error("Error scanning movie row", zap.Error(err))
+		var w models.Watchlist
+		if err := scanWatchlist(rows, &w); err != nil {
+			logger.Error("Could not scan watchlist", zap.Error(err))
 			return nil, err
 		}
+		watchlists = append(watchlists, w)
+	}
+
+	if err = rows.Err(); err != nil {
+		logger.Error("Error iterating over watchlists", zap.Error(err))
+		return nil, err
+	}
+
+	logger.Info("Successfully fetched watchlists", zap.Int("user_id", userId), zap.Int("count", len(watchlists)))
+	return watchlists, nil
+}
+
+func (r *WatchlistRepository) FindById(c context.Context, id int) (models.Watchlist, error) {
+	logger := logger.GetLogger()
+	logger.Info("Fetching watchlist by ID", zap.Int("watchlist_id", id))
+
+	var watchlist models.Watchlist
+	sql := fmt.Sprintf("select %s from watchlists w where w.id = $1", watchlistColumns)
+	err := scanWatchlist(r.db.QueryRow(c, sql, id), &watchlist)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.Watchlist{}, ErrWatchlistNotFound
+	}
+	if err != nil {
+		logger.Error("Could not fetch watchlist", zap.Error(err))
+		return models.Watchlist{}, err
+	}
+
+	return watchlist, nil
+}
+
+func (r *WatchlistRepository) FindByShareToken(c context.Context, token string) (models.Watchlist, error) {
+	logger := logger.GetLogger()
+	logger.Info("Fetching shared watchlist")
+
+	var watchlist models.Watchlist
+	sql := fmt.Sprintf("select %s from watchlists w where w.share_token = $1 and w.visibility = $2", watchlistColumns)
+	err := scanWatchlist(r.db.QueryRow(c, sql, token, models.WatchlistLink), &watchlist)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.Watchlist{}, ErrWatchlistNotFound
+	}
+	if err != nil {
+		logger.Error("Could not fetch shared watchlist", zap.Error(err))
+		return models.Watchlist{}, err
+	}
+
+	return watchlist, nil
+}
+
+func (r *WatchlistRepository) Create(c context.Context, watchlist models.Watchlist) (int, error) {
+	logger := logger.GetLogger()
+	logger.Info("Creating watchlist", zap.Int("user_id", watchlist.UserId), zap.String("title", watchlist.Title))
+
+	var id int
+	err := r.db.QueryRow(
+		c,
+		"insert into watchlists(user_id, title, visibility, share_token) values($1, $2, $3, $4) returning id",
+		watchlist.UserId,
+		watchlist.Title,
+		watchlist.Visibility,
+		shareTokenFor(watchlist.Visibility, "")).Scan(&id)
+	if err != nil {
+		logger.Error("Could not create watchlist", zap.Error(err))
+		return 0, err
+	}
+
+	logger.Info("Successfully created watchlist", zap.Int("watchlist_id", id))
+	return id, nil
+}
+
+// Update changes the title and visibility of a watchlist. A share token is
+// issued when the list becomes shared by link and dropped when it stops being
+// shared, so previously handed out links stop working.
+func (r *WatchlistRepository) Update(c context.Context, id int, watchlist models.Watchlist) error {
+	logger := logger.GetLogger()
+	logger.Info("Updating watchlist", zap.Int("watchlist_id", id))
+
+	current, err := r.FindById(c, id)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.Exec(
+		c,
+		"update watchlists set title = $1, visibility = $2, share_token = $3 where id = $4",
+		watchlist.Title,
+		watchlist.Visibility,
+		shareTokenFor(watchlist.Visibility, current.ShareToken),
+		id)
+	if err != nil {
+		logger.Error("Could not update watchlist", zap.Error(err))
+		return err
+	}
+
+	logger.Info("Successfully updated watchlist", zap.Int("watchlist_id", id))
+	return nil
+}
+
+func (r *WatchlistRepository) DeleteList(c context.Context, id int) error {
+	logger := logger.GetLogger()
+	logger.Info("Deleting watchlist", zap.Int("watchlist_id", id))
+
+	_, err := r.db.Exec(c, "delete from watchlists where id = $1", id)
+	if err != nil {
+		logger.Error("Could not delete watchlist", zap.Error(err))
+		return err
+	}
+
+	logger.Info("Successfully deleted watchlist", zap.Int("watchlist_id", id))
+	return nil
+}
 
-		if _, exists := moviesMap[m.Id]; !exists {
-			moviesMap[m.Id] = &m
-			movies = append(movies, &m)
+// FindItems returns the items of a watchlist in list order. Ratings and
+// watched state of the movies are the ones of viewerId, texts are in the first
+// of locales they are translated to.
+func (r *WatchlistRepository) FindItems(c context.Context, watchlistId int, viewerId int, locales []string) ([]models.WatchlistItem, error) {
+	return r.queryItems(c, watchlistId, viewerId, locales, nil)
+}
+
+// watchlistItemKeys is the list order of watchlist items.
+var watchlistItemKeys = []sortKey{{expr: "%[1]s.position"}, {expr: "%[1]s.movie_id"}}
+
+// queryItems fetches the items of a watchlist, or only one page of them when
+// page is set. One extra item is fetched to detect further pages, see pageInfo.
+func (r *WatchlistRepository) queryItems(c context.Context, watchlistId int, viewerId int, locales []string, page *models.PageRequest) ([]models.WatchlistItem, error) {
+	logger := logger.GetLogger()
+	logger.Info("Fetching watchlist items", zap.Int("watchlist_id", watchlistId))
+
+	params := pgx.NamedArgs{"watchlistId": watchlistId, "userId": viewerId, "locales": locales}
+	cursorJoin, where, limit := "", "", ""
+	reverse := false
+	if page != nil {
+		limit = "limit @limit offset @offset"
+		params["limit"] = page.Limit + 1
+		params["offset"] = page.Offset()
+		if page.Cursor != nil {
+			reverse = page.Cursor.Before != 0
+			cursorJoin = "join watchlist_items cur on cur.watchlist_id = wi.watchlist_id and cur.movie_id = @cursorId"
+			where = "and " + keysetCondition(watchlistItemKeys, "wi", "cur", reverse)
+			params["cursorId"] = cursorId(*page)
+			params["offset"] = 0
 		}
+	}
+
+	sql := fmt.Sprintf(`
+    select %s,
+        wi.position,
+        wi.note,
+        wi.added_at
+    from (
+        select wi.*
+        from watchlist_items wi
+        join movies im on im.id = wi.movie_id and im.deleted_at is null
+        %s
+        where wi.watchlist_id = @watchlistId %s
+        order by %s
+        %s
+    ) wi
+    join movies m on m.id = wi.movie_id
+    %s
+    order by %s
+    `, movieColumns, cursorJoin, where, orderBy(watchlistItemKeys, "wi", reverse), limit, movieUserJoins, orderBy(watchlistItemKeys, "wi", reverse))
 
-		moviesMap[m.Id].Genres = append(moviesMap[m.Id].Genres, g)
+	rows, err := r.db.Query(c, sql, params)
+	if err != nil {
+		logger.Error("Error querying watchlist movies", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	watchlistItems := make([]models.WatchlistItem, 0)
+	for rows.Next() {
+		var item models.WatchlistItem
+
+		err := scanMovie(rows, &item.Movie, &item.Position, &item.Note, &item.AddedAt)
+		if err != nil {
+			logger.Error("Error scanning movie row", zap.Error(err))
+			return nil, err
+		}
+		watchlistItems = append(watchlistItems, item)
 	}
 
 	if err = rows.Err(); err != nil {
@@ -87,63 +329,228 @@ defer rows.Close()
 		return nil, err
 	}
 
-	watchlistMovies := make([]models.Movie, 0, len(movies))
-	for _, v := range movies {
-		watchlistMovies = append(watchlistMovies, *v)
-	}
-
-	logger.Info("Successfully fetched watchlist movies", zap.Int("count", len(watchlistMovies)))
-	return watchlistMovies, nil
+	logger.Info("Successfully fetched watchlist items", zap.Int("watchlist_id", watchlistId), zap.Int("count", len(watchlistItems)))
+	return watchlistItems, nil
 }
 
-func (r *WatchlistRepository) AddToWatchlist(c context.Context, movieId int) error {
+// AddItem appends a movie to the end of a watchlist. Adding a movie that is
+// already in the list only updates its note.
+func (r *WatchlistRepository) AddItem(c context.Context, watchlistId int, movieId int, note string) error {
 	logger := logger.GetLogger()
-	logger.Info("Adding movie to watchlist", zap.Int("movie_id", movieId))
+	logger.Info("Adding movie to watchlist", zap.Int("watchlist_id", watchlistId), zap.Int("movie_id", movieId))
 
 	var exists bool
-	err := r.db.QueryRow(c, "SELECT EXISTS(SELECT 1 FROM movies WHERE id = $1)", movieId).Scan(&exists)
+	err := r.db.QueryRow(c, "SELECT EXISTS(SELECT 1 FROM movies WHERE id = $1 AND deleted_at IS NULL)", movieId).Scan(&exists)
 	if err != nil {
 		logger.Error("Error checking if movie exists", zap.Error(err))
 		return err
 	}
 	if !exists {
 		logger.Warn("Movie not found", zap.Int("movie_id", movieId))
-		return fmt.Errorf("movie not found")
+		return ErrMovieNotFound
 	}
 
-	_, err = r.db.Exec(c, "INSERT INTO watchlist (movie_id) VALUES ($1)", movieId)
+	_, err = r.db.Exec(
+		c,
+		`
+insert into watchlist_items(watchlist_id, movie_id, position, note, added_at)
+select $1, $2, coalesce(max(position), 0) + 1, $3, now()
+from watchlist_items
+where watchlist_id = $1
+on conflict (watchlist_id, movie_id) do update
+set note = case when excluded.note = '' then watchlist_items.note else excluded.note end
+		`,
+		watchlistId,
+		movieId,
+		note)
 	if err != nil {
 		logger.Error("Error inserting movie into watchlist", zap.Error(err))
 		return err
 	}
 
-	logger.Info("Successfully added movie to watchlist", zap.Int("movie_id", movieId))
+	logger.Info("Successfully added movie to watchlist", zap.Int("watchlist_id", watchlistId), zap.Int("movie_id", movieId))
+	return nil
+}
+
+func (r *WatchlistRepository) UpdateItemNote(c context.Context, watchlistId int, movieId int, note string) error {
+	logger := logger.GetLogger()
+	logger.Info("Updating watchlist item note", zap.Int("watchlist_id", watchlistId), zap.Int("movie_id", movieId))
+
+	tag, err := r.db.Exec(c, "update watchlist_items set note = $1 where watchlist_id = $2 and movie_id = $3", note, watchlistId, movieId)
+	if err != nil {
+		logger.Error("Could not update watchlist item note", zap.Error(err))
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrWatchlistItemNotFound
+	}
+
+	logger.Info("Successfully updated watchlist item note", zap.Int("watchlist_id", watchlistId), zap.Int("movie_id", movieId))
 	return nil
 }
 
-func (r *WatchlistRepository) Delete(c context.Context, movieId int) error {
+// MoveItem moves a movie to the given 1-based position, shifting the other
+// items. Positions past the end of the list move the movie to the end.
+func (r *WatchlistRepository) MoveItem(c context.Context, watchlistId int, movieId int, position int) error {
 	logger := logger.GetLogger()
-	logger.Info("Removing movie from watchlist", zap.Int("movie_id", movieId))
+	logger.Info("Moving watchlist item", zap.Int("watchlist_id", watchlistId), zap.Int("movie_id", movieId), zap.Int("position", position))
 
 	tx, err := r.db.Begin(c)
 	if err != nil {
-		logger.Error("Error beginning transaction", zap.Error(err))
+		logger.Error("Could not begin transaction", zap.Error(err))
 		return err
 	}
+	defer tx.Rollback(c)
 
-	_, err = tx.Exec(c, "delete from watchlist where movie_id = $1", movieId)
+	movieIds, err := r.lockItems(c, tx, watchlistId)
 	if err != nil {
-		tx.Rollback(c)
-		logger.Error("Error deleting movie from watchlist", zap.Error(err))
+		return err
+	}
+
+	reordered := make([]int, 0, len(movieIds))
+	for _, id := range movieIds {
+		if id != movieId {
+			reordered = append(reordered, id)
+		}
+	}
+	if len(reordered) == len(movieIds) {
+		return ErrWatchlistItemNotFound
+	}
+
+	index := min(max(position, 1), len(movieIds)) - 1
+	reordered = append(reordered[:index], append([]int{movieId}, reordered[index:]...)...)
+
+	if err = r.writePositions(c, tx, watchlistId, reordered); err != nil {
+		return err
+	}
+
+	err = tx.Commit(c)
+	if err != nil {
+		logger.Error("Could not commit transaction", zap.Error(err))
+		return err
+	}
+
+	logger.Info("Successfully moved watchlist item", zap.Int("watchlist_id", watchlistId), zap.Int("movie_id", movieId))
+	return nil
+}
+
+// Reorder sets the order of a watchlist. movieIds must contain every movie of
+// the list exactly once.
+func (r *WatchlistRepository) Reorder(c context.Context, watchlistId int, movieIds []int) error {
+	logger := logger.GetLogger()
+	logger.Info("Reordering watchlist", zap.Int("watchlist_id", watchlistId), zap.Ints("movie_ids", movieIds))
+
+	tx, err := r.db.Begin(c)
+	if err != nil {
+		logger.Error("Could not begin transaction", zap.Error(err))
+		return err
+	}
+	defer tx.Rollback(c)
+
+	current, err := r.lockItems(c, tx, watchlistId)
+	if err != nil {
+		return err
+	}
+
+	if len(current) != len(movieIds) {
+		return ErrWatchlistOrderMismatch
+	}
+	seen := make(map[int]bool, len(current))
+	for _, id := range current {
+		seen[id] = false
+	}
+	for _, id := range movieIds {
+		if done, ok := seen[id]; !ok || done {
+			return ErrWatchlistOrderMismatch
+		}
+		seen[id] = true
+	}
+
+	if err = r.writePositions(c, tx, watchlistId, movieIds); err != nil {
 		return err
 	}
 
 	err = tx.Commit(c)
 	if err != nil {
-		logger.Error("Error committing transaction", zap.Error(err))
+		logger.Error("Could not commit transaction", zap.Error(err))
+		return err
+	}
+
+	logger.Info("Successfully reordered watchlist", zap.Int("watchlist_id", watchlistId))
+	return nil
+}
+
+func (r *WatchlistRepository) lockItems(c context.Context, tx pgx.Tx, watchlistId int) ([]int, error) {
+	logger := logger.GetLogger()
+
+	// Items of trashed movies are hidden and keep their position.
+	rows, err := tx.Query(
+		c,
+		`
+select wi.movie_id
+from watchlist_items wi
+join movies m on m.id = wi.movie_id and m.deleted_at is null
+where wi.watchlist_id = $1
+order by wi.position, wi.movie_id
+for update of wi
+		`,
+		watchlistId)
+	if err != nil {
+		logger.Error("Could not lock watchlist items", zap.Error(err))
+		return nil, err
+	}
+
+	movieIds, err := pgx.CollectRows(rows, pgx.RowTo[int])
+	if err != nil {
+		logger.Error("Could not scan watchlist items", zap.Error(err))
+		return nil, err
+	}
+
+	return movieIds, nil
+}
+
+func (r *WatchlistRepository) writePositions(c context.Context, tx pgx.Tx, watchlistId int, movieIds []int) error {
+	_, err := tx.Exec(
+		c,
+		`
+update watchlist_items wi
+set position = o.position
+from unnest($2::int[]) with ordinality as o(movie_id, position)
+where wi.watchlist_id = $1 and wi.movie_id = o.movie_id
+		`,
+		watchlistId,
+		movieIds)
+	if err != nil {
+		logger.GetLogger().Error("Could not update watchlist positions", zap.Error(err))
 		return err
 	}
 
-	logger.Info("Successfully removed movie from watchlist", zap.Int("movie_id", movieId))
 	return nil
 }
+
+func (r *WatchlistRepository) RemoveItem(c context.Context, watchlistId int, movieId int) error {
+	logger := logger.GetLogger()
+	logger.Info("Removing movie from watchlist", zap.Int("watchlist_id", watchlistId), zap.Int("movie_id", movieId))
+
+	tag, err := r.db.Exec(c, "delete from watchlist_items where watchlist_id = $1 and movie_id = $2", watchlistId, movieId)
+	if err != nil {
+		logger.Error("Error deleting movie from watchlist", zap.Error(err))
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrWatchlistItemNotFound
+	}
+
+	logger.Info("Successfully removed movie from watchlist", zap.Int("watchlist_id", watchlistId), zap.Int("movie_id", movieId))
+	return nil
+}
+
+func shareTokenFor(visibility string, current string) *string {
+	if visibility != models.WatchlistLink {
+		return nil
+	}
+	if current == "" {
+		current = uuid.NewString()
+	}
+	return &current
+}
@@ -0,0 +1,118 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+type S3Options struct {
+	Endpoint  string
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	UseSSL    bool
+}
+
+// S3Storage keeps files in a bucket of an S3 compatible object storage such
+// as AWS S3 or MinIO.
+type S3Storage struct {
+	client *minio.Client
+	bucket string
+}
+
+func NewS3Storage(options S3Options) (*S3Storage, error) {
+	if options.Endpoint == "" || options.Bucket == "" {
+		return nil, errors.New("S3 storage needs S3_ENDPOINT and S3_BUCKET")
+	}
+
+	client, err := minio.New(options.Endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(options.AccessKey, options.SecretKey, ""),
+		Secure: options.UseSSL,
+		Region: options.Region,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	c := context.Background()
+	exists, err := client.BucketExists(c, options.Bucket)
+	if err != nil {
+		return nil, fmt.Errorf("could not reach bucket %q: %w", options.Bucket, err)
+	}
+	if !exists {
+		err = client.MakeBucket(c, options.Bucket, minio.MakeBucketOptions{Region: options.Region})
+		if err != nil {
+			return nil, fmt.Errorf("could not create bucket %q: %w", options.Bucket, err)
+		}
+	}
+
+	return &S3Storage{client: client, bucket: options.Bucket}, nil
+}
+
+func (s *S3Storage) Put(c context.Context, key string, r io.Reader, size int64, contentType string) error {
+	if !validKey(key) {
+		return ErrInvalidKey
+	}
+
+	_, err := s.client.PutObject(c, s.bucket, key, r, size, minio.PutObjectOptions{ContentType: contentType})
+	return err
+}
+
+func (s *S3Storage) Get(c context.Context, key string) (*Object, error) {
+	if !validKey(key) {
+		return nil, ErrInvalidKey
+	}
+
+	// GetObject doesn't send a request until the object is read or stat'ed.
+	object, err := s.client.GetObject(c, s.bucket, key, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := object.Stat()
+	if err != nil {
+		object.Close()
+		if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return &Object{
+		Body:        object,
+		Size:        info.Size,
+		ContentType: info.ContentType,
+		ModTime:     info.LastModified,
+		ETag:        fmt.Sprintf(`"%s"`, info.ETag),
+	}, nil
+}
+
+func (s *S3Storage) Delete(c context.Context, key string) error {
+	if !validKey(key) {
+		return ErrInvalidKey
+	}
+
+	return s.client.RemoveObject(c, s.bucket, key, minio.RemoveObjectOptions{})
+}
+
+func (s *S3Storage) List(c context.Context, fn func(info ObjectInfo) error) error {
+	// Cancelling stops the listing goroutine when fn fails half way.
+	c, cancel := context.WithCancel(c)
+	defer cancel()
+
+	for object := range s.client.ListObjects(c, s.bucket, minio.ListObjectsOptions{}) {
+		if object.Err != nil {
+			return object.Err
+		}
+		if err := fn(ObjectInfo{Key: object.Key, Size: object.Size, ModTime: object.LastModified}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+// Package storage keeps uploaded files such as movie posters. Files are
+// addressed by a key, which is the image id used in /images/:imageId URLs.
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"goozinshe/config"
+	"io"
+	"time"
+)
+
+const (
+	DriverLocal = "local"
+	DriverS3    = "s3"
+)
+
+var (
+	ErrNotFound   = errors.New("file not found")
+	ErrInvalidKey = errors.New("invalid file key")
+)
+
+// Object is a stored file. The caller must close Body. Bodies of both
+// drivers are also io.Seekers, which allows serving ranges.
+type Object struct {
+	Body        io.ReadCloser
+	Size        int64
+	ContentType string
+	ModTime     time.Time
+	ETag        string
+}
+
+// ObjectInfo describes a stored file without its content.
+type ObjectInfo struct {
+	Key     string
+	Size    int64
+	ModTime time.Time
+}
+
+type Storage interface {
+	// Put stores size bytes read from r under key, replacing an existing file.
+	Put(c context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Get returns ErrNotFound when there is no file with the key.
+	Get(c context.Context, key string) (*Object, error)
+	// Delete doesn't fail when there is no file with the key.
+	Delete(c context.Context, key string) error
+	// List calls fn for every stored file.
+	List(c context.Context, fn func(info ObjectInfo) error) error
+}
+
+// New creates the storage driver selected by STORAGE_DRIVER.
+func New(cfg *config.MapConfig) (Storage, error) {
+	switch cfg.StorageDriver {
+	case DriverLocal, "":
+		return NewLocalStorage(cfg.StorageLocalDir)
+	case DriverS3:
+		return NewS3Storage(S3Options{
+			Endpoint:  cfg.S3Endpoint,
+			Region:    cfg.S3Region,
+			Bucket:    cfg.S3Bucket,
+			AccessKey: cfg.S3AccessKey,
+			SecretKey: cfg.S3SecretKey,
+			UseSSL:    cfg.S3UseSSL,
+		})
+	default:
+		return nil, fmt.Errorf("unknown storage driver %q", cfg.StorageDriver)
+	}
+}
+
+// validKey rejects keys that could escape the storage root, since keys come
+// straight from request paths.
+func validKey(key string) bool {
+	if key == "" || key == "." || key == ".." {
+		return false
+	}
+	for _, r := range key {
+		if r == '/' || r == '\\' || r == 0 {
+			return false
+		}
+	}
+	return true
+}
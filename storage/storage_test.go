@@ -0,0 +1,28 @@
+package storage
+
+import "testing"
+
+func TestValidKey(t *testing.T) {
+	tests := []struct {
+		key  string
+		want bool
+	}{
+		{"3f1c9a7e-poster.jpg", true},
+		{"poster_thumb.webp", true},
+		{"..poster", true},
+		{"", false},
+		{".", false},
+		{"..", false},
+		{"../etc/passwd", false},
+		{"posters/1.jpg", false},
+		{"/poster.jpg", false},
+		{`..\poster.jpg`, false},
+		{"poster\x00.jpg", false},
+	}
+
+	for _, tt := range tests {
+		if got := validKey(tt.key); got != tt.want {
+			t.Errorf("validKey(%q) = %v, want %v", tt.key, got, tt.want)
+		}
+	}
+}